@@ -0,0 +1,76 @@
+package main
+
+import (
+    "flag"
+    "fmt"
+    "os"
+
+    "github.com/pgavlin/jitdash/pkg/email"
+    "github.com/pgavlin/jitdash/pkg/notify"
+    "github.com/pgavlin/jitdash/pkg/render"
+    "github.com/pgavlin/jitdash/pkg/server"
+)
+
+// checkConfigMain implements the check-config subcommand, which reports whether a config is valid. With -dry-run, it
+// also makes a lightweight request for each configured folder, view, and job to check their URLs and credentials. It
+// returns the process's exit code.
+func checkConfigMain(args []string) int {
+    flags := flag.NewFlagSet("check-config", flag.ExitOnError)
+    common := addCommonFlags(flags)
+    dryRun := flags.Bool("dry-run", false, "also check that each instance's folders, views, and jobs can be fetched")
+    flags.Usage = func() {
+        fmt.Fprintf(flags.Output(), "usage: jitdash check-config [flags]\n\n")
+        fmt.Fprintf(flags.Output(), "Checks that a config is valid and that its regular expressions compile. With -dry-run,\n")
+        fmt.Fprintf(flags.Output(), "also checks that the configured URLs and credentials work.\n")
+        flags.PrintDefaults()
+    }
+    flags.Parse(args)
+
+    config, err := common.setup()
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "%s\n", err)
+        return 1
+    }
+    if _, err := server.ParseAuthConfig(config.Object); err != nil {
+        fmt.Fprintf(os.Stderr, "invalid config: %s\n", err)
+        return 1
+    }
+    if _, ok := config.Object.GetObject("email"); ok {
+        if _, err := email.ParseConfig(config.Object); err != nil {
+            fmt.Fprintf(os.Stderr, "invalid config: %s\n", err)
+            return 1
+        }
+    }
+
+    if _, err := notify.ParseConfig(config.Object); err != nil {
+        fmt.Fprintf(os.Stderr, "invalid config: %s\n", err)
+        return 1
+    }
+    if _, err := notify.ParseAlerts(config.Object); err != nil {
+        fmt.Fprintf(os.Stderr, "invalid config: %s\n", err)
+        return 1
+    }
+    if _, err := render.ParseMessages(config.Object); err != nil {
+        fmt.Fprintf(os.Stderr, "invalid config: %s\n", err)
+        return 1
+    }
+
+    fmt.Printf("config is valid: %d instances\n", len(config.Instances))
+    if !*dryRun {
+        return 0
+    }
+
+    status := 0
+    for _, i := range config.Instances {
+        errs := i.Check()
+        if len(errs) == 0 {
+            fmt.Printf("%s: ok\n", i.Name)
+            continue
+        }
+        for _, err := range errs {
+            fmt.Printf("%s\n", err)
+        }
+        status = 1
+    }
+    return status
+}
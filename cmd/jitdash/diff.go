@@ -0,0 +1,128 @@
+package main
+
+import (
+    "bytes"
+    "flag"
+    "fmt"
+    "io"
+    "os"
+
+    "github.com/pgavlin/jitdash/pkg/jenkins"
+    "github.com/pgavlin/jitdash/pkg/notify"
+)
+
+// failureCount describes the number of failures of the given build.
+func failureCount(b *jenkins.Build) string {
+    switch {
+    case b == nil || !b.Failed():
+        return "passing"
+    case b.Failures == -1:
+        return "failed"
+    case b.Failures == 1:
+        return "1 failure"
+    default:
+        return fmt.Sprintf("%d failures", b.Failures)
+    }
+}
+
+// writeDiff writes a report of the given changes, grouped by kind.
+func writeDiff(w io.Writer, changes []*jenkins.JobChange) error {
+    b := new(bytes.Buffer)
+
+    sections := []struct {
+        kind jenkins.ChangeKind
+        title string
+    }{
+        {jenkins.ChangeBroken, "Newly failing"},
+        {jenkins.ChangeFixed, "Recovered"},
+        {jenkins.ChangeFailures, "Failure count changed"},
+    }
+    for _, s := range sections {
+        header := false
+        for _, c := range changes {
+            if c.Kind != s.kind {
+                continue
+            }
+            if !header {
+                fmt.Fprintf(b, "%s:\n", s.title)
+                header = true
+            }
+
+            status := failureCount(c.After)
+            if c.Kind == jenkins.ChangeFailures {
+                status = failureCount(c.Before) + " -> " + status
+            }
+            fmt.Fprintf(b, "  %s/%s: #%d, %s: %s\n", c.Instance, c.Job.Label(), c.After.Id, status, c.After.Url)
+        }
+    }
+    if len(changes) == 0 {
+        fmt.Fprintf(b, "No changes.\n")
+    }
+
+    _, err := w.Write(b.Bytes())
+    return err
+}
+
+// diffMain implements the diff subcommand, which compares a snapshot with either a second snapshot or a fresh fetch of
+// the configured instances, and reports the jobs that newly failed, recovered, or changed their failure counts. It
+// returns the process's exit code.
+func diffMain(args []string) int {
+    flags := flag.NewFlagSet("diff", flag.ExitOnError)
+    common := addCommonFlags(flags)
+    notifyFlag := flags.Bool("notify", false, "also send the changes to the notifiers in the config's notify section")
+    flags.Usage = func() {
+        fmt.Fprintf(flags.Output(), "usage: jitdash diff before.json [after.json]\n\n")
+        fmt.Fprintf(flags.Output(), "Compares two snapshots written by jitdash fetch. If only one snapshot is given, it is\n")
+        fmt.Fprintf(flags.Output(), "compared with a fresh fetch of the configured instances.\n")
+        fmt.Fprintf(flags.Output(), "With -notify, the changes are also sent to the configured notifiers.\n")
+        flags.PrintDefaults()
+    }
+    flags.Parse(args)
+    if flags.NArg() < 1 || flags.NArg() > 2 {
+        flags.Usage()
+        return 2
+    }
+
+    before, err := readSnapshot(flags.Arg(0), nil)
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "could not read snapshot: %s\n", err)
+        return -1
+    }
+
+    var config *jenkins.Config
+    if flags.NArg() == 1 || *notifyFlag {
+        config, err = common.setup()
+        if err != nil {
+            fmt.Fprintf(os.Stderr, "%s\n", err)
+            return -1
+        }
+    }
+
+    var notifiers []notify.Notifier
+    if *notifyFlag {
+        notifiers, err = notify.ParseConfig(config.Object)
+        if err != nil {
+            fmt.Fprintf(os.Stderr, "invalid config: %s\n", err)
+            return -1
+        }
+    }
+
+    var after []*jenkins.InstanceJobs
+    if flags.NArg() == 2 {
+        after, err = readSnapshot(flags.Arg(1), nil)
+        if err != nil {
+            fmt.Fprintf(os.Stderr, "could not read snapshot: %s\n", err)
+            return -1
+        }
+    } else {
+        after = jenkins.Fetch(config.Instances, config.MaxBuilds, config.Workers)
+    }
+
+    changes := jenkins.Diff(before, after)
+    if err := writeDiff(os.Stdout, changes); err != nil {
+        fmt.Fprintf(os.Stderr, "could not write diff: %s\n", err)
+        return -1
+    }
+    notify.Notify(notifiers, changes)
+    return 0
+}
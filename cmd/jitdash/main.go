@@ -0,0 +1,452 @@
+package main
+
+import (
+    "bytes"
+    "encoding/json"
+    "errors"
+    "flag"
+    "fmt"
+    "io"
+    "log/slog"
+    "net/url"
+    "os"
+    "path/filepath"
+
+    "github.com/pgavlin/jitdash/pkg/email"
+    "github.com/pgavlin/jitdash/pkg/jenkins"
+    "github.com/pgavlin/jitdash/pkg/metrics"
+    "github.com/pgavlin/jitdash/pkg/render"
+    "github.com/pgavlin/jitdash/pkg/tracing"
+
+    _ "github.com/pgavlin/jitdash/pkg/azuredevops"
+    _ "github.com/pgavlin/jitdash/pkg/buildkite"
+    _ "github.com/pgavlin/jitdash/pkg/circleci"
+    _ "github.com/pgavlin/jitdash/pkg/plugin"
+    _ "github.com/pgavlin/jitdash/pkg/teamcity"
+)
+
+// newLogger creates a logger that writes records at or above the given level to stderr in the given format.
+func newLogger(level, format string) (*slog.Logger, error) {
+    var l slog.Level
+    if err := l.UnmarshalText([]byte(level)); err != nil {
+        return nil, err
+    }
+
+    options := &slog.HandlerOptions{Level: l}
+    switch format {
+    case "text":
+        return slog.New(slog.NewTextHandler(os.Stderr, options)), nil
+    case "json":
+        return slog.New(slog.NewJSONHandler(os.Stderr, options)), nil
+    default:
+        return nil, errors.New(fmt.Sprintf("unknown log format %s", format))
+    }
+}
+
+// instancePage returns the file name of the page of the named instance.
+func instancePage(instance string) string {
+    return instancePageN(instance, 1)
+}
+
+// instancePageN returns the file name of the given page of the named instance.
+func instancePageN(instance string, page int) string {
+    if page == 1 {
+        return url.PathEscape(instance) + ".html"
+    }
+    return fmt.Sprintf("%s.%d.html", url.PathEscape(instance), page)
+}
+
+// dashboardPageN returns the file name of the given page of the named dashboard.
+func dashboardPageN(dashboard string, page int) string {
+    if page == 1 {
+        return filepath.Join("d", url.PathEscape(dashboard) + ".html")
+    }
+    return filepath.Join("d", fmt.Sprintf("%s.%d.html", url.PathEscape(dashboard), page))
+}
+
+// writePages writes an overview page, index.html, a page per instance, and a page per named dashboard under d/ to the
+// given directory. If options.PageSize is non-zero, instances and dashboards with more jobs than fit on a page are
+// split across several pages.
+func writePages(dir string, instances []*jenkins.InstanceJobs, dashboards []*jenkins.Dashboard, options render.Options) error {
+    if err := os.MkdirAll(dir, 0755); err != nil {
+        return err
+    }
+
+    // Links name the pages' files, so the escapes in the file names must themselves be escaped.
+    b := new(bytes.Buffer)
+    if err := render.Overview(b, instances, options, func(instance string) string { return url.PathEscape(instancePage(instance)) }); err != nil {
+        return err
+    }
+    if err := os.WriteFile(filepath.Join(dir, "index.html"), b.Bytes(), 0644); err != nil {
+        return err
+    }
+
+    for _, ij := range instances {
+        name, instance := ij.Instance.Name, []*jenkins.InstanceJobs{ij}
+
+        pageOptions := options
+        pageOptions.Sections = nil
+        pageOptions.PageUrl = func(page int) string { return url.PathEscape(instancePageN(name, page)) }
+        for page := 1; page <= render.Pages(instance, pageOptions); page++ {
+            pageOptions.Page = page
+
+            b.Reset()
+            if err := render.HTML(b, instance, pageOptions); err != nil {
+                return err
+            }
+            if err := os.WriteFile(filepath.Join(dir, instancePageN(name, page)), b.Bytes(), 0644); err != nil {
+                return err
+            }
+        }
+    }
+
+    if len(dashboards) != 0 {
+        if err := os.MkdirAll(filepath.Join(dir, "d"), 0755); err != nil {
+            return err
+        }
+    }
+    for _, d := range dashboards {
+        name, filtered := d.Name, d.Filter(instances)
+
+        // Dashboard pages link to their other pages, which are in the same directory.
+        pageOptions := options.ForDashboard(d)
+        pageOptions.PageUrl = func(page int) string { return url.PathEscape(filepath.Base(dashboardPageN(name, page))) }
+        for page := 1; page <= render.Pages(filtered, pageOptions); page++ {
+            pageOptions.Page = page
+
+            b.Reset()
+            if err := render.HTML(b, filtered, pageOptions); err != nil {
+                return err
+            }
+            if err := os.WriteFile(filepath.Join(dir, dashboardPageN(name, page)), b.Bytes(), 0644); err != nil {
+                return err
+            }
+        }
+    }
+    return nil
+}
+
+// publishMetrics publishes the state of the fetched jobs and jitdash's own metrics if the config says to. Batch runs
+// exit before they could be scraped, so this is how they report metrics. Failures are logged but do not affect the
+// exit code.
+func publishMetrics(config *jenkins.Config, instances []*jenkins.InstanceJobs) {
+    if len(config.Sinks) == 0 {
+        return
+    }
+    samples := append(jenkins.JobSamples(instances), metrics.Gather()...)
+    for _, sink := range config.Sinks {
+        if err := sink.Publish(samples); err != nil {
+            slog.Error("could not publish metrics", "err", err)
+        }
+    }
+}
+
+// readSnapshot reads the snapshot in the named file.
+func readSnapshot(path string, configured []*jenkins.Instance) ([]*jenkins.InstanceJobs, error) {
+    f, err := os.Open(path)
+    if err != nil {
+        return nil, err
+    }
+    defer f.Close()
+    return jenkins.ReadSnapshot(f, configured)
+}
+
+// writeSnapshot writes a snapshot of the given instances to the named file.
+func writeSnapshot(path string, instances []*jenkins.InstanceJobs) error {
+    b := new(bytes.Buffer)
+    if err := jenkins.WriteSnapshot(b, instances); err != nil {
+        return err
+    }
+    return os.WriteFile(path, b.Bytes(), 0644)
+}
+
+// commonFlags holds the flags that are shared by all of the subcommands.
+type commonFlags struct {
+    logLevel *string
+    logFormat *string
+    config *string
+    record *string
+    replay *string
+}
+
+// addCommonFlags adds the shared flags to the given flag set.
+func addCommonFlags(flags *flag.FlagSet) *commonFlags {
+    return &commonFlags{
+        logLevel: flags.String("log-level", "info", "the minimum level of log records to write (debug, info, warn, or error)"),
+        logFormat: flags.String("log-format", "text", "the format of log records (text or json)"),
+        config: flags.String("config", "-", "the file to read the config from, or - to read it from stdin"),
+        record: flags.String("record", "", "save every response from the configured instances under the given directory, so that they can be replayed with -replay"),
+        replay: flags.String("replay", "", "answer every request to the configured instances with the responses recorded under the given directory by -record instead of fetching them"),
+    }
+}
+
+// setup installs the default logger and reads the config.
+func (c *commonFlags) setup() (*jenkins.Config, error) {
+    logger, err := newLogger(*c.logLevel, *c.logFormat)
+    if err != nil {
+        return nil, errors.New(fmt.Sprintf("invalid logging options: %s", err))
+    }
+    slog.SetDefault(logger)
+
+    config, err := c.readConfig()
+    if err != nil {
+        return nil, err
+    }
+
+    if config.Tracing != nil {
+        tracing.SetExporter(config.Tracing)
+    }
+    return config, nil
+}
+
+// readConfig reads the config from its file or from stdin.
+func (c *commonFlags) readConfig() (*jenkins.Config, error) {
+    r := io.Reader(os.Stdin)
+    if *c.config != "-" {
+        f, err := os.Open(*c.config)
+        if err != nil {
+            return nil, err
+        }
+        defer f.Close()
+        r = f
+    }
+
+    config, err := jenkins.ReadConfig(r)
+    if err != nil {
+        return nil, errors.New(fmt.Sprintf("invalid config: %s", err))
+    }
+
+    // Recording and replaying wrap each instance's transport, so they apply to every request the instance makes,
+    // including those of non-Jenkins backends.
+    if *c.record != "" && *c.replay != "" {
+        return nil, errors.New("-record and -replay cannot be used together")
+    }
+    for _, i := range config.Instances {
+        client := *i.Client
+        switch {
+        case *c.record != "":
+            client.Transport = jenkins.RecordingTransport(*c.record, client.Transport)
+        case *c.replay != "":
+            client.Transport = jenkins.ReplayTransport(*c.replay)
+        default:
+            continue
+        }
+        i.Client = &client
+    }
+    return config, nil
+}
+
+// renderOptions returns the render options given by the config.
+func renderOptions(config *jenkins.Config) (render.Options, error) {
+    messages, err := render.ParseMessages(config.Object)
+    if err != nil {
+        return render.Options{}, err
+    }
+    shapes, _ := config.Object.GetBool("statusShapes")
+    trends := map[string]string{}
+    for _, key := range []string{"coverage", "analysisWarnings"} {
+        trend, _ := config.Object.GetString(key)
+        if trend != "" && trend != render.TrendColumn && trend != render.TrendSparkline {
+            return render.Options{}, errors.New(fmt.Sprintf("invalid %s %s", key, trend))
+        }
+        trends[key] = trend
+    }
+    return render.Options{
+        MaxBuilds: config.MaxBuilds,
+        MaxHistory: config.MaxHistory,
+        Scale: config.Scale,
+        Sort: config.Sort,
+        Location: config.Location,
+        StaleAfter: config.StaleAfter,
+        StaleSection: config.StaleSection,
+        Sections: config.Sections,
+        Messages: messages,
+        Shapes: shapes,
+        Coverage: trends["coverage"],
+        AnalysisWarnings: trends["analysisWarnings"],
+    }, nil
+}
+
+// outputFlags holds the flags that control how a dashboard is written and how its contents affect the exit code.
+type outputFlags struct {
+    onlyFailing *bool
+    format *string
+    email *bool
+    outputDir *string
+    offline *bool
+    summary *bool
+    errorExitCode *int
+    authExitCode *int
+    failIfRed *bool
+}
+
+// addOutputFlags adds the output flags to the given flag set.
+func addOutputFlags(flags *flag.FlagSet) *outputFlags {
+    return &outputFlags{
+        onlyFailing: flags.Bool("only-failing", false, "only show jobs whose latest completed build failed"),
+        format: flags.String("format", "html", "the format of the dashboard (html, markdown, term, csv, or dot)"),
+        email: flags.Bool("email", false, "send an email digest as configured by the config's email section instead of writing the dashboard to stdout"),
+        outputDir: flags.String("output-dir", "", "write an overview page, a page per instance, and a page per named dashboard to the given directory instead of writing the dashboard to stdout"),
+        offline: flags.Bool("offline", false, "render HTML pages that load nothing from the network, so that they can be viewed from a file share or an email attachment"),
+        summary: flags.Bool("summary", false, "write a JSON summary of failing jobs per instance to stderr"),
+        errorExitCode: flags.Int("error-exit-code", 2, "the exit code to use if any data could not be fetched"),
+        authExitCode: flags.Int("auth-exit-code", 3, "the exit code to use if any instance rejected its credentials or required credentials"),
+        failIfRed: flags.Bool("fail-if-red", false, "exit with code 1 if any job's latest completed build failed"),
+    }
+}
+
+// write writes the dashboard for the given instances as directed by the flags and returns the process's exit code.
+func (o *outputFlags) write(config *jenkins.Config, instances []*jenkins.InstanceJobs) int {
+    options, err := renderOptions(config)
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "invalid config: %s\n", err)
+        return -1
+    }
+    options.OnlyFailing = *o.onlyFailing
+    options.Offline = *o.offline
+
+    renderer, ok := render.Formats[*o.format]
+    if !ok {
+        fmt.Fprintf(os.Stderr, "unknown format %s\n", *o.format)
+        return -1
+    }
+
+    switch {
+    case *o.email:
+        emailConfig, err := email.ParseConfig(config.Object)
+        if err != nil {
+            fmt.Fprintf(os.Stderr, "invalid config: %s\n", err)
+            return -1
+        }
+
+        body := new(bytes.Buffer)
+        if err := render.Email(body, instances, options); err != nil {
+            fmt.Fprintf(os.Stderr, "could not render digest: %s\n", err)
+            return -1
+        }
+        if err := emailConfig.Deliver(os.Stdout, body.Bytes()); err != nil {
+            fmt.Fprintf(os.Stderr, "could not send digest: %s\n", err)
+            return -1
+        }
+    case *o.outputDir != "":
+        options.PageSize = config.PageSize
+        if err := writePages(*o.outputDir, instances, config.Dashboards, options); err != nil {
+            fmt.Fprintf(os.Stderr, "could not write dashboard: %s\n", err)
+            return -1
+        }
+    default:
+        if err := renderer(os.Stdout, instances, options); err != nil {
+            fmt.Fprintf(os.Stderr, "could not render dashboard: %s\n", err)
+            return -1
+        }
+    }
+
+    return o.exitCode(instances)
+}
+
+// exitCode writes the summary of the given instances if requested and returns the process's exit code.
+func (o *outputFlags) exitCode(instances []*jenkins.InstanceJobs) int {
+    s := render.Summarize(instances)
+    if *o.summary {
+        json.NewEncoder(os.Stderr).Encode(s)
+    }
+
+    if s.AuthErrors != 0 {
+        return *o.authExitCode
+    }
+    if s.Errors != 0 {
+        return *o.errorExitCode
+    }
+    if *o.failIfRed && s.Failing != 0 {
+        return 1
+    }
+    return 0
+}
+
+// commands maps the names of the subcommands to their implementations.
+var commands = map[string]func(args []string) int{
+    "fetch": fetchMain,
+    "render": renderMain,
+    "serve": serveMain,
+    "check-config": checkConfigMain,
+    "diff": diffMain,
+}
+
+// usage describes the subcommands.
+const usage = `usage: jitdash <command> [flags]
+
+Commands:
+  fetch          fetch the configured instances and write a snapshot of them
+  render         render a snapshot as a dashboard
+  serve          serve a live dashboard
+  check-config   check that a config is valid
+  diff           compare two snapshots
+
+Run jitdash <command> -h for the flags of a command. For compatibility with earlier
+versions, running jitdash with only flags fetches and renders in a single step:
+`
+
+// legacyMain fetches and renders in a single run, as jitdash did before it had subcommands.
+func legacyMain() int {
+    common := addCommonFlags(flag.CommandLine)
+    output := addOutputFlags(flag.CommandLine)
+    serve := flag.String("serve", "", "serve the dashboard at the given address instead of writing it to stdout")
+    serving := addServeFlags(flag.CommandLine)
+    saveSnapshot := flag.String("save-snapshot", "", "also save the fetched model to the given file")
+    renderSnapshot := flag.String("render-snapshot", "", "render the model saved in the given file instead of fetching the configured instances")
+    flag.Usage = func() {
+        fmt.Fprint(flag.CommandLine.Output(), usage)
+        flag.PrintDefaults()
+    }
+    flag.Parse()
+    if flag.NArg() != 0 {
+        fmt.Fprintf(os.Stderr, "unknown command %s\n", flag.Arg(0))
+        flag.Usage()
+        return 2
+    }
+
+    config, err := common.setup()
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "%s\n", err)
+        return -1
+    }
+
+    if *serve != "" {
+        options, err := renderOptions(config)
+        if err != nil {
+            fmt.Fprintf(os.Stderr, "invalid config: %s\n", err)
+            return -1
+        }
+        options.OnlyFailing = *output.onlyFailing
+        return serving.serve(common, config, options, *serve)
+    }
+
+    var instances []*jenkins.InstanceJobs
+    if *renderSnapshot != "" {
+        instances, err = readSnapshot(*renderSnapshot, config.Instances)
+        if err != nil {
+            fmt.Fprintf(os.Stderr, "could not read snapshot: %s\n", err)
+            return -1
+        }
+    } else {
+        instances = jenkins.Fetch(config.Instances, config.MaxBuilds, config.Workers)
+        publishMetrics(config, instances)
+    }
+    if *saveSnapshot != "" {
+        if err := writeSnapshot(*saveSnapshot, instances); err != nil {
+            fmt.Fprintf(os.Stderr, "could not save snapshot: %s\n", err)
+            return -1
+        }
+    }
+
+    return output.write(config, instances)
+}
+
+func main() {
+    if len(os.Args) > 1 {
+        if command, ok := commands[os.Args[1]]; ok {
+            os.Exit(command(os.Args[2:]))
+        }
+    }
+    os.Exit(legacyMain())
+}
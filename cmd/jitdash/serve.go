@@ -0,0 +1,202 @@
+package main
+
+import (
+    "context"
+    "flag"
+    "fmt"
+    "log/slog"
+    "net/http"
+    "os"
+    "os/signal"
+    "syscall"
+    "time"
+
+    "github.com/pgavlin/jitdash/pkg/jenkins"
+    "github.com/pgavlin/jitdash/pkg/notify"
+    "github.com/pgavlin/jitdash/pkg/render"
+    "github.com/pgavlin/jitdash/pkg/server"
+)
+
+// serveFlags holds the flags that control a served dashboard.
+type serveFlags struct {
+    refresh *time.Duration
+    overview *bool
+    shutdownTimeout *time.Duration
+    watchConfig *time.Duration
+}
+
+// addServeFlags adds the serve flags to the given flag set.
+func addServeFlags(flags *flag.FlagSet) *serveFlags {
+    return &serveFlags{
+        refresh: flags.Duration("refresh", 5 * time.Minute, "in serve mode, the default interval between instance refreshes, or 0 to rely on webhooks"),
+        overview: flags.Bool("overview", false, "in serve mode, serve an overview of the instances that links to a page per instance"),
+        shutdownTimeout: flags.Duration("shutdown-timeout", 30 * time.Second, "in serve mode, the time to wait for in-flight requests to finish on SIGTERM"),
+        watchConfig: flags.Duration("watch-config", 0, "in serve mode, the interval at which to check the config file for changes and reload it, or 0 to only reload it on SIGHUP"),
+    }
+}
+
+// serve serves the dashboard at the given address until the process is interrupted and returns the process's exit
+// code. If the config was read from a file, it is reloaded on SIGHUP and, if so configured, whenever the file changes.
+func (f *serveFlags) serve(common *commonFlags, config *jenkins.Config, options render.Options, addr string) int {
+    auth, err := server.ParseAuthConfig(config.Object)
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "invalid config: %s\n", err)
+        return -1
+    }
+    notifiers, err := notify.ParseConfig(config.Object)
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "invalid config: %s\n", err)
+        return -1
+    }
+    alerter, err := notify.ParseAlerts(config.Object)
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "invalid config: %s\n", err)
+        return -1
+    }
+
+    ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+    defer stop()
+
+    options.Overview = *f.overview
+    options.PageSize = config.PageSize
+    s := server.New(config, options)
+    s.SetNotifiers(notifiers)
+    s.SetAlerter(alerter)
+    ran := make(chan struct{})
+    go func() {
+        s.Run(ctx, *f.refresh)
+        close(ran)
+    }()
+
+    if *common.config != "-" {
+        go f.reload(ctx, common, s, options)
+    }
+
+    handler := s.Handler()
+    if auth != nil {
+        handler = auth.Wrap(handler)
+    } else {
+        for _, d := range config.Dashboards {
+            if d.Restricted() {
+                slog.Warn("dashboard is restricted but the config has no auth section, so no one can see it", "dashboard", d.Name)
+            }
+        }
+    }
+    httpServer := &http.Server{Addr: addr, Handler: handler}
+
+    // On SIGTERM, stop accepting requests, finish those in flight, and then wait for any refreshes to finish.
+    shutDown := make(chan struct{})
+    go func() {
+        <-ctx.Done()
+        slog.Info("shutting down")
+
+        shutdownCtx, cancel := context.WithTimeout(context.Background(), *f.shutdownTimeout)
+        defer cancel()
+        if err := httpServer.Shutdown(shutdownCtx); err != nil {
+            slog.Warn("error shutting down", "err", err)
+        }
+        close(shutDown)
+    }()
+
+    slog.Info("serving dashboard", "addr", addr)
+    if err := httpServer.ListenAndServe(); err != http.ErrServerClosed {
+        fmt.Fprintf(os.Stderr, "could not serve dashboard: %s\n", err)
+        return -1
+    }
+    <-shutDown
+    <-ran
+    s.Wait()
+    return 0
+}
+
+// reload reloads the config on SIGHUP or when the config file changes until the given context is done. The render
+// options given on the command line are kept.
+func (f *serveFlags) reload(ctx context.Context, common *commonFlags, s *server.Server, flagOptions render.Options) {
+    hup := make(chan os.Signal, 1)
+    signal.Notify(hup, syscall.SIGHUP)
+    defer signal.Stop(hup)
+
+    var changed <-chan time.Time
+    var modTime time.Time
+    if *f.watchConfig > 0 {
+        if info, err := os.Stat(*common.config); err == nil {
+            modTime = info.ModTime()
+        }
+        ticker := time.NewTicker(*f.watchConfig)
+        defer ticker.Stop()
+        changed = ticker.C
+    }
+
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-hup:
+        case <-changed:
+            info, err := os.Stat(*common.config)
+            if err != nil || info.ModTime().Equal(modTime) {
+                continue
+            }
+            modTime = info.ModTime()
+        }
+
+        config, err := common.readConfig()
+        if err != nil {
+            slog.Error("could not reload config", "err", err)
+            continue
+        }
+        notifiers, err := notify.ParseConfig(config.Object)
+        if err != nil {
+            slog.Error("could not reload config", "err", err)
+            continue
+        }
+        alerter, err := notify.ParseAlerts(config.Object)
+        if err != nil {
+            slog.Error("could not reload config", "err", err)
+            continue
+        }
+        options, err := renderOptions(config)
+        if err != nil {
+            slog.Error("could not reload config", "err", err)
+            continue
+        }
+        slog.Info("reloading config", "instances", len(config.Instances))
+
+        options.OnlyFailing = flagOptions.OnlyFailing
+        options.Overview = flagOptions.Overview
+        options.PageSize = config.PageSize
+        s.SetNotifiers(notifiers)
+        s.SetAlerter(alerter)
+        s.Reload(config, options)
+    }
+}
+
+// serveMain implements the serve subcommand, which periodically fetches the configured instances and serves a live
+// dashboard over HTTP. It returns the process's exit code.
+func serveMain(args []string) int {
+    flags := flag.NewFlagSet("serve", flag.ExitOnError)
+    common := addCommonFlags(flags)
+    addr := flags.String("addr", "localhost:8080", "the address to serve the dashboard at")
+    onlyFailing := flags.Bool("only-failing", false, "only show jobs whose latest completed build failed")
+    serving := addServeFlags(flags)
+    flags.Usage = func() {
+        fmt.Fprintf(flags.Output(), "usage: jitdash serve [flags]\n\n")
+        fmt.Fprintf(flags.Output(), "Serves a live dashboard of the configured instances.\n")
+        flags.PrintDefaults()
+    }
+    flags.Parse(args)
+
+    config, err := common.setup()
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "%s\n", err)
+        return -1
+    }
+
+    options, err := renderOptions(config)
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "invalid config: %s\n", err)
+        return -1
+    }
+    options.OnlyFailing = *onlyFailing
+    return serving.serve(common, config, options, *addr)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+    "flag"
+    "fmt"
+    "os"
+
+    "github.com/pgavlin/jitdash/pkg/jenkins"
+)
+
+// fetchMain implements the fetch subcommand, which fetches the configured instances and writes a snapshot of them to
+// stdout or to a file. It returns the process's exit code.
+func fetchMain(args []string) int {
+    flags := flag.NewFlagSet("fetch", flag.ExitOnError)
+    common := addCommonFlags(flags)
+    out := flags.String("o", "-", "the file to write the snapshot to, or - to write it to stdout")
+    summary := flags.Bool("summary", false, "write a JSON summary of failing jobs per instance to stderr")
+    errorExitCode := flags.Int("error-exit-code", 2, "the exit code to use if any data could not be fetched")
+    authExitCode := flags.Int("auth-exit-code", 3, "the exit code to use if any instance rejected its credentials or required credentials")
+    failIfRed := flags.Bool("fail-if-red", false, "exit with code 1 if any job's latest completed build failed")
+    flags.Usage = func() {
+        fmt.Fprintf(flags.Output(), "usage: jitdash fetch [flags]\n\n")
+        fmt.Fprintf(flags.Output(), "Fetches the configured instances and writes a snapshot that can be rendered with\n")
+        fmt.Fprintf(flags.Output(), "jitdash render or compared with jitdash diff.\n")
+        flags.PrintDefaults()
+    }
+    flags.Parse(args)
+
+    config, err := common.setup()
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "%s\n", err)
+        return -1
+    }
+
+    instances := jenkins.Fetch(config.Instances, config.MaxBuilds, config.Workers)
+    publishMetrics(config, instances)
+    if *out == "-" {
+        err = jenkins.WriteSnapshot(os.Stdout, instances)
+    } else {
+        err = writeSnapshot(*out, instances)
+    }
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "could not write snapshot: %s\n", err)
+        return -1
+    }
+
+    output := &outputFlags{summary: summary, errorExitCode: errorExitCode, authExitCode: authExitCode, failIfRed: failIfRed}
+    return output.exitCode(instances)
+}
+
+// renderMain implements the render subcommand, which renders a snapshot written by jitdash fetch. It returns the
+// process's exit code.
+func renderMain(args []string) int {
+    flags := flag.NewFlagSet("render", flag.ExitOnError)
+    common := addCommonFlags(flags)
+    output := addOutputFlags(flags)
+    flags.Usage = func() {
+        fmt.Fprintf(flags.Output(), "usage: jitdash render [flags] [snapshot.json]\n\n")
+        fmt.Fprintf(flags.Output(), "Renders a snapshot written by jitdash fetch. If no snapshot is named, it is read from\n")
+        fmt.Fprintf(flags.Output(), "stdin, and the config must be given with -config.\n")
+        flags.PrintDefaults()
+    }
+    flags.Parse(args)
+    if flags.NArg() > 1 {
+        flags.Usage()
+        return 2
+    }
+
+    path := flags.Arg(0)
+    if path == "" {
+        path = "-"
+    }
+    if path == "-" && *common.config == "-" {
+        fmt.Fprintf(os.Stderr, "the snapshot and the config cannot both be read from stdin\n")
+        return 2
+    }
+
+    config, err := common.setup()
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "%s\n", err)
+        return -1
+    }
+
+    var instances []*jenkins.InstanceJobs
+    if path == "-" {
+        instances, err = jenkins.ReadSnapshot(os.Stdin, config.Instances)
+    } else {
+        instances, err = readSnapshot(path, config.Instances)
+    }
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "could not read snapshot: %s\n", err)
+        return -1
+    }
+
+    return output.write(config, instances)
+}
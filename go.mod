@@ -0,0 +1,3 @@
+module github.com/pgavlin/jitdash
+
+go 1.21
@@ -0,0 +1,277 @@
+// Package azuredevops implements a jitdash backend that fetches pipeline runs from Azure DevOps. Each configured
+// pipeline is presented as a job whose builds are the pipeline's runs.
+//
+// Importing this package registers the backend for instances of type "azuredevops":
+//
+//     "my-org": {
+//         "type": "azuredevops",
+//         "organization": "my-org",
+//         "project": "my-project",
+//         "pipelines": [12, 34],
+//         "token": "..."
+//     }
+//
+// The token is a personal access token with read access to builds and test results.
+package azuredevops
+
+import (
+    "encoding/base64"
+    "errors"
+    "fmt"
+    "net/http"
+    "net/url"
+    "sort"
+    "strconv"
+    "sync"
+    "time"
+
+    "github.com/pgavlin/jitdash/pkg/jenkins"
+)
+
+const apiVersion = "7.1"
+
+func init() {
+    jenkins.RegisterBackend("azuredevops", newBackend)
+}
+
+type backend struct {
+    apiUrl string // the base URL of the REST API
+    testUrl string // the base URL of the test results API
+    token string
+    organization string
+    project string
+    pipelines []int64
+
+    m sync.Mutex
+    runs map[string]jenkins.JsonObject // the runs listed by the most recent FetchJobs, by URL
+}
+
+func newBackend(i *jenkins.Instance, config jenkins.JsonObject) (jenkins.Backend, error) {
+    b := &backend{
+        apiUrl: "https://dev.azure.com",
+        testUrl: "https://vstmr.dev.azure.com",
+    }
+
+    if u, ok := config.GetString("url"); ok {
+        b.apiUrl, b.testUrl = u, u
+    }
+    if u, ok := config.GetString("testUrl"); ok {
+        b.testUrl = u
+    }
+
+    b.token, _ = config.GetString("token")
+
+    organization, ok := config.GetString("organization")
+    if !ok {
+        return nil, errors.New("no organization")
+    }
+    b.organization = organization
+
+    project, ok := config.GetString("project")
+    if !ok {
+        return nil, errors.New("no project")
+    }
+    b.project = project
+
+    pipelinesArray, ok := config.GetArray("pipelines")
+    if !ok {
+        return nil, errors.New("no pipelines")
+    }
+    for _, p := range pipelinesArray {
+        pipeline, ok := jenkins.AsInt64(p)
+        if !ok {
+            return nil, errors.New(fmt.Sprintf("invalid pipeline: %v", p))
+        }
+        b.pipelines = append(b.pipelines, pipeline)
+    }
+
+    return b, nil
+}
+
+func (b *backend) header() http.Header {
+    header := http.Header{}
+    if b.token != "" {
+        header.Set("Authorization", "Basic " + base64.StdEncoding.EncodeToString([]byte(":" + b.token)))
+    }
+    return header
+}
+
+func (b *backend) get(i *jenkins.Instance, url string) (jenkins.JsonObject, error) {
+    var object jenkins.JsonObject
+    if err := jenkins.FetchJson(i.Client, url, b.header(), &object); err != nil {
+        return nil, err
+    }
+    return object, nil
+}
+
+func (b *backend) pipelineUrl(pipeline string) string {
+    return fmt.Sprintf("%s/%s/%s/_apis/pipelines/%s", b.apiUrl, url.PathEscape(b.organization), url.PathEscape(b.project), pipeline)
+}
+
+func webLink(o jenkins.JsonObject) (string, bool) {
+    links, ok := o.GetObject("_links")
+    if !ok {
+        return "", false
+    }
+    web, ok := links.GetObject("web")
+    if !ok {
+        return "", false
+    }
+    return web.GetString("href")
+}
+
+// FetchJobs fetches the configured pipelines and lists their runs. The listed runs carry their states and results, so
+// they are kept for FetchDetails.
+func (b *backend) FetchJobs(i *jenkins.Instance) ([]*jenkins.Job, []*jenkins.FetchError) {
+    var jobs []*jenkins.Job
+    var errs []*jenkins.FetchError
+    listed := make(map[string]jenkins.JsonObject)
+    defer func() {
+        b.m.Lock()
+        defer b.m.Unlock()
+        b.runs = listed
+    }()
+
+    for _, id := range b.pipelines {
+        pipeline := strconv.FormatInt(id, 10)
+        pipelineUrl := b.pipelineUrl(pipeline)
+
+        details, err := b.get(i, pipelineUrl + "?api-version=" + apiVersion)
+        if err != nil {
+            errs = append(errs, i.NewFetchError(pipeline, pipelineUrl, err))
+            continue
+        }
+
+        name, ok := details.GetString("name")
+        if !ok {
+            name = pipeline
+        }
+        if i.IsExcluded(name) {
+            continue
+        }
+
+        i.Logger().Info("fetching pipeline", "pipeline", name)
+
+        jobUrl, _ := webLink(details)
+
+        runs, err := b.get(i, pipelineUrl + "/runs?api-version=" + apiVersion)
+        if err != nil {
+            errs = append(errs, i.NewFetchError(name, pipelineUrl + "/runs", err))
+            continue
+        }
+
+        var builds []*jenkins.Build
+        runObjects, _ := runs.GetArray("value")
+        for _, r := range runObjects {
+            run, ok := jenkins.AsJsonObject(r)
+            if !ok {
+                continue
+            }
+
+            runId, ok := run.GetInt64("id")
+            if !ok {
+                continue
+            }
+
+            runUrl, ok := webLink(run)
+            if !ok {
+                continue
+            }
+
+            listed[runUrl] = run
+            builds = append(builds, &jenkins.Build{Id: runId, Url: runUrl, BackendId: pipeline})
+        }
+
+        sort.Sort(jenkins.BuildSorter(builds))
+        jobs = append(jobs, &jenkins.Job{Name: name, Url: jobUrl, Builds: builds})
+    }
+    return jobs, errs
+}
+
+// testFailures returns the number of failed tests reported for the given run.
+func (b *backend) testFailures(i *jenkins.Instance, runId int64) (int64, error) {
+    summaryUrl := fmt.Sprintf("%s/%s/%s/_apis/testresults/resultsummarybybuild?buildId=%d&api-version=%s-preview.1",
+        b.testUrl, url.PathEscape(b.organization), url.PathEscape(b.project), runId, apiVersion)
+
+    summary, err := b.get(i, summaryUrl)
+    if err != nil {
+        return 0, err
+    }
+
+    analysis, ok := summary.GetObject("aggregatedResultsAnalysis")
+    if !ok {
+        return 0, nil
+    }
+    byOutcome, ok := analysis.GetObject("resultsByOutcome")
+    if !ok {
+        return 0, nil
+    }
+    for _, key := range []string{"failed", "Failed"} {
+        if failed, ok := byOutcome.GetObject(key); ok {
+            count, _ := failed.GetInt64("count")
+            return count, nil
+        }
+    }
+    return 0, nil
+}
+
+// runResults maps the results of completed runs to build results. Runs that succeeded with issues, e.g. with failed
+// tests or a task that is allowed to fail, are unstable.
+var runResults = map[string]jenkins.Result{
+    "succeeded": jenkins.ResultSuccess,
+    "partiallySucceeded": jenkins.ResultUnstable,
+    "failed": jenkins.ResultFailure,
+    "canceled": jenkins.ResultAborted,
+}
+
+// FetchDetails fills in the given build from its run as listed by FetchJobs, or fetches the run if it was not listed.
+func (b *backend) FetchDetails(i *jenkins.Instance, build *jenkins.Build) error {
+    b.m.Lock()
+    run, ok := b.runs[build.Url]
+    b.m.Unlock()
+
+    if !ok {
+        var err error
+        run, err = b.get(i, fmt.Sprintf("%s/runs/%d?api-version=%s", b.pipelineUrl(build.BackendId), build.Id, apiVersion))
+        if err != nil {
+            return err
+        }
+    }
+
+    state, ok := run.GetString("state")
+    if !ok {
+        return errors.New("missing state")
+    }
+    build.Complete = state == "completed"
+
+    if created, ok := run.GetString("createdDate"); ok {
+        if t, err := time.Parse(time.RFC3339, created); err == nil {
+            build.Timestamp = t.UTC()
+        }
+    }
+    if finished, ok := run.GetString("finishedDate"); ok && build.Complete {
+        if t, err := time.Parse(time.RFC3339, finished); err == nil {
+            build.Duration = t.Sub(build.Timestamp)
+        }
+    }
+
+    result, _ := run.GetString("result")
+    if build.Complete {
+        build.Result = runResults[result]
+    }
+    if result != "failed" && result != "partiallySucceeded" {
+        build.Failures = 0
+        return nil
+    }
+
+    failures, err := b.testFailures(i, build.Id)
+    if err != nil {
+        i.Logger().Debug("error fetching test results", "build", build.Url, "err", err)
+    }
+    if failures == 0 {
+        failures = -1
+    }
+
+    build.Failures = failures
+    return nil
+}
@@ -0,0 +1,225 @@
+// Package buildkite implements a jitdash backend that fetches builds from Buildkite. Each configured pipeline is
+// presented as a job.
+//
+// Importing this package registers the backend for instances of type "buildkite":
+//
+//     "my-org": {
+//         "type": "buildkite",
+//         "org": "my-org",
+//         "pipelines": ["pipeline-a", "pipeline-b"],
+//         "branch": "main",
+//         "token": "..."
+//     }
+package buildkite
+
+import (
+    "errors"
+    "fmt"
+    "net/http"
+    "net/url"
+    "sort"
+    "strconv"
+    "time"
+
+    "github.com/pgavlin/jitdash/pkg/jenkins"
+)
+
+func init() {
+    jenkins.RegisterBackend("buildkite", newBackend)
+}
+
+type backend struct {
+    apiUrl string // the base URL of the v2 REST API
+    webUrl string // the base URL of the web UI, used for links
+    token string
+    org string
+    pipelines []string
+    branch string
+    maxBuilds int // the number of most recent builds to list per pipeline
+}
+
+func newBackend(i *jenkins.Instance, config jenkins.JsonObject) (jenkins.Backend, error) {
+    b := &backend{
+        apiUrl: "https://api.buildkite.com/v2",
+        webUrl: "https://buildkite.com",
+        maxBuilds: 30,
+    }
+
+    if u, ok := config.GetString("url"); ok {
+        b.apiUrl = u
+    }
+    if u, ok := config.GetString("webUrl"); ok {
+        b.webUrl = u
+    }
+
+    b.token, _ = config.GetString("token")
+
+    org, ok := config.GetString("org")
+    if !ok {
+        return nil, errors.New("no org")
+    }
+    b.org = org
+
+    pipelinesArray, ok := config.GetArray("pipelines")
+    if !ok {
+        return nil, errors.New("no pipelines")
+    }
+    for _, p := range pipelinesArray {
+        pipeline, ok := p.(string)
+        if !ok {
+            return nil, errors.New(fmt.Sprintf("invalid pipeline: %v", p))
+        }
+        b.pipelines = append(b.pipelines, pipeline)
+    }
+
+    b.branch, _ = config.GetString("branch")
+
+    if maxBuilds, ok := config.GetInt64("maxBuilds"); ok {
+        b.maxBuilds = int(maxBuilds)
+    }
+
+    return b, nil
+}
+
+func (b *backend) header() http.Header {
+    header := http.Header{}
+    if b.token != "" {
+        header.Set("Authorization", "Bearer " + b.token)
+    }
+    return header
+}
+
+func (b *backend) pipelineUrl(pipeline string) string {
+    return fmt.Sprintf("%s/organizations/%s/pipelines/%s", b.apiUrl, b.org, pipeline)
+}
+
+func (b *backend) FetchJobs(i *jenkins.Instance) ([]*jenkins.Job, []*jenkins.FetchError) {
+    var jobs []*jenkins.Job
+    var errs []*jenkins.FetchError
+    for _, pipeline := range b.pipelines {
+        if i.IsExcluded(pipeline) {
+            continue
+        }
+
+        i.Logger().Info("fetching pipeline", "pipeline", pipeline)
+
+        query := url.Values{}
+        query.Set("per_page", strconv.Itoa(b.maxBuilds))
+        if b.branch != "" {
+            query.Set("branch", b.branch)
+        }
+
+        buildsUrl := b.pipelineUrl(pipeline) + "/builds?" + query.Encode()
+
+        var buildObjects []interface{}
+        if err := jenkins.FetchJson(i.Client, buildsUrl, b.header(), &buildObjects); err != nil {
+            errs = append(errs, i.NewFetchError(pipeline, buildsUrl, err))
+            continue
+        }
+
+        var builds []*jenkins.Build
+        for _, bo := range buildObjects {
+            buildObject, ok := jenkins.AsJsonObject(bo)
+            if !ok {
+                continue
+            }
+
+            number, ok := buildObject.GetInt64("number")
+            if !ok {
+                continue
+            }
+
+            webUrl, ok := buildObject.GetString("web_url")
+            if !ok {
+                continue
+            }
+
+            builds = append(builds, &jenkins.Build{Id: number, Url: webUrl, BackendId: pipeline})
+        }
+
+        sort.Sort(jenkins.BuildSorter(builds))
+        jobs = append(jobs, &jenkins.Job{Name: pipeline, Url: b.webUrl + "/" + b.org + "/" + pipeline, Builds: builds})
+    }
+    return jobs, errs
+}
+
+// completeStates is the set of build states that indicate that a build has finished.
+var completeStates = map[string]bool{
+    "passed": true,
+    "failed": true,
+    "canceled": true,
+    "skipped": true,
+    "not_run": true,
+}
+
+// stateResults maps the states of completed builds to build results.
+var stateResults = map[string]jenkins.Result{
+    "passed": jenkins.ResultSuccess,
+    "failed": jenkins.ResultFailure,
+    "canceled": jenkins.ResultAborted,
+    "skipped": jenkins.ResultNotBuilt,
+    "not_run": jenkins.ResultNotBuilt,
+}
+
+// failedJobStates is the set of job states that count as failures.
+var failedJobStates = map[string]bool{
+    "failed": true,
+    "timed_out": true,
+}
+
+func (b *backend) FetchDetails(i *jenkins.Instance, build *jenkins.Build) error {
+    buildUrl := fmt.Sprintf("%s/builds/%d", b.pipelineUrl(build.BackendId), build.Id)
+
+    var details jenkins.JsonObject
+    if err := jenkins.FetchJson(i.Client, buildUrl, b.header(), &details); err != nil {
+        return err
+    }
+
+    state, ok := details.GetString("state")
+    if !ok {
+        return errors.New("missing state")
+    }
+    build.Complete = completeStates[state]
+    build.Result = stateResults[state]
+
+    timestamp, ok := details.GetString("started_at")
+    if !ok {
+        timestamp, ok = details.GetString("created_at")
+    }
+    if ok {
+        if t, err := time.Parse(time.RFC3339, timestamp); err == nil {
+            build.Timestamp = t.UTC()
+        }
+    }
+    if finished, ok := details.GetString("finished_at"); ok && build.Complete {
+        if t, err := time.Parse(time.RFC3339, finished); err == nil {
+            build.Duration = t.Sub(build.Timestamp)
+        }
+    }
+
+    build.Commit, _ = details.GetString("commit")
+    if message, ok := details.GetString("message"); ok {
+        build.Change = jenkins.FirstLine(message)
+    }
+
+    if state != "failed" {
+        build.Failures = 0
+        return nil
+    }
+
+    var failures int64
+    jobs, _ := details.GetArray("jobs")
+    for _, j := range jobs {
+        if job, ok := jenkins.AsJsonObject(j); ok {
+            if s, _ := job.GetString("state"); failedJobStates[s] {
+                failures++
+            }
+        }
+    }
+    if failures == 0 {
+        failures = -1
+    }
+
+    build.Failures = failures
+    return nil
+}
@@ -0,0 +1,303 @@
+// Package circleci implements a jitdash backend that fetches workflow runs from CircleCI. Each workflow of each
+// configured project is presented as a job whose builds are the workflow's runs.
+//
+// Importing this package registers the backend for instances of type "circleci":
+//
+//     "my-org": {
+//         "type": "circleci",
+//         "org": "gh/my-org",
+//         "projects": ["repo-a", "repo-b"],
+//         "branch": "main",
+//         "token": "..."
+//     }
+package circleci
+
+import (
+    "errors"
+    "fmt"
+    "net/http"
+    "net/url"
+    "sort"
+    "time"
+
+    "github.com/pgavlin/jitdash/pkg/jenkins"
+)
+
+func init() {
+    jenkins.RegisterBackend("circleci", newBackend)
+}
+
+type backend struct {
+    apiUrl string // the base URL of the v2 API
+    appUrl string // the base URL of the web app, used for links
+    token string
+    org string // the VCS type and organization, e.g. "gh/my-org"
+    projects []string
+    branch string
+    maxPipelines int // the number of most recent pipelines to consider per project
+}
+
+func newBackend(i *jenkins.Instance, config jenkins.JsonObject) (jenkins.Backend, error) {
+    b := &backend{
+        apiUrl: "https://circleci.com/api/v2",
+        appUrl: "https://app.circleci.com",
+        maxPipelines: 20,
+    }
+
+    if u, ok := config.GetString("url"); ok {
+        b.apiUrl = u
+    }
+    if u, ok := config.GetString("appUrl"); ok {
+        b.appUrl = u
+    }
+
+    b.token, _ = config.GetString("token")
+
+    org, ok := config.GetString("org")
+    if !ok {
+        return nil, errors.New("no org")
+    }
+    b.org = org
+
+    projectsArray, ok := config.GetArray("projects")
+    if !ok {
+        return nil, errors.New("no projects")
+    }
+    for _, p := range projectsArray {
+        project, ok := p.(string)
+        if !ok {
+            return nil, errors.New(fmt.Sprintf("invalid project: %v", p))
+        }
+        b.projects = append(b.projects, project)
+    }
+
+    b.branch, _ = config.GetString("branch")
+
+    if maxPipelines, ok := config.GetInt64("maxPipelines"); ok {
+        b.maxPipelines = int(maxPipelines)
+    }
+
+    return b, nil
+}
+
+func (b *backend) get(i *jenkins.Instance, url string) (jenkins.JsonObject, error) {
+    header := http.Header{}
+    if b.token != "" {
+        header.Set("Circle-Token", b.token)
+    }
+
+    var object jenkins.JsonObject
+    if err := jenkins.FetchJson(i.Client, url, header, &object); err != nil {
+        return nil, err
+    }
+    return object, nil
+}
+
+// pipelines fetches the most recent pipelines for the given project slug.
+func (b *backend) pipelines(i *jenkins.Instance, slug string) ([]jenkins.JsonObject, error) {
+    var pipelines []jenkins.JsonObject
+    pageToken := ""
+    for len(pipelines) < b.maxPipelines {
+        query := url.Values{}
+        if b.branch != "" {
+            query.Set("branch", b.branch)
+        }
+        if pageToken != "" {
+            query.Set("page-token", pageToken)
+        }
+
+        page, err := b.get(i, fmt.Sprintf("%s/project/%s/pipeline?%s", b.apiUrl, slug, query.Encode()))
+        if err != nil {
+            return nil, err
+        }
+
+        items, _ := page.GetArray("items")
+        for _, item := range items {
+            if pipeline, ok := jenkins.AsJsonObject(item); ok && len(pipelines) < b.maxPipelines {
+                pipelines = append(pipelines, pipeline)
+            }
+        }
+
+        pageToken, _ = page.GetString("next_page_token")
+        if pageToken == "" || len(items) == 0 {
+            break
+        }
+    }
+    return pipelines, nil
+}
+
+func (b *backend) FetchJobs(i *jenkins.Instance) ([]*jenkins.Job, []*jenkins.FetchError) {
+    var jobs []*jenkins.Job
+    var errs []*jenkins.FetchError
+    for _, project := range b.projects {
+        slug := b.org + "/" + project
+        i.Logger().Info("fetching project", "project", slug)
+
+        pipelines, err := b.pipelines(i, slug)
+        if err != nil {
+            errs = append(errs, i.NewFetchError(project, b.apiUrl + "/project/" + slug, err))
+            continue
+        }
+
+        // Each workflow name becomes a job. Jobs are ordered by the first appearance of their workflow.
+        projectJobs := make(map[string]*jenkins.Job)
+        var projectOrder []string
+        for _, pipeline := range pipelines {
+            id, ok := pipeline.GetString("id")
+            if !ok {
+                continue
+            }
+
+            workflowsUrl := b.apiUrl + "/pipeline/" + id + "/workflow"
+            workflows, err := b.get(i, workflowsUrl)
+            if err != nil {
+                errs = append(errs, i.NewFetchError(project, workflowsUrl, err))
+                continue
+            }
+
+            items, _ := workflows.GetArray("items")
+            for _, item := range items {
+                workflow, ok := jenkins.AsJsonObject(item)
+                if !ok {
+                    continue
+                }
+
+                build, name, ok := b.processWorkflow(slug, workflow)
+                if !ok {
+                    continue
+                }
+                if vcs, ok := pipeline.GetObject("vcs"); ok {
+                    build.Commit, _ = vcs.GetString("revision")
+                    if commit, ok := vcs.GetObject("commit"); ok {
+                        subject, _ := commit.GetString("subject")
+                        build.Change = jenkins.FirstLine(subject)
+                    }
+                }
+
+                name = project + "/" + name
+                if i.IsExcluded(name) {
+                    continue
+                }
+
+                job, ok := projectJobs[name]
+                if !ok {
+                    job = &jenkins.Job{Name: name, Url: b.appUrl + "/pipelines/" + slug}
+                    projectJobs[name] = job
+                    projectOrder = append(projectOrder, name)
+                }
+                job.Builds = append(job.Builds, build)
+            }
+        }
+
+        for _, name := range projectOrder {
+            job := projectJobs[name]
+            sort.Sort(jenkins.BuildSorter(job.Builds))
+            jobs = append(jobs, job)
+        }
+    }
+    return jobs, errs
+}
+
+// processWorkflow maps a workflow run onto a build. The build's failure count is filled in by FetchDetails.
+func (b *backend) processWorkflow(slug string, workflow jenkins.JsonObject) (*jenkins.Build, string, bool) {
+    id, ok := workflow.GetString("id")
+    if !ok {
+        return nil, "", false
+    }
+
+    name, ok := workflow.GetString("name")
+    if !ok {
+        return nil, "", false
+    }
+
+    number, ok := workflow.GetInt64("pipeline_number")
+    if !ok {
+        return nil, "", false
+    }
+
+    build := &jenkins.Build{
+        Id: number,
+        Url: fmt.Sprintf("%s/pipelines/%s/%d/workflows/%s", b.appUrl, slug, number, id),
+        BackendId: id,
+    }
+
+    if createdAt, ok := workflow.GetString("created_at"); ok {
+        if t, err := time.Parse(time.RFC3339, createdAt); err == nil {
+            build.Timestamp = t.UTC()
+        }
+    }
+
+    return build, name, true
+}
+
+// failedJobStatuses is the set of job statuses that count as failures.
+var failedJobStatuses = map[string]bool{
+    "failed": true,
+    "infrastructure_fail": true,
+    "timedout": true,
+}
+
+// completeWorkflowStatuses is the set of workflow statuses that indicate that a workflow has finished.
+var completeWorkflowStatuses = map[string]bool{
+    "success": true,
+    "failed": true,
+    "error": true,
+    "canceled": true,
+    "unauthorized": true,
+    "not_run": true,
+}
+
+// workflowResults maps the statuses of completed workflows to build results.
+var workflowResults = map[string]jenkins.Result{
+    "success": jenkins.ResultSuccess,
+    "failed": jenkins.ResultFailure,
+    "error": jenkins.ResultFailure,
+    "canceled": jenkins.ResultAborted,
+    "unauthorized": jenkins.ResultNotBuilt,
+    "not_run": jenkins.ResultNotBuilt,
+}
+
+func (b *backend) FetchDetails(i *jenkins.Instance, build *jenkins.Build) error {
+    workflow, err := b.get(i, b.apiUrl + "/workflow/" + build.BackendId)
+    if err != nil {
+        return err
+    }
+
+    status, ok := workflow.GetString("status")
+    if !ok {
+        return errors.New("missing status")
+    }
+    build.Complete = completeWorkflowStatuses[status]
+    build.Result = workflowResults[status]
+    if stopped, ok := workflow.GetString("stopped_at"); ok && build.Complete {
+        if t, err := time.Parse(time.RFC3339, stopped); err == nil {
+            build.Duration = t.Sub(build.Timestamp)
+        }
+    }
+
+    if status != "failed" && status != "error" {
+        build.Failures = 0
+        return nil
+    }
+
+    jobs, err := b.get(i, b.apiUrl + "/workflow/" + build.BackendId + "/job")
+    if err != nil {
+        return err
+    }
+
+    var failures int64
+    items, _ := jobs.GetArray("items")
+    for _, item := range items {
+        if job, ok := jenkins.AsJsonObject(item); ok {
+            if s, _ := job.GetString("status"); failedJobStatuses[s] {
+                failures++
+            }
+        }
+    }
+    if failures == 0 {
+        failures = -1
+    }
+
+    build.Failures = failures
+    return nil
+}
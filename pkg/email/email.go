@@ -0,0 +1,109 @@
+// Package email delivers rendered digests by email. Delivery is configured by the "email" section of the jitdash
+// configuration:
+//
+//     "email": {
+//         "smtp": "smtp.example.com:587",
+//         "username": "jitdash",
+//         "password": "...",
+//         "from": "jitdash@example.com",
+//         "to": ["team@example.com"],
+//         "subject": "Build health"
+//     }
+//
+// If no SMTP server is configured, the message is written to stdout so that it can be piped to `sendmail -t`.
+package email
+
+import (
+    "bytes"
+    "errors"
+    "fmt"
+    "io"
+    "mime"
+    "net"
+    "net/smtp"
+    "strings"
+    "time"
+
+    "github.com/pgavlin/jitdash/pkg/jenkins"
+)
+
+// Config describes how to deliver a digest.
+type Config struct {
+    Smtp string // the address of the SMTP server, or empty to write the message out for sendmail
+    Username string // the SMTP username, or empty to skip authentication
+    Password string
+    From string
+    To []string
+    Subject string
+}
+
+// ParseConfig parses the "email" section of the given configuration object.
+func ParseConfig(config jenkins.JsonObject) (*Config, error) {
+    emailObject, ok := config.GetObject("email")
+    if !ok {
+        return nil, errors.New("no email configuration")
+    }
+
+    c := &Config{}
+    c.Smtp, _ = emailObject.GetString("smtp")
+    c.Username, _ = emailObject.GetString("username")
+    c.Password, _ = emailObject.GetString("password")
+
+    c.From, ok = emailObject.GetString("from")
+    if !ok {
+        return nil, errors.New("email has no sender")
+    }
+
+    toArray, _ := emailObject.GetArray("to")
+    for _, t := range toArray {
+        to, ok := t.(string)
+        if !ok {
+            return nil, errors.New(fmt.Sprintf("recipient %v is not a string", t))
+        }
+        c.To = append(c.To, to)
+    }
+    if len(c.To) == 0 {
+        return nil, errors.New("email has no recipients")
+    }
+
+    c.Subject, ok = emailObject.GetString("subject")
+    if !ok {
+        c.Subject = "Build health"
+    }
+
+    return c, nil
+}
+
+// Message composes a MIME message with the given HTML body.
+func (c *Config) Message(body []byte) []byte {
+    b := new(bytes.Buffer)
+    fmt.Fprintf(b, "From: %s\r\n", c.From)
+    fmt.Fprintf(b, "To: %s\r\n", strings.Join(c.To, ", "))
+    fmt.Fprintf(b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", c.Subject))
+    fmt.Fprintf(b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+    fmt.Fprintf(b, "MIME-Version: 1.0\r\n")
+    fmt.Fprintf(b, "Content-Type: text/html; charset=utf-8\r\n")
+    fmt.Fprintf(b, "\r\n")
+    b.Write(body)
+    return b.Bytes()
+}
+
+// Deliver sends a message with the given HTML body. If an SMTP server is configured, the message is sent through it;
+// otherwise, the message is written to w.
+func (c *Config) Deliver(w io.Writer, body []byte) error {
+    message := c.Message(body)
+    if c.Smtp == "" {
+        _, err := w.Write(message)
+        return err
+    }
+
+    var auth smtp.Auth
+    if c.Username != "" {
+        host, _, err := net.SplitHostPort(c.Smtp)
+        if err != nil {
+            return err
+        }
+        auth = smtp.PlainAuth("", c.Username, c.Password, host)
+    }
+    return smtp.SendMail(c.Smtp, auth, c.From, c.To, message)
+}
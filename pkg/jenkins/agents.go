@@ -0,0 +1,69 @@
+package jenkins
+
+// Agent describes a Jenkins agent (or the built-in node).
+type Agent struct {
+    Name string
+    Offline bool
+    OfflineReason string // if the agent is offline, the reason given for it, if any
+    Executors int // the number of executors on the agent
+    Idle bool // true if none of the agent's executors are busy
+}
+
+// AgentStatus summarizes the agents of an instance.
+type AgentStatus struct {
+    Agents []*Agent
+    BusyExecutors int
+    TotalExecutors int
+}
+
+// FetchAgents fetches the status of the agents of the instance's servers.
+func (i *Instance) FetchAgents() (*AgentStatus, []*FetchError) {
+    status := &AgentStatus{}
+    var errs []*FetchError
+    for _, root := range i.RootUrls() {
+        computerUrl := apiUrl(root, "computer/api/json")
+        computers, err := i.Api().Object(computerUrl)
+        if err != nil {
+            errs = append(errs, i.NewFetchError("", computerUrl, err))
+            continue
+        }
+
+        busy, _ := computers.GetInt64("busyExecutors")
+        total, _ := computers.GetInt64("totalExecutors")
+        status.BusyExecutors += int(busy)
+        status.TotalExecutors += int(total)
+
+        computerObjects, _ := computers.GetArray("computer")
+        for _, c := range computerObjects {
+            computer, ok := AsJsonObject(c)
+            if !ok {
+                continue
+            }
+
+            name, ok := computer.GetString("displayName")
+            if !ok {
+                continue
+            }
+            agent := &Agent{Name: name}
+            agent.Offline, _ = computer.GetBool("offline")
+            agent.OfflineReason, _ = computer.GetString("offlineCauseReason")
+            if executors, ok := computer.GetInt64("numExecutors"); ok {
+                agent.Executors = int(executors)
+            }
+            agent.Idle, _ = computer.GetBool("idle")
+            status.Agents = append(status.Agents, agent)
+        }
+    }
+    return status, errs
+}
+
+// OnlineAgents returns the number of agents that are online.
+func (s *AgentStatus) OnlineAgents() int {
+    online := 0
+    for _, a := range s.Agents {
+        if !a.Offline {
+            online++
+        }
+    }
+    return online
+}
@@ -0,0 +1,69 @@
+package jenkins
+
+import (
+    "errors"
+    "fmt"
+    "net/url"
+    "path"
+    "strings"
+)
+
+// buildArtifacts returns the relative paths of the artifacts that a Jenkins build archived.
+func buildArtifacts(details JsonObject) []string {
+    var artifacts []string
+    artifactObjects, _ := details.GetArray("artifacts")
+    for _, a := range artifactObjects {
+        artifact, ok := AsJsonObject(a)
+        if !ok {
+            continue
+        }
+        if relativePath, ok := artifact.GetString("relativePath"); ok {
+            artifacts = append(artifacts, relativePath)
+        }
+    }
+    return artifacts
+}
+
+// parseArtifactPatterns parses an array of artifact patterns, e.g. ["*.zip", "dist/*.tar.gz"].
+func parseArtifactPatterns(array []interface{}) ([]string, error) {
+    patterns := []string{}
+    for _, p := range array {
+        pattern, ok := p.(string)
+        if !ok {
+            return nil, errors.New(fmt.Sprintf("invalid artifact pattern: %v", p))
+        }
+        if _, err := path.Match(pattern, ""); err != nil {
+            return nil, errors.New(fmt.Sprintf("invalid artifact pattern %s: %s", pattern, err))
+        }
+        patterns = append(patterns, pattern)
+    }
+    return patterns, nil
+}
+
+// ShownArtifacts returns the given artifacts that match any of the instance's artifact patterns, in order. Patterns
+// that contain a slash match artifacts' relative paths; other patterns match their file names.
+func (i *Instance) ShownArtifacts(artifacts []string) []string {
+    var shown []string
+    for _, a := range artifacts {
+        for _, pattern := range i.Artifacts {
+            name := a
+            if !strings.Contains(pattern, "/") {
+                name = path.Base(a)
+            }
+            if ok, _ := path.Match(pattern, name); ok {
+                shown = append(shown, a)
+                break
+            }
+        }
+    }
+    return shown
+}
+
+// ArtifactUrl returns the URL from which the build's artifact with the given relative path can be downloaded.
+func (b *Build) ArtifactUrl(artifact string) string {
+    segments := strings.Split(artifact, "/")
+    for n, s := range segments {
+        segments[n] = url.PathEscape(s)
+    }
+    return apiUrl(b.Url, "artifact/" + strings.Join(segments, "/"))
+}
@@ -0,0 +1,26 @@
+package jenkins
+
+// A Backend fetches jobs and builds from a CI system other than Jenkins. Backends map their system's notion of jobs
+// and runs onto Job and Build so that they can be aggregated and rendered alongside Jenkins instances.
+type Backend interface {
+    // FetchJobs fetches the instance's jobs and their builds. Build details are fetched separately by FetchDetails.
+    FetchJobs(i *Instance) ([]*Job, []*FetchError)
+
+    // FetchDetails fills in the details of the given build.
+    FetchDetails(i *Instance, b *Build) error
+}
+
+// A BackendFactory creates a backend for the given instance from the instance's configuration object. The instance's
+// name, exclusions, groups, and HTTP client have already been configured.
+type BackendFactory func(i *Instance, config JsonObject) (Backend, error)
+
+var backends = map[string]BackendFactory{}
+
+// RegisterBackend registers a backend factory for instances whose configuration specifies the given type. It is
+// intended to be called from the init function of the package that implements the backend.
+func RegisterBackend(kind string, factory BackendFactory) {
+    if _, ok := backends[kind]; ok {
+        panic("jenkins: backend " + kind + " registered twice")
+    }
+    backends[kind] = factory
+}
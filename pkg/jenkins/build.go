@@ -0,0 +1,328 @@
+package jenkins
+
+import (
+    "errors"
+    "fmt"
+    "log/slog"
+    "strings"
+    "time"
+)
+
+// ProcessBuildObject processes a build listed by the job at the given URL.
+func (i* Instance) ProcessBuildObject(jobUrl string, buildIf interface{}) (*Build, bool) {
+    build, ok := AsJsonObject(buildIf)
+    if !ok {
+        return nil, false
+    }
+
+    if class, ok := build.GetString("_class"); !ok || !i.Classes.IsBuild(class) {
+        return nil, false
+    }
+
+    id, ok := build.GetInt64("number")
+    if !ok {
+        return nil, false
+    }
+
+    url, ok := build.GetString("url")
+    if !ok {
+        return nil, false
+    }
+
+    // Start times are listed so that windows can be applied before the details of builds are fetched.
+    b := &Build{Id: id, Url: ResolveUrl(jobUrl, url)}
+    if unixMilliseconds, ok := build.GetInt64("timestamp"); ok {
+        b.Timestamp = time.Unix(unixMilliseconds / 1000, 0).UTC()
+    }
+    return b, true
+}
+
+var missingResultError = errors.New("missing result")
+var missingTimestampError = errors.New("missing timestamp")
+
+// BuildDetails fetches and parses the details of the given build: its timestamp, result, test results, parameters,
+// changes, and artifacts. The names of failed tests, the failed stage of a pipeline, and the coverage and
+// static-analysis warnings of a completed build are fetched as well if the build has them.
+func (c *Client) BuildDetails(b *Build) error {
+    details, err := c.Object(apiUrl(b.Url, "api/json"))
+    if err != nil {
+        return err
+    }
+
+    unixMilliseconds, ok := details.GetInt64("timestamp")
+    if !ok {
+        return missingTimestampError
+    }
+    b.Timestamp = time.Unix(unixMilliseconds / 1000, 0).UTC()
+
+    // The result is null while a build is running.
+    result, hasResult := details.GetString("result")
+
+    building, ok := details.GetBool("building")
+    if !ok {
+        building = !hasResult
+    }
+    b.Complete = !building
+    if !building && !hasResult {
+        return missingResultError
+    }
+    b.Result = ParseResult(result)
+
+    if durationMilliseconds, ok := details.GetInt64("duration"); ok && !building {
+        b.Duration = time.Duration(durationMilliseconds) * time.Millisecond
+    }
+    if estimateMilliseconds, ok := details.GetInt64("estimatedDuration"); ok && estimateMilliseconds > 0 {
+        b.EstimatedDuration = time.Duration(estimateMilliseconds) * time.Millisecond
+    }
+
+    var failures int64
+    var coverageKind ClassKind
+    var hasWarnings bool
+    if actions, ok := details.GetArray("actions"); ok {
+        for _, a := range actions {
+            action, ok := AsJsonObject(a)
+            if !ok {
+                continue
+            }
+
+            class, _ := action.GetString("_class")
+            kind := c.Classes.Kind(class)
+            if kind == ClassParameters {
+                b.Parameters = buildParameters(action)
+                continue
+            }
+            if kind == ClassCoberturaCoverage || kind == ClassJacocoCoverage {
+                coverageKind = kind
+                continue
+            }
+            if kind == ClassWarnings {
+                hasWarnings = true
+                continue
+            }
+            if kind != ClassTestResults {
+                continue
+            }
+
+            failures, _ = action.GetInt64("failCount")
+            b.Tests, _ = action.GetInt64("totalCount")
+            b.Skipped, _ = action.GetInt64("skipCount")
+        }
+    }
+
+    // Builds that failed, or that a quality gate or an unstable step marked unstable, without failing any tests have an
+    // unknown number of failures.
+    if failures == 0 && (result == "FAILURE" || result == "UNSTABLE") {
+        failures = -1
+    }
+
+    b.Failures = failures
+    if failures > 0 {
+        b.FailedTests, err = c.FailedTests(b.Url)
+        if err != nil {
+            slog.Debug("error fetching test report", "build", b.Url, "err", err)
+        }
+    }
+    if class, _ := details.GetString("_class"); c.Classes.Kind(class) == ClassPipelineBuild && (result == "FAILURE" || result == "UNSTABLE") {
+        b.FailedStage, err = c.FailedStage(b.Url)
+        if err != nil {
+            slog.Debug("error fetching stages", "build", b.Url, "err", err)
+        }
+    }
+    if coverageKind != "" && !building {
+        b.Coverage, err = c.BuildCoverage(b.Url, coverageKind)
+        if err != nil {
+            slog.Debug("error fetching coverage", "build", b.Url, "err", err)
+        }
+    }
+    if hasWarnings && !building {
+        b.Warnings, err = c.BuildWarnings(b.Url)
+        if err != nil {
+            slog.Debug("error fetching warnings", "build", b.Url, "err", err)
+        }
+    }
+    b.Artifacts = buildArtifacts(details)
+    b.Commit, b.Change = buildRevision(c.Classes, details)
+    b.Culprits = buildCulprits(details)
+    return nil
+}
+
+// FailedStage returns the name of the first stage of the pipeline build at the given URL that failed or, if no stage
+// failed, the first stage that is unstable.
+func (c *Client) FailedStage(buildUrl string) (string, error) {
+    description, err := c.Object(apiUrl(buildUrl, "wfapi/describe"))
+    if err != nil {
+        return "", err
+    }
+
+    unstable := ""
+    stages, _ := description.GetArray("stages")
+    for _, s := range stages {
+        stage, ok := AsJsonObject(s)
+        if !ok {
+            continue
+        }
+        name, _ := stage.GetString("name")
+        switch status, _ := stage.GetString("status"); status {
+        case "FAILED":
+            return name, nil
+        case "UNSTABLE":
+            if unstable == "" {
+                unstable = name
+            }
+        }
+    }
+    return unstable, nil
+}
+
+// buildParameters returns the parameters recorded by a build's parameters action. Values that are not strings (e.g.
+// booleans) are formatted as strings, and parameters whose values Jenkins does not expose (e.g. passwords) are omitted.
+func buildParameters(action JsonObject) []Parameter {
+    var parameters []Parameter
+    parameterObjects, _ := action.GetArray("parameters")
+    for _, p := range parameterObjects {
+        parameter, ok := AsJsonObject(p)
+        if !ok {
+            continue
+        }
+        name, ok := parameter.GetString("name")
+        value, hasValue := parameter["value"]
+        if !ok || !hasValue || value == nil {
+            continue
+        }
+        parameters = append(parameters, Parameter{name, fmt.Sprint(value)})
+    }
+    return parameters
+}
+
+// testReportTree selects the names and statuses of the test cases in a test report. Matrix builds aggregate the reports
+// of their configurations as child reports.
+const testReportTree = "suites[cases[className,name,status]],childReports[result[suites[cases[className,name,status]]]]"
+
+// FailedTests returns the names of the failed tests in the test report of the build at the given URL.
+func (c *Client) FailedTests(buildUrl string) ([]string, error) {
+    report, err := c.Object(apiUrl(buildUrl, "testReport/api/json?tree=" + testReportTree))
+    if err != nil {
+        return nil, err
+    }
+
+    reports := []JsonObject{report}
+    if children, ok := report.GetArray("childReports"); ok {
+        for _, c := range children {
+            if child, ok := AsJsonObject(c); ok {
+                if result, ok := child.GetObject("result"); ok {
+                    reports = append(reports, result)
+                }
+            }
+        }
+    }
+
+    failed := []string{}
+    for _, r := range reports {
+        suites, _ := r.GetArray("suites")
+        for _, s := range suites {
+            suite, ok := AsJsonObject(s)
+            if !ok {
+                continue
+            }
+            cases, _ := suite.GetArray("cases")
+            for _, c := range cases {
+                testCase, ok := AsJsonObject(c)
+                if !ok {
+                    continue
+                }
+                if status, _ := testCase.GetString("status"); status != "FAILED" && status != "REGRESSION" {
+                    continue
+                }
+                className, _ := testCase.GetString("className")
+                name, _ := testCase.GetString("name")
+                failed = append(failed, className + "." + name)
+            }
+        }
+    }
+    return failed, nil
+}
+
+// buildCulprits returns the full names of a Jenkins build's culprits.
+func buildCulprits(details JsonObject) []string {
+    var culprits []string
+    if culpritObjects, ok := details.GetArray("culprits"); ok {
+        for _, c := range culpritObjects {
+            culprit, ok := AsJsonObject(c)
+            if !ok {
+                continue
+            }
+            if name, ok := culprit.GetString("fullName"); ok {
+                culprits = append(culprits, name)
+            }
+        }
+    }
+    return culprits
+}
+
+// FirstLine returns the first non-empty line of a commit message.
+func FirstLine(message string) string {
+    for _, line := range strings.Split(message, "\n") {
+        if line = strings.TrimSpace(line); line != "" {
+            return line
+        }
+    }
+    return ""
+}
+
+// buildRevision returns the revision built by a Jenkins build and the summary of its most recent change. The revision
+// is taken from SCM data actions such as the Git plugin's build data if present, and otherwise from the most recent
+// change in the build's change sets. Freestyle builds report a single changeSet; pipeline builds report a list of
+// changeSets.
+func buildRevision(classes ClassMap, details JsonObject) (string, string) {
+    var commit, change string
+
+    var changeSets []interface{}
+    if changeSet, ok := details["changeSet"]; ok {
+        changeSets = append(changeSets, changeSet)
+    }
+    if sets, ok := details.GetArray("changeSets"); ok {
+        changeSets = append(changeSets, sets...)
+    }
+    for _, cs := range changeSets {
+        changeSet, ok := AsJsonObject(cs)
+        if !ok {
+            continue
+        }
+
+        items, ok := changeSet.GetArray("items")
+        if !ok || len(items) == 0 {
+            continue
+        }
+
+        item, ok := AsJsonObject(items[len(items) - 1])
+        if !ok {
+            continue
+        }
+
+        commit, _ = item.GetString("commitId")
+        message, ok := item.GetString("comment")
+        if !ok {
+            message, _ = item.GetString("msg")
+        }
+        change = FirstLine(message)
+    }
+
+    if actions, ok := details.GetArray("actions"); ok {
+        for _, a := range actions {
+            action, ok := AsJsonObject(a)
+            if !ok {
+                continue
+            }
+            if class, _ := action.GetString("_class"); classes.Kind(class) != ClassScmData {
+                continue
+            }
+            if revision, ok := action.GetObject("lastBuiltRevision"); ok {
+                if sha, ok := revision.GetString("SHA1"); ok {
+                    commit = sha
+                }
+            }
+        }
+    }
+
+    return commit, change
+}
@@ -0,0 +1,224 @@
+package jenkins_test
+
+import (
+    "reflect"
+    "strconv"
+    "testing"
+    "time"
+
+    "github.com/pgavlin/jitdash/pkg/jenkins"
+)
+
+// buildDetails returns the details of a completed build with the given result and actions.
+func buildDetails(class, path, result string, actions ...interface{}) object {
+    return object{"_class": class, "url": jenkinsUrl(path), "timestamp": 1700000000000, "duration": 60000,
+        "building": false, "result": result, "actions": array(actions)}
+}
+
+// testResults returns a test result action with the given counts.
+func testResults(failed, total int) object {
+    return object{"_class": "hudson.tasks.junit.TestResultAction", "failCount": failed, "totalCount": total,
+        "skipCount": 0}
+}
+
+// testReport returns a test report in which the given tests failed and one test passed.
+func testReport(failed ...string) object {
+    cases := array{object{"className": "T", "name": "passes", "status": "PASSED"}}
+    for _, name := range failed {
+        cases = append(cases, object{"className": "T", "name": name, "status": "FAILED"})
+    }
+    return object{"suites": array{object{"cases": cases}}}
+}
+
+func TestBuildDetails(t *testing.T) {
+    const freestyle, pipeline = "hudson.model.FreeStyleBuild", "org.jenkinsci.plugins.workflow.job.WorkflowRun"
+
+    s := newServer(t, map[string]interface{}{
+        "/job/a/1/api/json": buildDetails(freestyle, "job/a/1/", "SUCCESS", testResults(0, 10)),
+        "/job/a/2/api/json": buildDetails(freestyle, "job/a/2/", "UNSTABLE", testResults(2, 10)),
+        "/job/a/2/testReport/api/json": testReport("flaky", "broken"),
+        "/job/a/3/api/json": buildDetails(freestyle, "job/a/3/", "UNSTABLE"),
+        "/job/a/4/api/json": buildDetails(freestyle, "job/a/4/", "FAILURE"),
+        "/job/a/5/api/json": buildDetails(freestyle, "job/a/5/", "ABORTED"),
+        "/job/a/6/api/json": object{"_class": freestyle, "url": jenkinsUrl("job/a/6/"), "timestamp": 1700000000000,
+            "building": true, "result": nil},
+        "/job/p/1/api/json": buildDetails(pipeline, "job/p/1/", "FAILURE"),
+        "/job/p/1/wfapi/describe": object{"stages": array{
+            object{"name": "Build", "status": "SUCCESS"},
+            object{"name": "Lint", "status": "UNSTABLE"},
+            object{"name": "Test", "status": "FAILED"},
+        }},
+        "/job/p/2/api/json": buildDetails(pipeline, "job/p/2/", "UNSTABLE"),
+        "/job/p/2/wfapi/describe": object{"stages": array{
+            object{"name": "Build", "status": "SUCCESS"},
+            object{"name": "Quality Gate", "status": "UNSTABLE"},
+        }},
+        "/job/params/1/api/json": buildDetails(freestyle, "job/params/1/", "SUCCESS", object{
+            "_class": "hudson.model.ParametersAction",
+            "parameters": array{object{"name": "BRANCH", "value": "main"}, object{"name": "DEBUG", "value": true},
+                object{"name": "TOKEN"}},
+        }),
+        "/job/params/2/api/json": buildDetails(freestyle, "job/params/2/", "SUCCESS", object{
+            "_class": "com.example.CustomParametersAction",
+            "parameters": array{object{"name": "BRANCH", "value": "release"}},
+        }, object{
+            "_class": "com.example.CustomBuildData",
+            "lastBuiltRevision": object{"SHA1": "abc123"},
+        }),
+    })
+    c := s.Client()
+
+    tests := []struct {
+        name string
+        path string
+        result jenkins.Result
+        complete bool
+        failures int64
+        tests int64
+        failedTests []string
+        failedStage string
+        passed bool
+    }{
+        {"success", "job/a/1/", jenkins.ResultSuccess, true, 0, 10, nil, "", true},
+        {"unstable with test failures", "job/a/2/", jenkins.ResultUnstable, true, 2, 10, []string{"T.flaky", "T.broken"}, "",
+            false},
+        {"unstable without test failures", "job/a/3/", jenkins.ResultUnstable, true, -1, 0, nil, "", false},
+        {"failure without tests", "job/a/4/", jenkins.ResultFailure, true, -1, 0, nil, "", false},
+        {"aborted", "job/a/5/", jenkins.ResultAborted, true, 0, 0, nil, "", false},
+        {"building", "job/a/6/", jenkins.ResultUnknown, false, 0, 0, nil, "", false},
+        {"failed pipeline", "job/p/1/", jenkins.ResultFailure, true, -1, 0, nil, "Test", false},
+        {"unstable pipeline", "job/p/2/", jenkins.ResultUnstable, true, -1, 0, nil, "Quality Gate", false},
+    }
+    for _, test := range tests {
+        t.Run(test.name, func(t *testing.T) {
+            b := &jenkins.Build{Url: s.URL + "/" + test.path}
+            if err := c.BuildDetails(b); err != nil {
+                t.Fatalf("unexpected error: %s", err)
+            }
+            if b.Result != test.result || b.Complete != test.complete {
+                t.Errorf("result is %v (complete: %v), want %v (complete: %v)", b.Result, b.Complete, test.result, test.complete)
+            }
+            if b.Failures != test.failures || b.Tests != test.tests {
+                t.Errorf("failures are %d of %d tests, want %d of %d", b.Failures, b.Tests, test.failures, test.tests)
+            }
+            if !reflect.DeepEqual(b.FailedTests, test.failedTests) {
+                t.Errorf("failed tests are %v, want %v", b.FailedTests, test.failedTests)
+            }
+            if b.FailedStage != test.failedStage {
+                t.Errorf("failed stage is %q, want %q", b.FailedStage, test.failedStage)
+            }
+            if b.Passed() != test.passed {
+                t.Errorf("passed is %v, want %v", b.Passed(), test.passed)
+            }
+        })
+    }
+
+    t.Run("parameters", func(t *testing.T) {
+        b := &jenkins.Build{Url: s.URL + "/job/params/1/"}
+        if err := c.BuildDetails(b); err != nil {
+            t.Fatalf("unexpected error: %s", err)
+        }
+        want := []jenkins.Parameter{{"BRANCH", "main"}, {"DEBUG", "true"}}
+        if !reflect.DeepEqual(b.Parameters, want) {
+            t.Errorf("parameters are %v, want %v", b.Parameters, want)
+        }
+    })
+
+    t.Run("custom action classes", func(t *testing.T) {
+        i := newInstance(t, s, object{"jobs": array{"job/params/"}, "classes": object{
+            "com.example.CustomParametersAction": "parameters",
+            "com.example.CustomBuildData": "scmData",
+        }})
+        b := &jenkins.Build{Url: s.URL + "/job/params/2/"}
+        if err := i.Api().BuildDetails(b); err != nil {
+            t.Fatalf("unexpected error: %s", err)
+        }
+        want := []jenkins.Parameter{{"BRANCH", "release"}}
+        if !reflect.DeepEqual(b.Parameters, want) || b.Commit != "abc123" {
+            t.Errorf("parameters are %v and commit is %q, want %v and %q", b.Parameters, b.Commit, want, "abc123")
+        }
+    })
+
+    t.Run("missing", func(t *testing.T) {
+        if err := c.BuildDetails(&jenkins.Build{Url: s.URL + "/job/a/99/"}); err == nil {
+            t.Errorf("expected an error for a missing build")
+        }
+    })
+}
+
+func TestFetchInstance(t *testing.T) {
+    s := newServer(t, map[string]interface{}{
+        "/job/f/api/json": object{"name": "f", "jobs": array{
+            listed("hudson.model.FreeStyleProject", "a", "job/f/job/a/"),
+        }},
+        "/job/f/job/a/api/json": project("hudson.model.FreeStyleProject", "a", "job/f/job/a/", "hudson.model.FreeStyleBuild", 3),
+        "/job/f/job/a/1/api/json": buildDetails("hudson.model.FreeStyleBuild", "job/f/job/a/1/", "SUCCESS"),
+        "/job/f/job/a/2/api/json": buildDetails("hudson.model.FreeStyleBuild", "job/f/job/a/2/", "FAILURE"),
+        "/job/f/job/a/3/api/json": buildDetails("hudson.model.FreeStyleBuild", "job/f/job/a/3/", "UNSTABLE"),
+    })
+
+    ij := jenkins.FetchInstance(newInstance(t, s, object{"folders": array{"job/f/"}}), 2, 0)
+    if len(ij.Errors) != 0 {
+        t.Fatalf("unexpected errors: %v", ij.Errors)
+    }
+    if len(ij.Jobs) != 1 {
+        t.Fatalf("fetched %d jobs, want 1", len(ij.Jobs))
+    }
+
+    // Only the most recent builds are kept, and a build that a quality gate marked unstable is failing.
+    job := ij.Jobs[0]
+    if len(job.Builds) != 2 || job.Builds[0].Id != 2 || job.Builds[1].Id != 3 {
+        t.Fatalf("builds are %v, want builds 2 and 3", job.Builds)
+    }
+    if s.Requests("/job/f/job/a/1/api/json") != 0 {
+        t.Errorf("fetched the details of a build beyond the limit")
+    }
+    if !job.Failing() || job.LastSuccess() != nil {
+        t.Errorf("job whose last build is unstable is not failing")
+    }
+}
+
+func TestFetchInstanceWindow(t *testing.T) {
+    // Builds 1 and 2 started more than a week ago; builds 3 to 5 started within the last week.
+    ages := []time.Duration{10 * 24 * time.Hour, 8 * 24 * time.Hour, 3 * 24 * time.Hour, 2 * time.Hour, time.Hour}
+    fixtures := map[string]interface{}{
+        "/job/f/api/json": object{"name": "f", "jobs": array{
+            listed("hudson.model.FreeStyleProject", "a", "job/f/job/a/"),
+        }},
+    }
+    builds := array{}
+    for n, age := range ages {
+        path := "job/f/job/a/" + strconv.Itoa(n + 1) + "/"
+        timestamp := time.Now().Add(-age).UnixMilli()
+        builds = append(builds, object{"_class": "hudson.model.FreeStyleBuild", "number": n + 1, "url": jenkinsUrl(path),
+            "timestamp": timestamp})
+
+        details := buildDetails("hudson.model.FreeStyleBuild", path, "SUCCESS")
+        details["timestamp"] = timestamp
+        fixtures["/" + path + "api/json"] = details
+    }
+    fixtures["/job/f/job/a/api/json"] = object{"_class": "hudson.model.FreeStyleProject", "name": "a",
+        "url": jenkinsUrl("job/f/job/a/"), "builds": builds}
+    s := newServer(t, fixtures)
+
+    // The window reaches further back than the build limit, and builds outside the window are never fetched.
+    ij := jenkins.FetchInstance(newInstance(t, s, object{"folders": array{"job/f/"}, "window": "7d"}), 2, 0)
+    if len(ij.Errors) != 0 {
+        t.Fatalf("unexpected errors: %v", ij.Errors)
+    }
+    job := ij.Jobs[0]
+    if len(job.Builds) != 3 || job.Builds[0].Id != 3 {
+        t.Errorf("builds are %v, want builds 3 to 5", job.Builds)
+    }
+    if s.Requests("/job/f/job/a/2/api/json") != 0 {
+        t.Errorf("fetched the details of a build outside the window")
+    }
+
+    refreshed, err := newInstance(t, s, object{"folders": array{"job/f/"}, "window": "7d"}).RefreshJob(job, 2)
+    if err != nil {
+        t.Fatalf("unexpected error: %s", err)
+    }
+    if len(refreshed.Builds) != 3 {
+        t.Errorf("refreshed builds are %v, want builds 3 to 5", refreshed.Builds)
+    }
+}
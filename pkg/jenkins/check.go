@@ -0,0 +1,25 @@
+package jenkins
+
+// Check makes a lightweight request for each of the instance's folders, views, and jobs to verify that their URLs are
+// correct and that the instance's credentials grant access to them. Instances with backends are checked by fetching
+// their jobs.
+func (i *Instance) Check() []*FetchError {
+    if i.Backend != nil {
+        _, errs := i.Backend.FetchJobs(i)
+        return errs
+    }
+
+    urls := append(append([]string(nil), i.Folders...), i.Views...)
+    for _, jobUrl := range i.Jobs {
+        urls = append(urls, apiUrl(jobUrl, "api/json"))
+    }
+
+    var errs []*FetchError
+    for _, url := range urls {
+        i.Logger().Info("checking", "url", url)
+        if _, err := i.Api().Object(url + "?tree=_class"); err != nil {
+            errs = append(errs, i.NewFetchError("", url, err))
+        }
+    }
+    return errs
+}
@@ -0,0 +1,109 @@
+package jenkins
+
+import (
+    "errors"
+    "fmt"
+)
+
+// A ClassKind says how items of a Jenkins class, as given by their "_class" properties, are handled.
+type ClassKind string
+
+// Class kinds.
+const (
+    ClassIgnored ClassKind = "ignored" // items are skipped; this disables a built-in class
+    ClassJob ClassKind = "job" // a job whose builds are shown
+    ClassMatrix ClassKind = "matrix" // a matrix project, whose configurations are shown as jobs if the instance expands them
+    ClassFolder ClassKind = "folder" // a folder, whose jobs are listed by instances that recurse into nested folders
+    ClassOrganizationFolder ClassKind = "organizationFolder" // an organization folder, whose jobs are multibranch projects
+    ClassMultibranch ClassKind = "multibranch" // a multibranch project, whose jobs are branches
+    ClassBuild ClassKind = "build" // a build
+    ClassPipelineBuild ClassKind = "pipelineBuild" // a pipeline build, whose failed stage is fetched from the Pipeline Stage View API
+    ClassTestResults ClassKind = "testResults" // a build action that carries test results
+    ClassCoberturaCoverage ClassKind = "coberturaCoverage" // a build action whose coverage report is served like Cobertura's
+    ClassJacocoCoverage ClassKind = "jacocoCoverage" // a build action whose coverage report is served like JaCoCo's
+    ClassWarnings ClassKind = "warnings" // a build action that records static-analysis warnings with Warnings NG
+    ClassParameters ClassKind = "parameters" // a build action that records the build's parameters
+    ClassScmData ClassKind = "scmData" // a build action that records the revision built, like the Git plugin's build data
+    ClassPrimaryBranch ClassKind = "primaryBranch" // a branch action that marks a multibranch project's default branch
+)
+
+// classKinds is the set of valid class kinds.
+var classKinds = map[ClassKind]bool{
+    ClassIgnored: true,
+    ClassJob: true,
+    ClassMatrix: true,
+    ClassFolder: true,
+    ClassOrganizationFolder: true,
+    ClassMultibranch: true,
+    ClassBuild: true,
+    ClassPipelineBuild: true,
+    ClassTestResults: true,
+    ClassCoberturaCoverage: true,
+    ClassJacocoCoverage: true,
+    ClassWarnings: true,
+    ClassParameters: true,
+    ClassScmData: true,
+    ClassPrimaryBranch: true,
+}
+
+// A ClassMap maps Jenkins classes to the kinds of items they are. Classes that a map does not list are looked up in
+// DefaultClasses.
+type ClassMap map[string]ClassKind
+
+// DefaultClasses maps the classes of core Jenkins and of common plugins to their kinds. Matrix builds report the
+// aggregate of their configurations' test results.
+var DefaultClasses = ClassMap{
+    "hudson.model.FreeStyleProject": ClassJob,
+    "hudson.matrix.MatrixProject": ClassMatrix,
+    "org.jenkinsci.plugins.workflow.job.WorkflowJob": ClassJob,
+    "com.cloudbees.hudson.plugins.folder.Folder": ClassFolder,
+    "jenkins.branch.OrganizationFolder": ClassOrganizationFolder,
+    "org.jenkinsci.plugins.workflow.multibranch.WorkflowMultiBranchProject": ClassMultibranch,
+    "hudson.model.FreeStyleBuild": ClassBuild,
+    "hudson.matrix.MatrixBuild": ClassBuild,
+    "hudson.matrix.MatrixRun": ClassBuild,
+    "org.jenkinsci.plugins.workflow.job.WorkflowRun": ClassPipelineBuild,
+    "hudson.tasks.junit.TestResultAction": ClassTestResults,
+    "hudson.tasks.test.AggregatedTestResultAction": ClassTestResults,
+    "hudson.matrix.MatrixTestResult": ClassTestResults,
+    "hudson.plugins.cobertura.CoberturaBuildAction": ClassCoberturaCoverage,
+    "hudson.plugins.jacoco.JacocoBuildAction": ClassJacocoCoverage,
+    "io.jenkins.plugins.analysis.core.model.ResultAction": ClassWarnings,
+    "hudson.model.ParametersAction": ClassParameters,
+    "hudson.plugins.git.util.BuildData": ClassScmData,
+    "jenkins.scm.api.metadata.PrimaryInstanceMetadataAction": ClassPrimaryBranch,
+}
+
+// Kind returns the kind of the given class, or the empty string if the class is unknown.
+func (m ClassMap) Kind(class string) ClassKind {
+    if kind, ok := m[class]; ok {
+        return kind
+    }
+    return DefaultClasses[class]
+}
+
+// IsJob returns true if items of the given class are jobs, including matrix projects.
+func (m ClassMap) IsJob(class string) bool {
+    kind := m.Kind(class)
+    return kind == ClassJob || kind == ClassMatrix
+}
+
+// IsBuild returns true if items of the given class are builds, including pipeline builds.
+func (m ClassMap) IsBuild(class string) bool {
+    kind := m.Kind(class)
+    return kind == ClassBuild || kind == ClassPipelineBuild
+}
+
+// ParseClassMap parses a class map of the form {"com.example.CustomJob": "job"}, e.g. to accept the job classes that a
+// plugin introduces.
+func ParseClassMap(o JsonObject) (ClassMap, error) {
+    m := ClassMap{}
+    for class := range o {
+        kind, _ := o.GetString(class)
+        if !classKinds[ClassKind(kind)] {
+            return nil, errors.New(fmt.Sprintf("class %s has an invalid kind %v", class, o[class]))
+        }
+        m[class] = ClassKind(kind)
+    }
+    return m, nil
+}
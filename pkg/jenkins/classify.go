@@ -0,0 +1,36 @@
+package jenkins
+
+import (
+    "regexp"
+)
+
+// ClassificationRule assigns the failed builds whose console output tail matches Console or one of whose failed tests
+// matches Test to Category, e.g. "infra", "compile", or "test".
+type ClassificationRule struct {
+    Category string
+    Console *regexp.Regexp // nil to ignore the console output
+    Test *regexp.Regexp // nil to ignore the failed tests
+}
+
+// Classify returns the category of the first classification rule that matches the given build. If the build did not
+// fail or no rule matches, Classify returns the empty string. Console patterns only match builds whose console output
+// tail was captured; see Instance.ConsoleTail.
+func (i *Instance) Classify(b *Build) string {
+    if b == nil || !b.Failed() {
+        return ""
+    }
+
+    for _, r := range i.Classifications {
+        if r.Console != nil && b.ConsoleTail != "" && r.Console.MatchString(b.ConsoleTail) {
+            return r.Category
+        }
+        if r.Test != nil {
+            for _, t := range b.FailedTests {
+                if r.Test.MatchString(t) {
+                    return r.Category
+                }
+            }
+        }
+    }
+    return ""
+}
@@ -0,0 +1,35 @@
+package jenkins
+
+import (
+    "net/http"
+)
+
+// A Client makes requests to the API of a Jenkins instance and parses its responses. The requests are made by an HTTP
+// client whose transport may be replaced, e.g. by one that serves recorded responses; see the jenkinstest package.
+type Client struct {
+    HTTP *http.Client // the client that makes requests, or nil to use http.DefaultClient
+    Classes ClassMap // the kinds of classes that are not in DefaultClasses
+}
+
+// httpClient returns the client that makes requests.
+func (c *Client) httpClient() *http.Client {
+    if c.HTTP == nil {
+        return http.DefaultClient
+    }
+    return c.HTTP
+}
+
+// Json fetches the resource at the given URL with the given additional request headers and decodes it as JSON into v;
+// see FetchJson.
+func (c *Client) Json(url string, header http.Header, v interface{}) error {
+    return FetchJson(c.httpClient(), url, header, v)
+}
+
+// Object fetches and decodes the JSON object at the given URL.
+func (c *Client) Object(url string) (JsonObject, error) {
+    var object JsonObject
+    if err := c.Json(url, nil, &object); err != nil {
+        return nil, err
+    }
+    return object, nil
+}
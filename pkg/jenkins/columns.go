@@ -0,0 +1,274 @@
+package jenkins
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "os/exec"
+    "regexp"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// columnCommandTimeout bounds how long a column's command may run for a single build.
+const columnCommandTimeout = time.Minute
+
+// A Column is an extra column of an instance's job tables. Each job's value in the column is computed from its most
+// recent completed build, either by following a path through one of the build's JSON API resources, e.g. to the line
+// coverage that the Cobertura plugin reports, or by running a command. Values are computed once per build.
+type Column struct {
+    Name string
+    Match *regexp.Regexp // the jobs that have values in the column, or nil for every job
+    Resource string // the build's API resource that Path applies to, relative to the build's URL; "api/json" by default
+    Path []PathStep // the path to the value within the resource, if the column is not computed by Command
+    Command []string // the command that prints the value, if any; see ColumnValues
+    Format string // the format of the value for fmt.Sprintf, e.g. "%.1f%%", or empty to show the value as-is
+}
+
+// A PathStep selects a value within a JSON value: a property of an object, optionally followed by an element of the
+// array that the property holds. The element is selected by its index or, if Key is non-empty, as the first object
+// whose Key property equals Value.
+type PathStep struct {
+    Property string
+    Index int // the index of the element, or -1 to select no element
+    Key string
+    Value string
+}
+
+// pathStepPattern matches a step of a column's path, e.g. "elements", "builds[0]", or "elements[name=Lines]".
+var pathStepPattern = regexp.MustCompile(`^([^\[\]]+)(?:\[(?:(\d+)|([^=\]]+)=([^\]]*))\])?$`)
+
+// ParsePath parses a path of the form "actions[_class=hudson.plugins.cobertura.CoberturaBuildAction].ratio". Steps are
+// separated by periods outside of brackets.
+func ParsePath(path string) ([]PathStep, error) {
+    var steps []PathStep
+    start, depth := 0, 0
+    for n := 0; n <= len(path); n++ {
+        if n < len(path) {
+            switch path[n] {
+            case '[':
+                depth++
+                continue
+            case ']':
+                depth--
+                continue
+            case '.':
+                if depth != 0 {
+                    continue
+                }
+            default:
+                continue
+            }
+        }
+
+        m := pathStepPattern.FindStringSubmatch(path[start:n])
+        if m == nil {
+            return nil, errors.New(fmt.Sprintf("invalid path step \"%s\"", path[start:n]))
+        }
+        step := PathStep{Property: m[1], Index: -1, Key: m[3], Value: m[4]}
+        if m[2] != "" {
+            step.Index, _ = strconv.Atoi(m[2])
+        }
+        steps = append(steps, step)
+        start = n + 1
+    }
+    return steps, nil
+}
+
+// evaluatePath returns the value at the given path within the given JSON value.
+func evaluatePath(v interface{}, path []PathStep) (interface{}, bool) {
+    for _, step := range path {
+        object, ok := AsJsonObject(v)
+        if !ok {
+            return nil, false
+        }
+        if v, ok = object[step.Property]; !ok {
+            return nil, false
+        }
+        if step.Index < 0 && step.Key == "" {
+            continue
+        }
+
+        array, ok := v.([]interface{})
+        if !ok {
+            return nil, false
+        }
+        if step.Key == "" {
+            if step.Index >= len(array) {
+                return nil, false
+            }
+            v = array[step.Index]
+            continue
+        }
+
+        found := false
+        for _, e := range array {
+            if element, ok := AsJsonObject(e); ok && fmt.Sprint(element[step.Key]) == step.Value {
+                v, found = e, true
+                break
+            }
+        }
+        if !found {
+            return nil, false
+        }
+    }
+    return v, v != nil
+}
+
+// parseColumns parses an array of column objects:
+//
+//     "columns": [
+//         {
+//             "name": "Coverage",
+//             "resource": "cobertura/api/json?depth=2",
+//             "path": "results.elements[name=Lines].ratio",
+//             "format": "%.1f%%"
+//         },
+//         {"name": "Owner", "jobs": "^service-", "command": ["/usr/local/bin/job-owner"]}
+//     ]
+func parseColumns(array []interface{}) ([]Column, error) {
+    columns := []Column{}
+    for _, c := range array {
+        columnObject, ok := AsJsonObject(c)
+        if !ok {
+            return nil, errors.New(fmt.Sprintf("invalid column: %v", c))
+        }
+
+        name, ok := columnObject.GetString("name")
+        if !ok || name == "" {
+            return nil, errors.New("a column specifies no name")
+        }
+        column := Column{Name: name}
+
+        if jobs, ok := columnObject.GetString("jobs"); ok {
+            re, err := regexp.Compile(jobs)
+            if err != nil {
+                return nil, errors.New(fmt.Sprintf("column %s has an invalid jobs pattern %s: %s", name, jobs, err))
+            }
+            column.Match = re
+        }
+
+        commandArray, hasCommand := columnObject.GetArray("command")
+        path, hasPath := columnObject.GetString("path")
+        if hasCommand == hasPath {
+            return nil, errors.New(fmt.Sprintf("column %s must specify either a path or a command", name))
+        }
+        for _, a := range commandArray {
+            arg, ok := a.(string)
+            if !ok {
+                return nil, errors.New(fmt.Sprintf("column %s has an invalid command argument: %v", name, a))
+            }
+            column.Command = append(column.Command, arg)
+        }
+        if hasCommand && len(column.Command) == 0 {
+            return nil, errors.New(fmt.Sprintf("column %s has an empty command", name))
+        }
+        if hasPath {
+            steps, err := ParsePath(path)
+            if err != nil {
+                return nil, errors.New(fmt.Sprintf("column %s: %s", name, err))
+            }
+            column.Path = steps
+        }
+
+        column.Resource, ok = columnObject.GetString("resource")
+        if !ok {
+            column.Resource = "api/json"
+        }
+        column.Format, _ = columnObject.GetString("format")
+
+        columns = append(columns, column)
+    }
+    return columns, nil
+}
+
+// commandInput is the input of a column's command.
+type commandInput struct {
+    Instance string
+    Job string
+    Build *Build
+}
+
+// columnValue computes the column's value for the given build of the named job.
+func (i *Instance) columnValue(c *Column, job string, b *Build) (string, error) {
+    var value interface{}
+    if c.Command != nil {
+        input, err := json.Marshal(commandInput{i.Name, job, b})
+        if err != nil {
+            return "", err
+        }
+
+        ctx, cancel := context.WithTimeout(context.Background(), columnCommandTimeout)
+        defer cancel()
+
+        var stdout, stderr bytes.Buffer
+        cmd := exec.CommandContext(ctx, c.Command[0], c.Command[1:]...)
+        cmd.Stdin = bytes.NewReader(input)
+        cmd.Stdout, cmd.Stderr = &stdout, &stderr
+        if err := cmd.Run(); err != nil {
+            return "", errors.New(fmt.Sprintf("%s: %s", err, strings.TrimSpace(stderr.String())))
+        }
+        value = strings.TrimSpace(stdout.String())
+    } else {
+        if i.Backend != nil {
+            return "", nil
+        }
+
+        var resource interface{}
+        if err := i.Api().Json(apiUrl(b.Url, c.Resource), nil, &resource); err != nil {
+            return "", err
+        }
+        v, ok := evaluatePath(resource, c.Path)
+        if !ok {
+            return "", nil
+        }
+        value = v
+    }
+
+    if c.Format == "" {
+        return fmt.Sprint(value), nil
+    }
+    // Numbers are formatted as floating-point values, e.g. with "%.0f".
+    if n, ok := value.(json.Number); ok {
+        if f, err := n.Float64(); err == nil {
+            value = f
+        }
+    }
+    return fmt.Sprintf(c.Format, value), nil
+}
+
+// ColumnValues computes the values of the instance's columns for the given build of the named job. Commands are run
+// with a JSON object that holds the names of the instance and the job and the build's properties on their standard
+// input, e.g. {"Instance": "ci", "Job": "widgets", "Build": {"Id": 42, "Url": "...", "Result": "SUCCESS", ...}}, and
+// print the value on their standard output. Values that cannot be computed are left empty, as are the values of path
+// columns for non-Jenkins instances.
+func (i *Instance) ColumnValues(job string, b *Build) map[string]string {
+    values := make(map[string]string)
+    for n := range i.Columns {
+        c := &i.Columns[n]
+        if c.Match != nil && !c.Match.MatchString(job) {
+            continue
+        }
+
+        value, err := i.columnValue(c, job, b)
+        if err != nil {
+            i.Logger().Debug("error computing column", "column", c.Name, "build", b.Url, "err", err)
+            continue
+        }
+        if value != "" {
+            values[c.Name] = value
+        }
+    }
+    return values
+}
+
+// Column returns the job's value in the named column, which comes from its most recent completed build.
+func (job *Job) Column(name string) string {
+    if b := job.LastCompletedBuild(); b != nil {
+        return b.Columns[name]
+    }
+    return ""
+}
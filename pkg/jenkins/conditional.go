@@ -0,0 +1,204 @@
+package jenkins
+
+import (
+    "bytes"
+    "container/list"
+    "io"
+    "net/http"
+    "sync"
+)
+
+// conditionalCacheSize is the default limit on the total size of the bodies that a ConditionalCache holds.
+const conditionalCacheSize = 64 << 20
+
+// A ConditionalCache remembers the validators (ETag and Last-Modified) and bodies of the responses received from an
+// instance so that repeated requests for the same URL can be made conditional. When the server responds with 304 Not
+// Modified, the cached body is returned in place of the empty response, and JSON objects that were decoded from the
+// cached body are copied rather than parsed again. Once the cached bodies exceed the cache's size, the least recently
+// used entries are evicted.
+type ConditionalCache struct {
+    instance string // the name of the instance, by which the cache's metrics are labeled
+    size int // the limit on the total size of the cached bodies
+
+    m sync.Mutex
+    entries map[string]*list.Element // the elements of lru, by URL
+    lru *list.List // the entries, most recently used first
+    used int // the total size of the cached bodies
+}
+
+type conditionalEntry struct {
+    url string
+    etag string
+    lastModified string
+    body []byte
+    object JsonObject // the decoded body, if it has been decoded as an object; never handed out, only copies of it
+}
+
+// NewConditionalCache creates an empty cache for the named instance.
+func NewConditionalCache(instance string) *ConditionalCache {
+    return &ConditionalCache{
+        instance: instance,
+        size: conditionalCacheSize,
+        entries: make(map[string]*list.Element),
+        lru: list.New(),
+    }
+}
+
+func (c *ConditionalCache) get(url string) *conditionalEntry {
+    c.m.Lock()
+    defer c.m.Unlock()
+    e, ok := c.entries[url]
+    if !ok {
+        return nil
+    }
+    c.lru.MoveToFront(e)
+    return e.Value.(*conditionalEntry)
+}
+
+// put caches the given entry, replacing any previous entry for its URL, and evicts the least recently used entries
+// until the cache fits its size. Bodies larger than the cache are not cached.
+func (c *ConditionalCache) put(entry *conditionalEntry) {
+    c.m.Lock()
+    defer c.m.Unlock()
+    if e, ok := c.entries[entry.url]; ok {
+        c.remove(e)
+    }
+    if len(entry.body) > c.size {
+        return
+    }
+
+    c.entries[entry.url] = c.lru.PushFront(entry)
+    c.used += len(entry.body)
+    for c.used > c.size {
+        c.remove(c.lru.Back())
+    }
+}
+
+// remove removes the given element's entry from the cache. The caller must hold c.m.
+func (c *ConditionalCache) remove(e *list.Element) {
+    entry := c.lru.Remove(e).(*conditionalEntry)
+    delete(c.entries, entry.url)
+    c.used -= len(entry.body)
+}
+
+// Transport wraps the given round tripper so that GET requests are made conditional on the cached validators for
+// their URL.
+func (c *ConditionalCache) Transport(base http.RoundTripper) http.RoundTripper {
+    return &conditionalTransport{c, base}
+}
+
+type conditionalTransport struct {
+    cache *ConditionalCache
+    base http.RoundTripper
+}
+
+func (ct *conditionalTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+    if req.Method != "GET" {
+        return ct.base.RoundTrip(req)
+    }
+
+    url := req.URL.String()
+    entry := ct.cache.get(url)
+    if entry != nil {
+        req = req.Clone(req.Context())
+        if entry.etag != "" {
+            req.Header.Set("If-None-Match", entry.etag)
+        }
+        if entry.lastModified != "" {
+            req.Header.Set("If-Modified-Since", entry.lastModified)
+        }
+    }
+
+    resp, err := ct.base.RoundTrip(req)
+    if err != nil {
+        return nil, err
+    }
+
+    switch {
+    case resp.StatusCode == http.StatusNotModified && entry != nil:
+        cacheRequests.With(ct.cache.instance, "hit").Inc()
+        resp.Body.Close()
+        resp.StatusCode, resp.Status = http.StatusOK, "200 OK"
+        resp.ContentLength = int64(len(entry.body))
+        resp.Body = &cachedBody{bytes.NewReader(entry.body), ct.cache, entry}
+        return resp, nil
+
+    case resp.StatusCode == http.StatusOK:
+        if entry != nil {
+            cacheRequests.With(ct.cache.instance, "miss").Inc()
+        }
+        etag, lastModified := resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+        if etag == "" && lastModified == "" {
+            return resp, nil
+        }
+
+        body, err := io.ReadAll(resp.Body)
+        resp.Body.Close()
+        if err != nil {
+            return nil, err
+        }
+
+        entry = &conditionalEntry{url: url, etag: etag, lastModified: lastModified, body: body}
+        ct.cache.put(entry)
+
+        resp.Body = &cachedBody{bytes.NewReader(body), ct.cache, entry}
+        return resp, nil
+
+    default:
+        return resp, nil
+    }
+}
+
+// cachedBody is the body of a response whose content is held by a ConditionalCache.
+type cachedBody struct {
+    *bytes.Reader
+    cache *ConditionalCache
+    entry *conditionalEntry
+}
+
+func (b *cachedBody) Close() error {
+    return nil
+}
+
+// decodedObject returns a copy of the object previously decoded from the body, if any. Callers may modify the copy.
+func (b *cachedBody) decodedObject() JsonObject {
+    b.cache.m.Lock()
+    object := b.entry.object
+    b.cache.m.Unlock()
+
+    if object == nil {
+        return nil
+    }
+    return JsonObject(copyJson(map[string]interface{}(object)).(map[string]interface{}))
+}
+
+// setDecodedObject records a copy of the object decoded from the body so that it can be reused if the body is not
+// modified, whatever the caller does with the object.
+func (b *cachedBody) setDecodedObject(object JsonObject) {
+    c := JsonObject(copyJson(map[string]interface{}(object)).(map[string]interface{}))
+
+    b.cache.m.Lock()
+    defer b.cache.m.Unlock()
+    b.entry.object = c
+}
+
+// copyJson returns a deep copy of the given decoded JSON value. Only objects and arrays are mutable, so other values
+// are returned as they are.
+func copyJson(v interface{}) interface{} {
+    switch v := v.(type) {
+    case map[string]interface{}:
+        c := make(map[string]interface{}, len(v))
+        for k, e := range v {
+            c[k] = copyJson(e)
+        }
+        return c
+    case []interface{}:
+        c := make([]interface{}, len(v))
+        for n, e := range v {
+            c[n] = copyJson(e)
+        }
+        return c
+    default:
+        return v
+    }
+}
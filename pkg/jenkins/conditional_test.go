@@ -0,0 +1,156 @@
+package jenkins
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "sync"
+    "testing"
+)
+
+// versionedServer serves JSON documents, keyed by path, with ETags that change whenever a document changes, and
+// records the If-None-Match header of each request.
+type versionedServer struct {
+    *httptest.Server
+
+    m sync.Mutex
+    documents map[string]string
+    ifNoneMatch []string
+}
+
+func newVersionedServer(t *testing.T, documents map[string]string) *versionedServer {
+    s := &versionedServer{documents: documents}
+    s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        s.m.Lock()
+        document, ok := s.documents[r.URL.Path]
+        s.ifNoneMatch = append(s.ifNoneMatch, r.Header.Get("If-None-Match"))
+        s.m.Unlock()
+
+        if !ok {
+            http.NotFound(w, r)
+            return
+        }
+        etag := `"` + r.URL.Path + ":" + document + `"`
+        if r.Header.Get("If-None-Match") == etag {
+            w.WriteHeader(http.StatusNotModified)
+            return
+        }
+        w.Header().Set("ETag", etag)
+        w.Header().Set("Content-Type", "application/json")
+        w.Write([]byte(document))
+    }))
+    t.Cleanup(s.Close)
+    return s
+}
+
+// lastIfNoneMatch returns the If-None-Match header of the most recent request.
+func (s *versionedServer) lastIfNoneMatch() string {
+    s.m.Lock()
+    defer s.m.Unlock()
+    return s.ifNoneMatch[len(s.ifNoneMatch) - 1]
+}
+
+func TestConditionalCache(t *testing.T) {
+    s := newVersionedServer(t, map[string]string{
+        "/job/a/api/json": `{"name":"a","builds":[{"number":1}]}`,
+        "/job/b/api/json": `{"name":"b"}`,
+        "/job/c/api/json": `{"name":"c"}`,
+    })
+    cache := NewConditionalCache("test")
+    client := &http.Client{Transport: cache.Transport(http.DefaultTransport)}
+
+    fetch := func(path string) JsonObject {
+        var object JsonObject
+        if err := FetchJson(client, s.URL + path, nil, &object); err != nil {
+            t.Fatalf("fetching %s: %s", path, err)
+        }
+        return object
+    }
+
+    // The first request is unconditional and the second is made conditional on the first response's ETag; the server's
+    // 304 is answered with the cached body.
+    first := fetch("/job/a/api/json")
+    if etag := s.lastIfNoneMatch(); etag != "" {
+        t.Errorf("first request sent If-None-Match %s", etag)
+    }
+    second := fetch("/job/a/api/json")
+    if etag := s.lastIfNoneMatch(); etag == "" {
+        t.Errorf("second request sent no If-None-Match")
+    }
+    if name, _ := second.GetString("name"); name != "a" {
+        t.Errorf("cached object is %v, want job a", second)
+    }
+
+    // Objects returned from the cache are copies, so modifying them does not affect later responses.
+    first["name"] = "modified"
+    builds, _ := second.GetArray("builds")
+    builds[0] = "modified"
+    third := fetch("/job/a/api/json")
+    if name, _ := third.GetString("name"); name != "a" {
+        t.Errorf("modifying the first object changed the cached name to %q", name)
+    }
+    if builds, _ := third.GetArray("builds"); len(builds) != 1 || builds[0] == "modified" {
+        t.Errorf("modifying the second object changed the cached builds to %v", builds)
+    }
+
+    // A changed document replaces the cached body.
+    s.m.Lock()
+    s.documents["/job/a/api/json"] = `{"name":"a2"}`
+    s.m.Unlock()
+    if name, _ := fetch("/job/a/api/json").GetString("name"); name != "a2" {
+        t.Errorf("changed object has name %q, want a2", name)
+    }
+    if name, _ := fetch("/job/a/api/json").GetString("name"); name != "a2" {
+        t.Errorf("cached changed object has name %q, want a2", name)
+    }
+}
+
+func TestConditionalCacheEviction(t *testing.T) {
+    documents := map[string]string{
+        "/a": `{"name":"a"}`,
+        "/b": `{"name":"b"}`,
+        "/c": `{"name":"c"}`,
+        "/large": `{"name":"` + strings.Repeat("x", 64) + `"}`,
+    }
+    s := newVersionedServer(t, documents)
+    cache := NewConditionalCache("test")
+    cache.size = 2 * len(documents["/a"])
+    client := &http.Client{Transport: cache.Transport(http.DefaultTransport)}
+
+    // fetch fetches the given path and returns true if the request was conditional, i.e. the path was cached.
+    fetch := func(path string) bool {
+        var object JsonObject
+        if err := FetchJson(client, s.URL + path, nil, &object); err != nil {
+            t.Fatalf("fetching %s: %s", path, err)
+        }
+        return s.lastIfNoneMatch() != ""
+    }
+    cached := func(path string) bool {
+        cache.m.Lock()
+        defer cache.m.Unlock()
+        _, ok := cache.entries[s.URL + path]
+        return ok
+    }
+
+    fetch("/a")
+    fetch("/b")
+    if !fetch("/a") {
+        t.Errorf("a was not cached")
+    }
+
+    // Caching c exceeds the cache's size, so the least recently used entry, b, is evicted.
+    fetch("/c")
+    if cached("/b") || !cached("/a") || !cached("/c") {
+        t.Errorf("cache holds a: %v, b: %v, c: %v; want a and c", cached("/a"), cached("/b"), cached("/c"))
+    }
+    if fetch("/b") {
+        t.Errorf("evicted entry b was requested conditionally")
+    }
+
+    // Bodies larger than the cache are not cached and evict nothing.
+    fetch("/large")
+    if cached("/large") || cache.used > cache.size || cache.lru.Len() != 2 {
+        t.Errorf("cache holds %d entries of %d bytes after a large body, want 2 entries within %d bytes",
+            cache.lru.Len(), cache.used, cache.size)
+    }
+}
@@ -0,0 +1,918 @@
+package jenkins
+
+import (
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "regexp"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/pgavlin/jitdash/pkg/metrics"
+    "github.com/pgavlin/jitdash/pkg/tracing"
+)
+
+// Config is the parsed form of a jitdash configuration.
+type Config struct {
+    MaxBuilds int // the number of most recent builds to fetch per job
+    MaxHistory int // the number of most recent builds to render per job
+    Workers int // the number of build details to fetch in parallel across all instances
+    Sort string // the name of the job ordering; see JobOrders
+    Window time.Duration // the default history window for instances that do not specify their own, or zero
+    StaleAfter time.Duration // the age after which a job's most recent build makes the job stale, or zero
+    StaleSection bool // true to list stale jobs in a section of their own
+    Scale Scale // the default sparkline scale
+    PageSize int // the number of jobs per page of the HTML dashboard, or zero to render every job on a single page
+    Location *time.Location // the timezone in which to display timestamps
+    Tracing *tracing.Exporter // the exporter for traces of fetch cycles, or nil if tracing is disabled
+    Sinks []metrics.Sink // the sinks to which the state of the jobs is published after each fetch
+    Sections []*Section // the curated sections of the dashboard, or empty to organize the dashboard by instance
+    Dashboards []*Dashboard // additional named dashboards, ordered by name
+    Instances []*Instance
+    Object JsonObject // the raw configuration, from which other packages parse their own sections
+}
+
+// ReadConfig reads and parses a JSON configuration from the given reader. References to environment variables of the
+// form ${NAME} in the configuration's string values are replaced with the variables' values.
+func ReadConfig(r io.Reader) (*Config, error) {
+    var config JsonObject
+    if err := DecodeJson(r, &config); err != nil {
+        return nil, err
+    }
+    if _, err := interpolate(config); err != nil {
+        return nil, err
+    }
+    return ParseConfig(config)
+}
+
+// ParseConfig parses a configuration from its JSON object form.
+func ParseConfig(config JsonObject) (*Config, error) {
+    maxBuilds, ok := config.GetInt64("maxBuilds")
+    if !ok {
+        maxBuilds = 10
+    }
+
+    maxHistory, ok := config.GetInt64("maxHistory")
+    if !ok {
+        maxHistory = 10
+    }
+
+    if maxHistory > maxBuilds {
+        maxHistory = maxBuilds
+    }
+
+    workers, ok := config.GetInt64("workers")
+    if !ok {
+        workers = DefaultFetchWorkers
+    }
+    if workers <= 0 {
+        return nil, errors.New(fmt.Sprintf("invalid workers %d", workers))
+    }
+
+    order, ok := config.GetString("sort")
+    if !ok {
+        order = "name"
+    }
+    if _, ok := JobOrders[order]; !ok {
+        return nil, errors.New(fmt.Sprintf("unknown sort order %s", order))
+    }
+
+    var window time.Duration
+    if w, ok := config.GetString("window"); ok {
+        d, err := ParseWindow(w)
+        if err != nil {
+            return nil, errors.New(fmt.Sprintf("invalid window %s", w))
+        }
+        window = d
+    }
+
+    var staleAfter time.Duration
+    if s, ok := config.GetString("staleAfter"); ok {
+        d, err := ParseWindow(s)
+        if err != nil {
+            return nil, errors.New(fmt.Sprintf("invalid staleAfter %s", s))
+        }
+        staleAfter = d
+    }
+    staleSection, _ := config.GetBool("staleSection")
+
+    var scale Scale
+    if scaleObject, ok := config.GetObject("scale"); ok {
+        s, err := ParseScale(scaleObject)
+        if err != nil {
+            return nil, errors.New(fmt.Sprintf("invalid scale: %s", err))
+        }
+        scale = s
+    }
+
+    pageSize, _ := config.GetInt64("pageSize")
+    if pageSize < 0 {
+        return nil, errors.New(fmt.Sprintf("invalid pageSize %d", pageSize))
+    }
+
+    location := time.UTC
+    if tz, ok := config.GetString("timezone"); ok {
+        l, err := time.LoadLocation(tz)
+        if err != nil {
+            return nil, errors.New(fmt.Sprintf("invalid timezone %s: %s", tz, err))
+        }
+        location = l
+    }
+
+    var exporter *tracing.Exporter
+    if tracingObject, ok := config.GetObject("tracing"); ok {
+        e, err := parseTracing(tracingObject)
+        if err != nil {
+            return nil, errors.New(fmt.Sprintf("invalid tracing: %s", err))
+        }
+        exporter = e
+    }
+
+    var sinks []metrics.Sink
+    if publishObject, ok := config.GetObject("publishMetrics"); ok {
+        p, err := parsePublisher(publishObject)
+        if err != nil {
+            return nil, errors.New(fmt.Sprintf("invalid publishMetrics: %s", err))
+        }
+        sinks = append(sinks, p)
+    }
+    if statsdObject, ok := config.GetObject("statsd"); ok {
+        s, err := parseStatsD(statsdObject)
+        if err != nil {
+            return nil, errors.New(fmt.Sprintf("invalid statsd: %s", err))
+        }
+        sinks = append(sinks, s)
+    }
+
+    // Instances that refresh on the same interval are staggered by a random delay of up to refreshJitter, and refresh
+    // tiers give groups of instances their own intervals, e.g. {"critical": "1m", "archive": "1h"}.
+    var refreshJitter time.Duration
+    if jitter, ok := config.GetString("refreshJitter"); ok {
+        d, err := time.ParseDuration(jitter)
+        if err != nil || d < 0 {
+            return nil, errors.New(fmt.Sprintf("invalid refreshJitter %s", jitter))
+        }
+        refreshJitter = d
+    }
+    tiers := make(map[string]time.Duration)
+    tiersObject, _ := config.GetObject("refreshTiers")
+    for name := range tiersObject {
+        interval, _ := tiersObject.GetString(name)
+        d, err := time.ParseDuration(interval)
+        if err != nil || d <= 0 {
+            return nil, errors.New(fmt.Sprintf("refresh tier %s has an invalid interval %v", name, tiersObject[name]))
+        }
+        tiers[name] = d
+    }
+
+    // Classes introduced by plugins may be mapped for all instances; instances' own mappings take precedence.
+    var classes ClassMap
+    if classesObject, ok := config.GetObject("classes"); ok {
+        m, err := ParseClassMap(classesObject)
+        if err != nil {
+            return nil, errors.New(fmt.Sprintf("invalid classes: %s", err))
+        }
+        classes = m
+    }
+
+    // Columns may be given for all instances; instances that list their own columns, even none, show only those.
+    var columns []Column
+    if columnArray, ok := config.GetArray("columns"); ok {
+        c, err := parseColumns(columnArray)
+        if err != nil {
+            return nil, errors.New(fmt.Sprintf("invalid columns: %s", err))
+        }
+        columns = c
+    }
+
+    // Likewise for the patterns of the artifacts to link to.
+    var artifacts []string
+    if artifactArray, ok := config.GetArray("artifacts"); ok {
+        a, err := parseArtifactPatterns(artifactArray)
+        if err != nil {
+            return nil, errors.New(fmt.Sprintf("invalid artifacts: %s", err))
+        }
+        artifacts = a
+    }
+
+    instancesObject, ok := config.GetObject("instances")
+    if !ok {
+        return nil, errors.New("no instances")
+    }
+
+    var instances []*Instance
+    for k, v := range instancesObject {
+        i, err := ProcessInstanceObject(v, k)
+        if err != nil {
+            return nil, err
+        }
+        if i.Window == 0 {
+            i.Window = window
+        }
+        if i.RefreshJitter == 0 {
+            i.RefreshJitter = refreshJitter
+        }
+        for class, kind := range classes {
+            if _, ok := i.Classes[class]; !ok {
+                if i.Classes == nil {
+                    i.Classes = ClassMap{}
+                }
+                i.Classes[class] = kind
+            }
+        }
+        if i.Columns == nil {
+            i.Columns = columns
+        }
+        if i.Artifacts == nil {
+            i.Artifacts = artifacts
+        }
+        if i.Tier != "" {
+            interval, ok := tiers[i.Tier]
+            if !ok {
+                return nil, errors.New(fmt.Sprintf("Instance %s has unknown refresh tier %s", i.Name, i.Tier))
+            }
+            if i.RefreshInterval == 0 {
+                i.RefreshInterval = interval
+            }
+        }
+        instances = append(instances, i)
+    }
+
+    sectionsArray, _ := config.GetArray("sections")
+    sections, err := parseSections(sectionsArray)
+    if err != nil {
+        return nil, err
+    }
+
+    dashboardsObject, _ := config.GetObject("dashboards")
+    dashboards, err := parseDashboards(dashboardsObject)
+    if err != nil {
+        return nil, err
+    }
+
+    selectors := append([]*Section(nil), sections...)
+    for _, d := range dashboards {
+        selectors = append(append(selectors, &d.Section), d.Sections...)
+    }
+    for _, s := range selectors {
+        for _, name := range s.Instances {
+            if _, ok := instancesObject[name]; !ok {
+                return nil, errors.New(fmt.Sprintf("section %s refers to unknown instance %s", s.Name, name))
+            }
+        }
+    }
+
+    return &Config{int(maxBuilds), int(maxHistory), int(workers), order, window, staleAfter, staleSection, scale, int(pageSize), location, exporter,
+        sinks, sections, dashboards, instances, config}, nil
+}
+
+// parseTracing parses the tracing section of a configuration:
+//
+//     "tracing": {
+//         "endpoint": "http://otel-collector:4318",
+//         "serviceName": "jitdash",
+//         "headers": {"Authorization": "Bearer ..."}
+//     }
+func parseTracing(tracingObject JsonObject) (*tracing.Exporter, error) {
+    e := &tracing.Exporter{Headers: make(map[string]string), Client: &http.Client{Timeout: 10 * time.Second}}
+
+    endpoint, ok := tracingObject.GetString("endpoint")
+    if !ok {
+        return nil, errors.New("no endpoint")
+    }
+    e.Endpoint = strings.TrimSuffix(endpoint, "/")
+
+    e.ServiceName, ok = tracingObject.GetString("serviceName")
+    if !ok {
+        e.ServiceName = "jitdash"
+    }
+
+    headersObject, _ := tracingObject.GetObject("headers")
+    for k := range headersObject {
+        v, ok := headersObject.GetString(k)
+        if !ok {
+            return nil, errors.New(fmt.Sprintf("header %s is not a string", k))
+        }
+        e.Headers[k] = v
+    }
+
+    return e, nil
+}
+
+func parsePublisher(publishObject JsonObject) (*metrics.Publisher, error) {
+    p := &metrics.Publisher{Headers: make(map[string]string), Client: &http.Client{Timeout: 10 * time.Second}}
+
+    p.PushgatewayUrl, _ = publishObject.GetString("pushgateway")
+    p.RemoteWriteUrl, _ = publishObject.GetString("remoteWrite")
+    if p.PushgatewayUrl == "" && p.RemoteWriteUrl == "" {
+        return nil, errors.New("no pushgateway or remoteWrite URL")
+    }
+
+    var ok bool
+    p.Job, ok = publishObject.GetString("job")
+    if !ok {
+        p.Job = "jitdash"
+    }
+
+    headersObject, _ := publishObject.GetObject("headers")
+    for k := range headersObject {
+        v, ok := headersObject.GetString(k)
+        if !ok {
+            return nil, errors.New(fmt.Sprintf("header %s is not a string", k))
+        }
+        p.Headers[k] = v
+    }
+
+    return p, nil
+}
+
+func parseStatsD(statsdObject JsonObject) (*metrics.StatsD, error) {
+    s := &metrics.StatsD{}
+
+    var ok bool
+    s.Address, ok = statsdObject.GetString("address")
+    if !ok {
+        s.Address = "localhost:8125"
+    }
+    s.Prefix, _ = statsdObject.GetString("prefix")
+    s.DogStatsD, _ = statsdObject.GetBool("dogstatsd")
+
+    tagsArray, _ := statsdObject.GetArray("tags")
+    for _, t := range tagsArray {
+        tag, ok := t.(string)
+        if !ok {
+            return nil, errors.New(fmt.Sprintf("tag %v is not a string", t))
+        }
+        s.Tags = append(s.Tags, tag)
+    }
+
+    return s, nil
+}
+
+func ProcessInstanceObject(instanceIf interface{}, name string) (*Instance, error) {
+    instanceObject, ok := AsJsonObject(instanceIf)
+    if !ok {
+        return nil, errors.New(fmt.Sprintf("Instance %s is not an object", name))
+    }
+
+    // Folders, views, and jobs may be given relative to the instance's base URL, e.g. when Jenkins is served under a
+    // path prefix.
+    baseUrl, _ := instanceObject.GetString("url")
+    if baseUrl != "" && !strings.HasSuffix(baseUrl, "/") {
+        baseUrl += "/"
+    }
+
+    // Views listed among the folders are treated as views so that their names can be used as groups.
+    var folders, views []string
+    foldersArray, hasFolders := instanceObject.GetArray("folders")
+    for _, f := range foldersArray {
+        folder, ok := f.(string)
+        if !ok {
+            return nil, errors.New(fmt.Sprintf("Instance %s contains an invalid folder: %s", name, f))
+        }
+        folder = normalizeUrl(ResolveUrl(baseUrl, folder))
+        if isViewUrl(folder) {
+            views = append(views, folder + "api/json")
+        } else {
+            folders = append(folders, folder + "api/json")
+        }
+    }
+
+    viewsArray, hasViews := instanceObject.GetArray("views")
+    for _, v := range viewsArray {
+        view, ok := v.(string)
+        if !ok {
+            return nil, errors.New(fmt.Sprintf("Instance %s contains an invalid view: %s", name, v))
+        }
+        views = append(views, normalizeUrl(ResolveUrl(baseUrl, view)) + "api/json")
+    }
+
+    var jobs []string
+    jobsArray, hasJobs := instanceObject.GetArray("jobs")
+    for _, j := range jobsArray {
+        job, ok := j.(string)
+        if !ok {
+            return nil, errors.New(fmt.Sprintf("Instance %s contains an invalid job: %v", name, j))
+        }
+        jobs = append(jobs, normalizeUrl(ResolveUrl(baseUrl, job)))
+    }
+
+    kind, ok := instanceObject.GetString("type")
+    if !ok {
+        kind = "jenkins"
+    }
+    if kind == "jenkins" && !hasFolders && !hasViews && !hasJobs {
+        return nil, errors.New(fmt.Sprintf("Instance %s specifies no folders, views, or jobs", name))
+    }
+
+    var exclude []*regexp.Regexp
+    excludeArray, ok := instanceObject.GetArray("exclude")
+    if ok {
+        for _, e := range excludeArray {
+            estr, ok := e.(string)
+            if !ok {
+                return nil, errors.New(fmt.Sprintf("Instance %s contains an invalid exclude: %s", name, e))
+            }
+
+            ex, err := regexp.Compile(estr)
+            if err != nil {
+                return nil, errors.New(fmt.Sprintf("Instance %s contains an invalid exclude %s: %s", name, estr, err))
+            }
+
+            exclude = append(exclude, ex)
+        }
+    }
+
+    var groups []GroupRule
+    groupsArray, ok := instanceObject.GetArray("groups")
+    if ok {
+        for _, g := range groupsArray {
+            groupObject, ok := AsJsonObject(g)
+            if !ok {
+                return nil, errors.New(fmt.Sprintf("Instance %s contains an invalid group: %v", name, g))
+            }
+
+            groupName, ok := groupObject.GetString("name")
+            if !ok || groupName == "" {
+                return nil, errors.New(fmt.Sprintf("Instance %s contains a group with no name", name))
+            }
+
+            match, ok := groupObject.GetString("match")
+            if !ok {
+                return nil, errors.New(fmt.Sprintf("Instance %s group %s specifies no match", name, groupName))
+            }
+
+            re, err := regexp.Compile(match)
+            if err != nil {
+                return nil, errors.New(fmt.Sprintf("Instance %s group %s contains an invalid match %s: %s", name, groupName, match, err))
+            }
+
+            groups = append(groups, GroupRule{groupName, re})
+        }
+    }
+
+    expandMatrix, _ := instanceObject.GetBool("expandMatrix")
+    recurse, _ := instanceObject.GetBool("recurse")
+
+    consoleTail, err := positiveLimit(instanceObject, "consoleTail")
+    if err != nil {
+        return nil, errors.New(fmt.Sprintf("Instance %s %s", name, err))
+    }
+
+    var parameters []string
+    parametersArray, _ := instanceObject.GetArray("parameters")
+    for _, p := range parametersArray {
+        parameter, ok := p.(string)
+        if !ok {
+            return nil, errors.New(fmt.Sprintf("Instance %s contains an invalid parameter: %v", name, p))
+        }
+        parameters = append(parameters, parameter)
+    }
+
+    branches, err := parseBranchFilter(instanceObject)
+    if err != nil {
+        return nil, errors.New(fmt.Sprintf("Instance %s %s", name, err))
+    }
+    showAgents, _ := instanceObject.GetBool("agents")
+
+    var clientOptions ClientOptions
+    clientOptions.Proxy, _ = instanceObject.GetString("proxy")
+    clientOptions.CAFile, _ = instanceObject.GetString("caFile")
+    clientOptions.CertFile, _ = instanceObject.GetString("certFile")
+    clientOptions.KeyFile, _ = instanceObject.GetString("keyFile")
+    clientOptions.InsecureSkipVerify, _ = instanceObject.GetBool("insecureSkipVerify")
+    clientOptions.ConditionalRequests, ok = instanceObject.GetBool("conditionalRequests")
+    if !ok {
+        clientOptions.ConditionalRequests = true
+    }
+    if concurrency, ok := instanceObject.GetInt64("concurrency"); ok {
+        clientOptions.Concurrency = int(concurrency)
+    }
+    if rateLimit, ok := instanceObject.GetFloat64("rateLimit"); ok {
+        clientOptions.RateLimit = rateLimit
+    }
+    clientOptions.Timeout = time.Minute
+    if timeout, ok := instanceObject.GetString("timeout"); ok {
+        d, err := time.ParseDuration(timeout)
+        if err != nil || d < 0 {
+            return nil, errors.New(fmt.Sprintf("Instance %s has an invalid timeout %s", name, timeout))
+        }
+        clientOptions.Timeout = d
+    }
+
+    if headersObject, ok := instanceObject.GetObject("headers"); ok {
+        clientOptions.Headers = http.Header{}
+        for k, v := range headersObject {
+            value, ok := v.(string)
+            if !ok {
+                return nil, errors.New(fmt.Sprintf("Instance %s has an invalid value for header %s", name, k))
+            }
+            clientOptions.Headers.Set(k, value)
+        }
+    }
+    clientOptions.Redirects, _ = instanceObject.GetString("redirects")
+    clientOptions.Username, _ = instanceObject.GetString("username")
+    token, err := parseSecret(instanceObject, "apiToken")
+    if err != nil {
+        return nil, errors.New(fmt.Sprintf("Instance %s has invalid credentials: %s", name, err))
+    }
+    clientOptions.Token = token
+
+    clientOptions.Name = name
+    if workers, ok := instanceObject.GetInt64("workers"); ok {
+        if workers <= 0 {
+            return nil, errors.New(fmt.Sprintf("Instance %s has an invalid worker count %d", name, workers))
+        }
+        clientOptions.Workers = int(workers)
+    }
+    client, err := NewClient(clientOptions)
+    if err != nil {
+        return nil, errors.New(fmt.Sprintf("Instance %s has an invalid HTTP configuration: %s", name, err))
+    }
+
+    var refreshInterval time.Duration
+    if interval, ok := instanceObject.GetString("refreshInterval"); ok {
+        d, err := time.ParseDuration(interval)
+        if err != nil || d <= 0 {
+            return nil, errors.New(fmt.Sprintf("Instance %s has an invalid refreshInterval %s", name, interval))
+        }
+        refreshInterval = d
+    }
+
+    var refreshJitter time.Duration
+    if jitter, ok := instanceObject.GetString("refreshJitter"); ok {
+        d, err := time.ParseDuration(jitter)
+        if err != nil || d < 0 {
+            return nil, errors.New(fmt.Sprintf("Instance %s has an invalid refreshJitter %s", name, jitter))
+        }
+        refreshJitter = d
+    }
+    tier, _ := instanceObject.GetString("tier")
+
+    flagDuplicates := false
+    if duplicates, ok := instanceObject.GetString("duplicates"); ok {
+        switch duplicates {
+        case "merge":
+        case "flag":
+            flagDuplicates = true
+        default:
+            return nil, errors.New(fmt.Sprintf("Instance %s has an invalid duplicates setting %s", name, duplicates))
+        }
+    }
+
+    maxBuilds, err := positiveLimit(instanceObject, "maxBuilds")
+    if err != nil {
+        return nil, errors.New(fmt.Sprintf("Instance %s %s", name, err))
+    }
+    maxHistory, err := positiveLimit(instanceObject, "maxHistory")
+    if err != nil {
+        return nil, errors.New(fmt.Sprintf("Instance %s %s", name, err))
+    }
+
+    var limits []LimitRule
+    limitsArray, ok := instanceObject.GetArray("limits")
+    if ok {
+        for _, l := range limitsArray {
+            limitObject, ok := AsJsonObject(l)
+            if !ok {
+                return nil, errors.New(fmt.Sprintf("Instance %s contains an invalid limit: %v", name, l))
+            }
+
+            match, ok := limitObject.GetString("match")
+            if !ok {
+                return nil, errors.New(fmt.Sprintf("Instance %s contains a limit that specifies no match", name))
+            }
+
+            re, err := regexp.Compile(match)
+            if err != nil {
+                return nil, errors.New(fmt.Sprintf("Instance %s contains a limit with an invalid match %s: %s", name, match, err))
+            }
+
+            limitBuilds, err := positiveLimit(limitObject, "maxBuilds")
+            if err != nil {
+                return nil, errors.New(fmt.Sprintf("Instance %s limit %s %s", name, match, err))
+            }
+            limitHistory, err := positiveLimit(limitObject, "maxHistory")
+            if err != nil {
+                return nil, errors.New(fmt.Sprintf("Instance %s limit %s %s", name, match, err))
+            }
+
+            limits = append(limits, LimitRule{re, limitBuilds, limitHistory})
+        }
+    }
+
+    var aliases []AliasRule
+    aliasesArray, ok := instanceObject.GetArray("aliases")
+    if ok {
+        for _, a := range aliasesArray {
+            aliasObject, ok := AsJsonObject(a)
+            if !ok {
+                return nil, errors.New(fmt.Sprintf("Instance %s contains an invalid alias: %v", name, a))
+            }
+
+            match, ok := aliasObject.GetString("match")
+            if !ok {
+                return nil, errors.New(fmt.Sprintf("Instance %s contains an alias that specifies no match", name))
+            }
+
+            re, err := regexp.Compile(match)
+            if err != nil {
+                return nil, errors.New(fmt.Sprintf("Instance %s contains an alias with an invalid match %s: %s", name, match, err))
+            }
+
+            template, ok := aliasObject.GetString("name")
+            if !ok || template == "" {
+                return nil, errors.New(fmt.Sprintf("Instance %s alias %s specifies no name", name, match))
+            }
+
+            aliases = append(aliases, AliasRule{re, template})
+        }
+    }
+
+    var scale *Scale
+    if scaleObject, ok := instanceObject.GetObject("scale"); ok {
+        s, err := ParseScale(scaleObject)
+        if err != nil {
+            return nil, errors.New(fmt.Sprintf("Instance %s has an invalid scale: %s", name, err))
+        }
+        scale = &s
+    }
+
+    var scales []ScaleRule
+    scalesArray, ok := instanceObject.GetArray("scales")
+    if ok {
+        for _, s := range scalesArray {
+            scaleObject, ok := AsJsonObject(s)
+            if !ok {
+                return nil, errors.New(fmt.Sprintf("Instance %s contains an invalid scale: %v", name, s))
+            }
+
+            match, ok := scaleObject.GetString("match")
+            if !ok {
+                return nil, errors.New(fmt.Sprintf("Instance %s contains a scale that specifies no match", name))
+            }
+
+            re, err := regexp.Compile(match)
+            if err != nil {
+                return nil, errors.New(fmt.Sprintf("Instance %s contains a scale with an invalid match %s: %s", name, match, err))
+            }
+
+            jobScale, err := ParseScale(scaleObject)
+            if err != nil {
+                return nil, errors.New(fmt.Sprintf("Instance %s scale %s: %s", name, match, err))
+            }
+
+            scales = append(scales, ScaleRule{re, jobScale})
+        }
+    }
+
+    var knownFailures []KnownFailure
+    knownArray, ok := instanceObject.GetArray("knownFailures")
+    if ok {
+        for _, k := range knownArray {
+            knownObject, ok := AsJsonObject(k)
+            if !ok {
+                return nil, errors.New(fmt.Sprintf("Instance %s contains an invalid known failure: %v", name, k))
+            }
+
+            var res [2]*regexp.Regexp
+            for n, key := range []string{"job", "test"} {
+                pattern, ok := knownObject.GetString(key)
+                if !ok {
+                    pattern = ".*"
+                }
+                re, err := regexp.Compile(pattern)
+                if err != nil {
+                    return nil, errors.New(fmt.Sprintf("Instance %s contains a known failure with an invalid %s %s: %s", name, key, pattern, err))
+                }
+                res[n] = re
+            }
+
+            reason, ok := knownObject.GetString("reason")
+            if !ok {
+                return nil, errors.New(fmt.Sprintf("Instance %s contains a known failure that specifies no reason", name))
+            }
+            ticket, _ := knownObject.GetString("ticket")
+
+            knownFailures = append(knownFailures, KnownFailure{res[0], res[1], reason, ticket})
+        }
+    }
+
+    var classifications []ClassificationRule
+    classificationArray, _ := instanceObject.GetArray("classifications")
+    for _, c := range classificationArray {
+        classificationObject, ok := AsJsonObject(c)
+        if !ok {
+            return nil, errors.New(fmt.Sprintf("Instance %s contains an invalid classification: %v", name, c))
+        }
+
+        category, ok := classificationObject.GetString("category")
+        if !ok || category == "" {
+            return nil, errors.New(fmt.Sprintf("Instance %s contains a classification that specifies no category", name))
+        }
+
+        var res [2]*regexp.Regexp
+        for n, key := range []string{"console", "test"} {
+            pattern, ok := classificationObject.GetString(key)
+            if !ok {
+                continue
+            }
+            re, err := regexp.Compile(pattern)
+            if err != nil {
+                return nil, errors.New(fmt.Sprintf("Instance %s contains a classification with an invalid %s %s: %s", name, key, pattern, err))
+            }
+            res[n] = re
+        }
+        if res[0] == nil && res[1] == nil {
+            return nil, errors.New(fmt.Sprintf("Instance %s contains a classification for %s that specifies neither console nor test", name, category))
+        }
+
+        classifications = append(classifications, ClassificationRule{category, res[0], res[1]})
+    }
+
+    // URLs are fetched as configured but may be shown under another prefix, either by giving the display URL that
+    // corresponds to the instance's base URL or by listing rewrites.
+    var urlRewrites []UrlRewrite
+    if displayUrl, ok := instanceObject.GetString("displayUrl"); ok {
+        if baseUrl == "" {
+            return nil, errors.New(fmt.Sprintf("Instance %s has a displayUrl but no url", name))
+        }
+        if !strings.HasSuffix(displayUrl, "/") {
+            displayUrl += "/"
+        }
+        urlRewrites = append(urlRewrites, UrlRewrite{baseUrl, displayUrl})
+    }
+    rewriteArray, _ := instanceObject.GetArray("rewriteUrls")
+    for _, r := range rewriteArray {
+        rewriteObject, ok := AsJsonObject(r)
+        if !ok {
+            return nil, errors.New(fmt.Sprintf("Instance %s contains an invalid URL rewrite: %v", name, r))
+        }
+        fetch, hasFetch := rewriteObject.GetString("fetch")
+        display, hasDisplay := rewriteObject.GetString("display")
+        if !hasFetch || !hasDisplay || fetch == "" {
+            return nil, errors.New(fmt.Sprintf("Instance %s contains a URL rewrite that does not specify both fetch and display", name))
+        }
+        urlRewrites = append(urlRewrites, UrlRewrite{fetch, display})
+    }
+
+    var classes ClassMap
+    if classesObject, ok := instanceObject.GetObject("classes"); ok {
+        m, err := ParseClassMap(classesObject)
+        if err != nil {
+            return nil, errors.New(fmt.Sprintf("Instance %s: %s", name, err))
+        }
+        classes = m
+    }
+
+    var artifacts []string
+    if artifactArray, ok := instanceObject.GetArray("artifacts"); ok {
+        a, err := parseArtifactPatterns(artifactArray)
+        if err != nil {
+            return nil, errors.New(fmt.Sprintf("Instance %s: %s", name, err))
+        }
+        artifacts = a
+    }
+
+    var columns []Column
+    if columnArray, ok := instanceObject.GetArray("columns"); ok {
+        c, err := parseColumns(columnArray)
+        if err != nil {
+            return nil, errors.New(fmt.Sprintf("Instance %s: %s", name, err))
+        }
+        columns = c
+    }
+
+    var deepLinks []DeepLinkRule
+    deepLinkArray, _ := instanceObject.GetArray("deepLinks")
+    for _, l := range deepLinkArray {
+        linkObject, ok := AsJsonObject(l)
+        if !ok {
+            return nil, errors.New(fmt.Sprintf("Instance %s contains an invalid deep link: %v", name, l))
+        }
+
+        match, ok := linkObject.GetString("match")
+        if !ok {
+            return nil, errors.New(fmt.Sprintf("Instance %s contains a deep link that specifies no match", name))
+        }
+
+        re, err := regexp.Compile(match)
+        if err != nil {
+            return nil, errors.New(fmt.Sprintf("Instance %s contains a deep link with an invalid match %s: %s", name, match, err))
+        }
+
+        testReport, _ := linkObject.GetBool("testReport")
+        console, _ := linkObject.GetBool("console")
+        deepLinks = append(deepLinks, DeepLinkRule{re, testReport, console})
+    }
+
+    var window time.Duration
+    if w, ok := instanceObject.GetString("window"); ok {
+        d, err := ParseWindow(w)
+        if err != nil {
+            return nil, errors.New(fmt.Sprintf("Instance %s has an invalid window %s", name, w))
+        }
+        window = d
+    }
+
+    instance := &Instance{
+        Name: name,
+        Folders: folders,
+        Views: views,
+        Jobs: jobs,
+        Exclude: exclude,
+        Groups: groups,
+        ExpandMatrix: expandMatrix,
+        Recurse: recurse,
+        Branches: branches,
+        Parameters: parameters,
+        ConsoleTail: consoleTail,
+        Classifications: classifications,
+        UrlRewrites: urlRewrites,
+        DeepLinks: deepLinks,
+        Classes: classes,
+        Columns: columns,
+        Artifacts: artifacts,
+        Client: client,
+        RefreshInterval: refreshInterval,
+        RefreshJitter: refreshJitter,
+        Tier: tier,
+        Workers: clientOptions.Workers,
+        FlagDuplicates: flagDuplicates,
+        MaxBuilds: maxBuilds,
+        MaxHistory: maxHistory,
+        Limits: limits,
+        Window: window,
+        Aliases: aliases,
+        ShowAgents: showAgents,
+        Scale: scale,
+        Scales: scales,
+        KnownFailureRules: knownFailures,
+    }
+
+    if kind != "jenkins" {
+        factory, ok := backends[kind]
+        if !ok {
+            return nil, errors.New(fmt.Sprintf("Instance %s has unknown type %s", name, kind))
+        }
+
+        backend, err := factory(instance, instanceObject)
+        if err != nil {
+            return nil, errors.New(fmt.Sprintf("Instance %s: %s", name, err))
+        }
+        instance.Backend = backend
+    }
+
+    return instance, nil
+}
+
+// positiveLimit returns the value of the given optional limit, or zero if the limit is not present.
+func positiveLimit(o JsonObject, key string) (int, error) {
+    if _, ok := o[key]; !ok {
+        return 0, nil
+    }
+    limit, ok := o.GetInt64(key)
+    if !ok || limit <= 0 {
+        return 0, errors.New(fmt.Sprintf("has an invalid %s", key))
+    }
+    return int(limit), nil
+}
+
+// ParseWindow parses a history window. Windows are durations as accepted by time.ParseDuration, or a whole number of
+// days followed by "d" (e.g. "7d").
+func ParseWindow(s string) (time.Duration, error) {
+    var d time.Duration
+    var err error
+    if days, ok := strings.CutSuffix(s, "d"); ok {
+        var n int
+        n, err = strconv.Atoi(days)
+        d = time.Duration(n) * 24 * time.Hour
+    } else {
+        d, err = time.ParseDuration(s)
+    }
+    if err != nil {
+        return 0, err
+    }
+    if d <= 0 {
+        return 0, errors.New("window must be positive")
+    }
+    return d, nil
+}
+
+// normalizeUrl converts the URL of a Jenkins folder, view, or job to the form "/abs/path/to/item/". URLs may be given
+// with or without a trailing slash or a trailing "api/json".
+func normalizeUrl(url string) string {
+    url = strings.TrimSuffix(url, "api/json")
+    if !strings.HasSuffix(url, "/") {
+        url += "/"
+    }
+    return url
+}
+
+// isViewUrl returns true if the given normalized URL refers to a view.
+func isViewUrl(url string) bool {
+    segments := strings.Split(strings.TrimSuffix(url, "/"), "/")
+    return len(segments) >= 2 && segments[len(segments) - 2] == "view"
+}
@@ -0,0 +1,52 @@
+package jenkins
+
+import (
+    "fmt"
+    "io"
+    "net/http"
+)
+
+// ConsoleTail returns the last n bytes of the console output of the build at the given URL. The tail is requested with
+// a range request; if the server ignores the range, the output is read in full and all but its tail discarded.
+func (c *Client) ConsoleTail(buildUrl string, n int) (string, error) {
+    req, err := http.NewRequest("GET", apiUrl(buildUrl, "consoleText"), nil)
+    if err != nil {
+        return "", err
+    }
+    req.Header.Set("Range", fmt.Sprintf("bytes=-%d", n))
+
+    resp, err := c.httpClient().Do(req)
+    if err != nil {
+        return "", err
+    }
+    defer resp.Body.Close()
+
+    switch resp.StatusCode {
+    case http.StatusPartialContent:
+        tail, err := io.ReadAll(io.LimitReader(resp.Body, int64(n)))
+        return string(tail), err
+    case http.StatusOK:
+        buf := make([]byte, 2 * n)
+        length := 0
+        for {
+            read, err := resp.Body.Read(buf[length:])
+            length += read
+            if length == len(buf) {
+                copy(buf, buf[n:])
+                length = n
+            }
+            if err == io.EOF {
+                break
+            }
+            if err != nil {
+                return "", err
+            }
+        }
+        if length > n {
+            return string(buf[length - n:length]), nil
+        }
+        return string(buf[:length]), nil
+    default:
+        return "", &StatusError{StatusCode: resp.StatusCode, Status: resp.Status}
+    }
+}
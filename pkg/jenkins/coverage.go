@@ -0,0 +1,89 @@
+package jenkins
+
+import (
+    "errors"
+)
+
+// Coverage is the code coverage that a build's tests achieved, as percentages.
+type Coverage struct {
+    Lines float64
+    Branches float64 // or -1 if the build's coverage report does not measure branches
+}
+
+var missingCoverageError = errors.New("missing line coverage")
+
+// BuildCoverage fetches the coverage report of the build at the given URL from the plugin that recorded it, given by
+// the kind of the build's coverage action.
+func (c *Client) BuildCoverage(buildUrl string, kind ClassKind) (*Coverage, error) {
+    switch kind {
+    case ClassCoberturaCoverage:
+        return c.coberturaCoverage(buildUrl)
+    case ClassJacocoCoverage:
+        return c.jacocoCoverage(buildUrl)
+    default:
+        return nil, errors.New("unknown coverage action " + string(kind))
+    }
+}
+
+// coberturaCoverage fetches a Cobertura report, which lists a ratio per metric, e.g.
+// {"results": {"elements": [{"name": "Lines", "ratio": 83.3}, {"name": "Conditionals", "ratio": 61.0}]}}.
+func (c *Client) coberturaCoverage(buildUrl string) (*Coverage, error) {
+    report, err := c.Object(apiUrl(buildUrl, "cobertura/api/json?depth=2"))
+    if err != nil {
+        return nil, err
+    }
+
+    coverage, hasLines := &Coverage{Branches: -1}, false
+    results, _ := report.GetObject("results")
+    elements, _ := results.GetArray("elements")
+    for _, e := range elements {
+        element, ok := AsJsonObject(e)
+        if !ok {
+            continue
+        }
+        ratio, ok := element.GetFloat64("ratio")
+        if !ok {
+            continue
+        }
+        switch name, _ := element.GetString("name"); name {
+        case "Lines":
+            coverage.Lines, hasLines = ratio, true
+        case "Conditionals":
+            coverage.Branches = ratio
+        }
+    }
+    if !hasLines {
+        return nil, missingCoverageError
+    }
+    return coverage, nil
+}
+
+// jacocoCoverage fetches a JaCoCo report, which has an object per counter, e.g.
+// {"lineCoverage": {"percentageFloat": 83.3}, "branchCoverage": {"percentageFloat": 61.0}}.
+func (c *Client) jacocoCoverage(buildUrl string) (*Coverage, error) {
+    report, err := c.Object(apiUrl(buildUrl, "jacoco/api/json"))
+    if err != nil {
+        return nil, err
+    }
+
+    percentage := func(counter string) (float64, bool) {
+        o, ok := report.GetObject(counter)
+        if !ok {
+            return 0, false
+        }
+        if p, ok := o.GetFloat64("percentageFloat"); ok {
+            return p, true
+        }
+        return o.GetFloat64("percentage")
+    }
+
+    lines, ok := percentage("lineCoverage")
+    if !ok {
+        return nil, missingCoverageError
+    }
+    coverage := &Coverage{Lines: lines, Branches: -1}
+    if branches, ok := percentage("branchCoverage"); ok {
+        coverage.Branches = branches
+    }
+    return coverage, nil
+}
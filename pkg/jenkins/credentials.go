@@ -0,0 +1,97 @@
+package jenkins
+
+import (
+    "errors"
+    "fmt"
+    "net/http"
+    "os"
+    "time"
+
+    "github.com/pgavlin/jitdash/pkg/secrets"
+)
+
+// defaultSecretTTL is the time for which secrets read from external stores are cached.
+const defaultSecretTTL = 5 * time.Minute
+
+// parseSecret parses a secret that may be given by the value of key, read from the file named by key + "File", or read
+// from the external store described by key + "Secret". It returns nil if none of these keys is present.
+func parseSecret(o JsonObject, key string) (secrets.Source, error) {
+    if value, ok := o.GetString(key); ok {
+        return secrets.Literal(value), nil
+    }
+    if path, ok := o.GetString(key + "File"); ok {
+        return secrets.NewFile(path), nil
+    }
+
+    secretObject, ok := o.GetObject(key + "Secret")
+    if !ok {
+        return nil, nil
+    }
+
+    ttl := defaultSecretTTL
+    if s, ok := secretObject.GetString("cacheTTL"); ok {
+        d, err := time.ParseDuration(s)
+        if err != nil || d < 0 {
+            return nil, errors.New(fmt.Sprintf("%sSecret has an invalid cacheTTL %s", key, s))
+        }
+        ttl = d
+    }
+
+    client := &http.Client{Timeout: 30 * time.Second}
+    if vaultObject, ok := secretObject.GetObject("vault"); ok {
+        v := &secrets.Vault{Client: client}
+        v.Address, ok = vaultObject.GetString("address")
+        if !ok {
+            v.Address = os.Getenv("VAULT_ADDR")
+        }
+        v.Path, _ = vaultObject.GetString("path")
+        v.Field, _ = vaultObject.GetString("field")
+        if v.Address == "" || v.Path == "" || v.Field == "" {
+            return nil, errors.New(fmt.Sprintf("%sSecret must specify a Vault address, path, and field", key))
+        }
+
+        token, err := parseSecret(vaultObject, "token")
+        if err != nil {
+            return nil, err
+        }
+        if token == nil {
+            token = secrets.Literal(os.Getenv("VAULT_TOKEN"))
+        }
+        v.Token = token
+        return secrets.Cached(v, ttl), nil
+    }
+    if awsObject, ok := secretObject.GetObject("aws"); ok {
+        s := &secrets.AWSSecretsManager{Client: client}
+        s.Region, ok = awsObject.GetString("region")
+        if !ok {
+            s.Region = os.Getenv("AWS_REGION")
+        }
+        s.SecretId, _ = awsObject.GetString("secretId")
+        if s.Region == "" || s.SecretId == "" {
+            return nil, errors.New(fmt.Sprintf("%sSecret must specify an AWS region and secretId", key))
+        }
+        s.Key, _ = awsObject.GetString("key")
+        s.Endpoint, _ = awsObject.GetString("endpoint")
+        return secrets.Cached(s, ttl), nil
+    }
+    return nil, errors.New(fmt.Sprintf("%sSecret must specify either vault or aws", key))
+}
+
+// credentialsTransport authenticates each request to a Jenkins instance with a user name and API token. The token is
+// read for each request so that rotated tokens take effect without a restart.
+type credentialsTransport struct {
+    username string
+    token secrets.Source
+    next http.RoundTripper
+}
+
+func (t *credentialsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+    token, err := t.token.Secret()
+    if err != nil {
+        return nil, errors.New(fmt.Sprintf("could not read API token: %s", err))
+    }
+
+    req = req.Clone(req.Context())
+    req.SetBasicAuth(t.username, token)
+    return t.next.RoundTrip(req)
+}
@@ -0,0 +1,160 @@
+package jenkins
+
+import (
+    "errors"
+    "fmt"
+    "sort"
+)
+
+// A Dashboard is a named view of the configured instances, so that a single process can serve several teams. The
+// dashboard's selectors choose the instances and jobs it shows and its display options apply to all of them.
+type Dashboard struct {
+    Section
+    Sections []*Section // the dashboard's curated sections, or empty to organize the dashboard by instance
+    AllowedUsers []string // in serve mode, the users who may see the dashboard and the jobs it shows
+    AllowedGroups []string // in serve mode, the groups whose members may see the dashboard and the jobs it shows
+}
+
+// Restricted returns true if only the allowed users and groups may see the dashboard.
+func (d *Dashboard) Restricted() bool {
+    return len(d.AllowedUsers) != 0 || len(d.AllowedGroups) != 0
+}
+
+// Allows returns true if the given user, a member of the given groups, may see the dashboard. Anyone, including
+// anonymous users, may see unrestricted dashboards; restricted dashboards are hidden from anonymous users.
+func (d *Dashboard) Allows(user string, groups []string) bool {
+    if !d.Restricted() {
+        return true
+    }
+    if user == "" {
+        return false
+    }
+    if contains(d.AllowedUsers, user) {
+        return true
+    }
+    for _, g := range groups {
+        if contains(d.AllowedGroups, g) {
+            return true
+        }
+    }
+    return false
+}
+
+// Filter returns the instances and jobs that the dashboard shows. The jobs of the given instances are not modified.
+func (d *Dashboard) Filter(instances []*InstanceJobs) []*InstanceJobs {
+    var filtered []*InstanceJobs
+    for _, ij := range instances {
+        if len(d.Instances) != 0 && !contains(d.Instances, ij.Instance.Name) {
+            continue
+        }
+
+        f := *ij
+        f.Jobs = nil
+        for _, j := range ij.Jobs {
+            if d.Selects(ij.Instance, j) {
+                f.Jobs = append(f.Jobs, j)
+            }
+        }
+        filtered = append(filtered, &f)
+    }
+    return filtered
+}
+
+// shows returns true if the dashboard ever lists the given job of the given instance, whether or not the job is
+// failing.
+func (d *Dashboard) shows(i *Instance, job *Job) bool {
+    if len(d.Instances) != 0 && !contains(d.Instances, i.Name) {
+        return false
+    }
+    selector := d.Section
+    selector.OnlyFailing = false
+    return selector.Selects(i, job)
+}
+
+// Visible returns the instances and jobs that the given user, a member of the given groups, may see anywhere in serve
+// mode. The jobs that restricted dashboards show are confidential: only users whom one of those dashboards allows may
+// see them. Instances whose jobs are all confidential to the user are omitted. The jobs of the given instances are not
+// modified.
+func Visible(dashboards []*Dashboard, user string, groups []string, instances []*InstanceJobs) []*InstanceJobs {
+    var restricted []*Dashboard
+    for _, d := range dashboards {
+        if d.Restricted() {
+            restricted = append(restricted, d)
+        }
+    }
+    if len(restricted) == 0 {
+        return instances
+    }
+
+    visible := func(i *Instance, job *Job) bool {
+        confidential := false
+        for _, d := range restricted {
+            if d.shows(i, job) {
+                if d.Allows(user, groups) {
+                    return true
+                }
+                confidential = true
+            }
+        }
+        return !confidential
+    }
+
+    filtered := []*InstanceJobs{}
+    for _, ij := range instances {
+        f := *ij
+        f.Jobs = nil
+        for _, j := range ij.Jobs {
+            if visible(ij.Instance, j) {
+                f.Jobs = append(f.Jobs, j)
+            }
+        }
+        if len(f.Jobs) == 0 && len(ij.Jobs) != 0 {
+            continue
+        }
+        filtered = append(filtered, &f)
+    }
+    return filtered
+}
+
+// parseDashboards parses the named dashboards, which are ordered by name.
+func parseDashboards(dashboardsObject JsonObject) ([]*Dashboard, error) {
+    names := make([]string, 0, len(dashboardsObject))
+    for name := range dashboardsObject {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+
+    var dashboards []*Dashboard
+    for _, name := range names {
+        dashboardObject, ok := AsJsonObject(dashboardsObject[name])
+        if !ok {
+            return nil, errors.New(fmt.Sprintf("dashboard %s is not an object", name))
+        }
+
+        selector, err := parseSection(name, dashboardObject)
+        if err != nil {
+            return nil, err
+        }
+
+        sectionsArray, _ := dashboardObject.GetArray("sections")
+        sections, err := parseSections(sectionsArray)
+        if err != nil {
+            return nil, errors.New(fmt.Sprintf("dashboard %s: %s", name, err))
+        }
+
+        var access [2][]string
+        for n, key := range []string{"allowedUsers", "allowedGroups"} {
+            array, _ := dashboardObject.GetArray(key)
+            for _, e := range array {
+                str, ok := e.(string)
+                if !ok {
+                    return nil, errors.New(fmt.Sprintf("dashboard %s has an invalid %s entry: %v", name, key, e))
+                }
+                access[n] = append(access[n], str)
+            }
+        }
+
+        dashboards = append(dashboards, &Dashboard{*selector, sections, access[0], access[1]})
+    }
+    return dashboards, nil
+}
@@ -0,0 +1,87 @@
+package jenkins_test
+
+import (
+    "reflect"
+    "regexp"
+    "testing"
+
+    "github.com/pgavlin/jitdash/pkg/jenkins"
+)
+
+func TestDashboardAllows(t *testing.T) {
+    open := &jenkins.Dashboard{}
+    users := &jenkins.Dashboard{AllowedUsers: []string{"alice"}}
+    groups := &jenkins.Dashboard{AllowedUsers: []string{"alice"}, AllowedGroups: []string{"release"}}
+
+    tests := []struct {
+        name string
+        dashboard *jenkins.Dashboard
+        user string
+        groups []string
+        allows bool
+    }{
+        {"unrestricted anonymous", open, "", nil, true},
+        {"unrestricted user", open, "bob", nil, true},
+        {"allowed user", users, "alice", nil, true},
+        {"other user", users, "bob", []string{"release"}, false},
+        {"anonymous", users, "", nil, false},
+        {"allowed group", groups, "bob", []string{"qa", "release"}, true},
+        {"other group", groups, "bob", []string{"qa"}, false},
+        {"anonymous in allowed group", groups, "", []string{"release"}, false},
+    }
+    for _, test := range tests {
+        if allows := test.dashboard.Allows(test.user, test.groups); allows != test.allows {
+            t.Errorf("%s: Allows is %v, want %v", test.name, allows, test.allows)
+        }
+    }
+}
+
+func TestVisible(t *testing.T) {
+    ci, release := &jenkins.Instance{Name: "ci"}, &jenkins.Instance{Name: "release"}
+    instances := []*jenkins.InstanceJobs{
+        {Instance: ci, Jobs: []*jenkins.Job{{Name: "build"}, {Name: "secret-deploy"}}},
+        {Instance: release, Jobs: []*jenkins.Job{{Name: "publish"}}},
+    }
+
+    dashboards := []*jenkins.Dashboard{
+        {Section: jenkins.Section{Name: "all"}},
+        {Section: jenkins.Section{Name: "secrets", Jobs: []*regexp.Regexp{regexp.MustCompile("^secret-")},
+            OnlyFailing: true}, AllowedUsers: []string{"alice"}},
+        {Section: jenkins.Section{Name: "release", Instances: []string{"release"}}, AllowedGroups: []string{"release"}},
+    }
+
+    // visible returns the names of the instances and jobs that the given user may see.
+    visible := func(dashboards []*jenkins.Dashboard, user string, groups []string) map[string][]string {
+        names := map[string][]string{}
+        for _, ij := range jenkins.Visible(dashboards, user, groups, instances) {
+            names[ij.Instance.Name] = jobNames(ij.Jobs)
+        }
+        return names
+    }
+
+    tests := []struct {
+        name string
+        dashboards []*jenkins.Dashboard
+        user string
+        groups []string
+        visible map[string][]string
+    }{
+        {"unrestricted", dashboards[:1], "", nil,
+            map[string][]string{"ci": {"build", "secret-deploy"}, "release": {"publish"}}},
+        {"anonymous", dashboards, "", nil, map[string][]string{"ci": {"build"}}},
+        {"allowed user", dashboards, "alice", nil, map[string][]string{"ci": {"build", "secret-deploy"}}},
+        {"allowed group", dashboards, "bob", []string{"release"},
+            map[string][]string{"ci": {"build"}, "release": {"publish"}}},
+        {"other user", dashboards, "bob", []string{"qa"}, map[string][]string{"ci": {"build"}}},
+    }
+    for _, test := range tests {
+        if names := visible(test.dashboards, test.user, test.groups); !reflect.DeepEqual(names, test.visible) {
+            t.Errorf("%s: visible jobs are %v, want %v", test.name, names, test.visible)
+        }
+    }
+
+    // Filtering does not modify the given instances.
+    if n := len(instances[0].Jobs); n != 2 {
+        t.Errorf("instance ci has %d jobs after filtering, want 2", n)
+    }
+}
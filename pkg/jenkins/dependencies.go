@@ -0,0 +1,62 @@
+package jenkins
+
+// projectUrls returns the URLs of the projects listed under the given key of a job's details, e.g. "upstreamProjects".
+// Relative URLs are resolved against the job's URL.
+func projectUrls(jobUrl string, details JsonObject, key string) []string {
+    projects, _ := details.GetArray(key)
+
+    var urls []string
+    for _, p := range projects {
+        project, ok := AsJsonObject(p)
+        if !ok {
+            continue
+        }
+        if url, ok := project.GetString("url"); ok {
+            urls = append(urls, ResolveUrl(jobUrl, url))
+        }
+    }
+    return urls
+}
+
+// JobByUrl returns the first of the instance's jobs with the given URL, or nil if there is none.
+func (ij *InstanceJobs) JobByUrl(url string) *Job {
+    for _, j := range ij.Jobs {
+        if j.Url == url {
+            return j
+        }
+    }
+    return nil
+}
+
+// FailingUpstream returns the failing jobs upstream of the given job that are the likely cause of its failure: those
+// whose own upstream jobs are passing. Upstream jobs that are not among the instance's jobs are ignored. The result is
+// empty unless the given job is itself failing.
+func (ij *InstanceJobs) FailingUpstream(job *Job) []*Job {
+    if !job.Failing() {
+        return nil
+    }
+
+    var causes []*Job
+    visited := map[*Job]bool{job: true}
+    var visit func(j *Job) bool
+    visit = func(j *Job) bool {
+        found := false
+        for _, url := range j.Upstream {
+            u := ij.JobByUrl(url)
+            if u == nil || !u.Failing() {
+                continue
+            }
+            found = true
+            if visited[u] {
+                continue
+            }
+            visited[u] = true
+            if !visit(u) {
+                causes = append(causes, u)
+            }
+        }
+        return found
+    }
+    visit(job)
+    return causes
+}
@@ -0,0 +1,78 @@
+package jenkins
+
+// ChangeKind classifies the difference between two observations of a job.
+type ChangeKind int
+
+const (
+    // ChangeBroken means that the job was passing (or absent) and is now failing.
+    ChangeBroken ChangeKind = iota
+    // ChangeFixed means that the job was failing and is now passing.
+    ChangeFixed
+    // ChangeFailures means that the job was and is failing, but with a different number of failures.
+    ChangeFailures
+)
+
+// String returns the name of the change kind: broken, fixed, or failures.
+func (k ChangeKind) String() string {
+    switch k {
+    case ChangeBroken:
+        return "broken"
+    case ChangeFixed:
+        return "fixed"
+    default:
+        return "failures"
+    }
+}
+
+// A JobChange describes how a job differs between two fetches.
+type JobChange struct {
+    Kind ChangeKind
+    Instance string
+    Job *Job // the job as of the later fetch
+    Before *Build // the most recent completed build as of the earlier fetch, or nil if there was none
+    After *Build // the most recent completed build as of the later fetch
+}
+
+// Diff compares two fetches of the same instances and returns the jobs that newly failed, newly recovered, or whose
+// number of failures changed, in the order in which they appear in the later fetch. Jobs are matched by instance name
+// and URL. Jobs without completed builds in the later fetch and flagged duplicates are ignored.
+func Diff(before, after []*InstanceJobs) []*JobChange {
+    type jobKey struct {
+        instance string
+        url string
+    }
+
+    previous := make(map[jobKey]*Build)
+    for _, ij := range before {
+        for _, j := range ij.Jobs {
+            if !j.Duplicate {
+                previous[jobKey{ij.Instance.Name, j.Url}] = j.LastCompletedBuild()
+            }
+        }
+    }
+
+    var changes []*JobChange
+    for _, ij := range after {
+        for _, j := range ij.Jobs {
+            last := j.LastCompletedBuild()
+            if j.Duplicate || last == nil {
+                continue
+            }
+            prev := previous[jobKey{ij.Instance.Name, j.Url}]
+
+            var kind ChangeKind
+            switch {
+            case last.Failed() && (prev == nil || !prev.Failed()):
+                kind = ChangeBroken
+            case !last.Failed() && prev != nil && prev.Failed():
+                kind = ChangeFixed
+            case last.Failed() && prev.Failures != last.Failures:
+                kind = ChangeFailures
+            default:
+                continue
+            }
+            changes = append(changes, &JobChange{kind, ij.Instance.Name, j, prev, last})
+        }
+    }
+    return changes
+}
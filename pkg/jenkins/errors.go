@@ -0,0 +1,58 @@
+package jenkins
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "log/slog"
+    "net/http"
+)
+
+// A FetchError records a failure to fetch part of an instance's data. Fetch errors do not abort a fetch; they are
+// collected so that missing data can be reported alongside the data that was fetched.
+type FetchError struct {
+    Instance string
+    Job string // the name of the affected job, or empty if the error is not specific to a job
+    Url string
+    Err error
+}
+
+func (e *FetchError) Error() string {
+    if e.Job == "" {
+        return fmt.Sprintf("%s: %s: %s", e.Instance, e.Url, e.Err)
+    }
+    return fmt.Sprintf("%s: job %s: %s: %s", e.Instance, e.Job, e.Url, e.Err)
+}
+
+func (e *FetchError) Unwrap() error {
+    return e.Err
+}
+
+// IsAuthFailure returns true if the error is due to missing or rejected credentials.
+func (e *FetchError) IsAuthFailure() bool {
+    var se *StatusError
+    return errors.As(e.Err, &se) && (se.StatusCode == http.StatusUnauthorized || se.StatusCode == http.StatusForbidden)
+}
+
+// IsNotFound returns true if the error is due to a job, folder, or view that does not exist.
+func (e *FetchError) IsNotFound() bool {
+    var se *StatusError
+    return errors.As(e.Err, &se) && se.StatusCode == http.StatusNotFound
+}
+
+// NewFetchError logs and returns a FetchError for the given job and URL.
+// Authentication failures are logged as errors so that misconfigured credentials stand out.
+func (i *Instance) NewFetchError(job, url string, err error) *FetchError {
+    e := &FetchError{Instance: i.Name, Job: job, Url: url, Err: err}
+
+    level, msg := slog.LevelWarn, "fetch error"
+    if e.IsAuthFailure() {
+        level, msg = slog.LevelError, "authentication failure"
+    }
+    if job == "" {
+        i.Logger().Log(context.Background(), level, msg, "url", url, "err", err)
+    } else {
+        i.Logger().Log(context.Background(), level, msg, "job", job, "url", url, "err", err)
+    }
+    return e
+}
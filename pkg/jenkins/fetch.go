@@ -0,0 +1,228 @@
+package jenkins
+
+import (
+    "errors"
+    "fmt"
+    "log/slog"
+    "sort"
+    "sync"
+    "time"
+
+    "github.com/pgavlin/jitdash/pkg/tracing"
+)
+
+// DefaultFetchWorkers is the default number of build details that Fetch fetches in parallel across all instances.
+const DefaultFetchWorkers = 100
+
+// InstanceJobs is the set of jobs fetched for a single instance.
+type InstanceJobs struct {
+    Instance *Instance
+    Jobs []*Job
+    Errors []*FetchError // the errors encountered while fetching the instance's jobs
+    Agents *AgentStatus // the status of the instance's agents, or nil if the instance does not show its agents
+    FetchedAt time.Time // the time at which the instance's jobs finished fetching
+    Stale bool // true if the instance was unreachable when last refreshed, so its jobs are those fetched at FetchedAt
+}
+
+// Fetch fetches the jobs for each of the given instances, truncates each job's history to its most recent builds, and
+// fetches the details of the remaining builds. maxBuilds is the default limit; see Instance.JobLimits. If an instance
+// has a window, its Jenkins jobs' histories are instead truncated to the builds that started within the window, whose
+// start times are listed along with the builds; builds of other backends are limited by count and then windowed. At
+// most workers build details are fetched at once (DefaultFetchWorkers if workers is not positive), further limited per
+// instance by Instance.Workers.
+func Fetch(instances []*Instance, maxBuilds, workers int) []*InstanceJobs {
+    return Refetch(instances, nil, maxBuilds, workers, nil)
+}
+
+// Refetch fetches the given instances like Fetch, but reuses what it can of a previous fetch. A previous fetch is only
+// used for the instance it was made for, so that jobs fetched with a different configuration are never reused. The jobs
+// of Jenkins instances whose last builds are unchanged since the previous fetch are not fetched again, and neither are
+// the details of builds that were already complete. Properties of a reused job other than its builds, such as its
+// upstream and downstream jobs, are refreshed the next time it builds.
+//
+// Each instance is fetched independently of the others, so a slow instance does not hold up the rest. If fetched is
+// non-nil, it is called with each instance's jobs as soon as they have been fetched, possibly concurrently.
+func Refetch(instances []*Instance, previous []*InstanceJobs, maxBuilds, workers int, fetched func(ij *InstanceJobs)) []*InstanceJobs {
+    start := time.Now()
+
+    previousJobs := make(map[*Instance][]*Job)
+    for _, ij := range previous {
+        previousJobs[ij.Instance] = ij.Jobs
+    }
+
+    // Each fetch is traced, with a span per instance.
+    trace := tracing.Start("refresh")
+    defer trace.End()
+
+    // Build details are fetched in parallel. Each fetch holds a slot in its instance's pool, if the instance has one,
+    // and a slot in the global pool. The loops that start the fetches block while the pools are full.
+    if workers <= 0 {
+        workers = DefaultFetchWorkers
+    }
+    global := make(chan struct{}, workers)
+    queueDepth, workersBusy := fetchQueueDepth.With(), fetchWorkersBusy.With()
+
+    result := make([]*InstanceJobs, len(instances))
+    var wg sync.WaitGroup
+    for n, i := range instances {
+        span := trace.Start("instance", "instance", i.Name)
+
+        wg.Add(1)
+        go func(n int, i *Instance) {
+            defer wg.Done()
+
+            // Each instance's own client bounds the load placed on that instance.
+            jobs, errs := i.fetchJobs(span, previousJobs[i])
+            ij := &InstanceJobs{Instance: i, Jobs: jobs, Errors: errs}
+            if i.ShowAgents && i.Backend == nil {
+                agentSpan := span.Start("agents")
+                agents, agentErrs := i.FetchAgents()
+                if len(agentErrs) != 0 {
+                    agentSpan.SetError(agentErrs[0])
+                }
+                agentSpan.End()
+                ij.Agents, ij.Errors = agents, append(ij.Errors, agentErrs...)
+            }
+
+            // Flagged duplicate jobs share their builds with the original job, so each build is only fetched once.
+            // Builds of Jenkins jobs that were complete when previously fetched are reused.
+            i.Logger().Info("fetching build details")
+            complete := make(map[string]*Build)
+            if i.Backend == nil {
+                for _, j := range previousJobs[i] {
+                    for _, b := range j.Builds {
+                        if b.Complete && b.Err == nil {
+                            complete[b.Url] = b
+                        }
+                    }
+                }
+            }
+
+            queued := make(map[*Build]bool)
+            var builds []*Build
+            for _, j := range ij.Jobs {
+                j.Builds = i.limitBuilds(j.Name, j.Builds, maxBuilds)
+                for k, b := range j.Builds {
+                    if c, ok := complete[b.Url]; ok {
+                        if c != b {
+                            j.Builds[k] = c
+                        }
+                        continue
+                    }
+                    if !queued[b] {
+                        queued[b] = true
+                        builds = append(builds, b)
+                    }
+                }
+            }
+            queueDepth.Add(float64(len(builds)))
+
+            var local chan struct{}
+            if i.Workers > 0 {
+                local = make(chan struct{}, i.Workers)
+            }
+
+            var fetches sync.WaitGroup
+            var failedM sync.Mutex
+            var failed []*Build
+            for _, b := range builds {
+                if local != nil {
+                    local <- struct{}{}
+                }
+                global <- struct{}{}
+                queueDepth.Add(-1)
+                workersBusy.Add(1)
+
+                fetches.Add(1)
+                go func(b *Build) {
+                    defer fetches.Done()
+
+                    buildSpan := span.Start("build", "url", b.Url)
+                    if err := i.FetchDetails(b); err != nil {
+                        slog.Debug("error fetching build details", "build", b.Url, "err", err)
+                        buildSpan.SetError(err)
+
+                        failedM.Lock()
+                        failed = append(failed, b)
+                        failedM.Unlock()
+                    }
+                    buildSpan.End()
+
+                    workersBusy.Add(-1)
+                    <-global
+                    if local != nil {
+                        <-local
+                    }
+                }(b)
+            }
+            fetches.Wait()
+
+            // Builds whose details could not be fetched are reported by a single error per instance.
+            if len(failed) != 0 {
+                sort.Sort(BuildSorter(failed))
+                err := errors.New(fmt.Sprintf("could not fetch the details of %d builds: %s", len(failed), failed[0].Err))
+                ij.Errors = append(ij.Errors, i.NewFetchError("", failed[0].Url, err))
+            }
+
+            // Column values come from each job's most recent completed build and are kept with it, so they are only
+            // computed once per build. Reused builds may still be in use by the previous fetch and are left alone.
+            computed := make(map[*Build]bool)
+            for _, j := range ij.Jobs {
+                b := j.LastCompletedBuild()
+                if len(i.Columns) == 0 || b == nil || b.Err != nil || b.Columns != nil || !queued[b] || computed[b] {
+                    continue
+                }
+                computed[b] = true
+
+                global <- struct{}{}
+                fetches.Add(1)
+                go func(job string, b *Build) {
+                    defer fetches.Done()
+                    b.Columns = i.ColumnValues(job, b)
+                    <-global
+                }(j.Name, b)
+            }
+            fetches.Wait()
+
+            // The start times of builds whose backends do not list them are only known once details have been fetched,
+            // so windows are applied again last.
+            for _, j := range ij.Jobs {
+                j.Builds = i.windowBuilds(j.Builds)
+            }
+            ij.FetchedAt = time.Now()
+
+            fetchDuration.With(i.Name).Observe(time.Since(start).Seconds())
+            span.End()
+
+            result[n] = ij
+            if fetched != nil {
+                fetched(ij)
+            }
+        }(n, i)
+    }
+    wg.Wait()
+
+    return result
+}
+
+// Unreachable returns true if none of the instance's jobs could be fetched because of errors, e.g. because the
+// instance timed out.
+func (ij *InstanceJobs) Unreachable() bool {
+    return len(ij.Jobs) == 0 && len(ij.Errors) != 0
+}
+
+// KeepLastKnown returns the given fetch of an instance unless the instance was unreachable and the given previous fetch
+// of it has jobs. In that case, KeepLastKnown returns the previous fetch's jobs, marked as stale, along with the errors
+// of the new fetch. previous may be nil.
+func KeepLastKnown(previous, fetched *InstanceJobs) *InstanceJobs {
+    if previous == nil || len(previous.Jobs) == 0 || !fetched.Unreachable() {
+        return fetched
+    }
+    return &InstanceJobs{Instance: fetched.Instance, Jobs: previous.Jobs, Errors: fetched.Errors, Agents: previous.Agents,
+        FetchedAt: previous.FetchedAt, Stale: true}
+}
+
+// FetchInstance fetches a single instance. See Fetch.
+func FetchInstance(i *Instance, maxBuilds, workers int) *InstanceJobs {
+    return Fetch([]*Instance{i}, maxBuilds, workers)[0]
+}
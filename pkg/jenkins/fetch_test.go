@@ -0,0 +1,216 @@
+package jenkins_test
+
+import (
+    "reflect"
+    "strconv"
+    "testing"
+
+    "github.com/pgavlin/jitdash/pkg/jenkins"
+    "github.com/pgavlin/jitdash/pkg/jenkins/jenkinstest"
+)
+
+// object is shorthand for the JSON objects that fixtures are made of.
+type object = map[string]interface{}
+
+// array is shorthand for the JSON arrays that fixtures are made of.
+type array = []interface{}
+
+// jenkinsUrl returns the absolute URL of the given path as the fake server reports it.
+func jenkinsUrl(path string) string {
+    return jenkins.RecordedUrl + path
+}
+
+// newServer starts a fake Jenkins server that serves the given JSON fixtures, keyed by path, and an empty queue.
+func newServer(t *testing.T, fixtures map[string]interface{}) *jenkinstest.Server {
+    s := jenkinstest.NewServer()
+    t.Cleanup(s.Close)
+
+    fixtures["/queue/api/json"] = object{"items": array{}}
+    for path, v := range fixtures {
+        if err := s.HandleJson(path, v); err != nil {
+            t.Fatalf("encoding fixture %s: %s", path, err)
+        }
+    }
+    return s
+}
+
+// newInstance returns an instance of the given server that is configured by the given instance object.
+func newInstance(t *testing.T, s *jenkinstest.Server, config object) *jenkins.Instance {
+    i, err := s.Instance("test", config)
+    if err != nil {
+        t.Fatalf("configuring instance: %s", err)
+    }
+    return i
+}
+
+// listed returns the listing of a job of the given class in a folder or view.
+func listed(class, name, path string) object {
+    return object{"_class": class, "name": name, "url": jenkinsUrl(path)}
+}
+
+// project returns the details of a job of the given class whose builds, of the given class, are numbered 1 to builds.
+func project(class, name, path, buildClass string, builds int) object {
+    buildObjects := array{}
+    for n := builds; n > 0; n-- {
+        buildObjects = append(buildObjects, object{"_class": buildClass, "number": n,
+            "url": jenkinsUrl(path + strconv.Itoa(n) + "/")})
+    }
+    return object{"_class": class, "name": name, "url": jenkinsUrl(path), "builds": buildObjects}
+}
+
+// jobNames returns the names of the given jobs.
+func jobNames(jobs []*jenkins.Job) []string {
+    names := []string{}
+    for _, j := range jobs {
+        names = append(names, j.Name)
+    }
+    return names
+}
+
+func TestFetchJobsClasses(t *testing.T) {
+    s := newServer(t, map[string]interface{}{
+        "/job/f/api/json": object{"_class": "com.cloudbees.hudson.plugins.folder.Folder", "name": "f", "jobs": array{
+            listed("hudson.model.FreeStyleProject", "freestyle", "job/f/job/freestyle/"),
+            listed("org.jenkinsci.plugins.workflow.job.WorkflowJob", "pipeline", "job/f/job/pipeline/"),
+            listed("com.example.CustomProject", "custom", "job/f/job/custom/"),
+            listed("hudson.model.ExternalJob", "external", "job/f/job/external/"),
+            listed("com.cloudbees.hudson.plugins.folder.Folder", "nested", "job/f/job/nested/"),
+        }},
+        "/job/f/job/freestyle/api/json": project("hudson.model.FreeStyleProject", "freestyle", "job/f/job/freestyle/",
+            "hudson.model.FreeStyleBuild", 2),
+        "/job/f/job/pipeline/api/json": project("org.jenkinsci.plugins.workflow.job.WorkflowJob", "pipeline",
+            "job/f/job/pipeline/", "org.jenkinsci.plugins.workflow.job.WorkflowRun", 1),
+        "/job/f/job/custom/api/json": project("com.example.CustomProject", "custom", "job/f/job/custom/",
+            "com.example.CustomBuild", 1),
+    })
+
+    i := newInstance(t, s, object{"folders": array{"job/f/"}})
+    jobs, errs := i.FetchJobs()
+    if len(errs) != 0 {
+        t.Fatalf("unexpected errors: %v", errs)
+    }
+    if names, want := jobNames(jobs), []string{"freestyle", "pipeline"}; !reflect.DeepEqual(names, want) {
+        t.Errorf("jobs are %v, want %v", names, want)
+    }
+    if n := len(jobs[0].Builds); n != 2 {
+        t.Errorf("freestyle job has %d builds, want 2", n)
+    }
+    if s.Requests("/job/f/job/external/api/json") != 0 || s.Requests("/job/f/job/nested/api/json") != 0 {
+        t.Errorf("fetched a job of an unknown class or a nested folder without recursing")
+    }
+
+    // Classes that plugins introduce can be mapped to kinds, and built-in classes can be disabled.
+    i = newInstance(t, s, object{"folders": array{"job/f/"}, "classes": object{
+        "com.example.CustomProject": "job",
+        "com.example.CustomBuild": "build",
+        "org.jenkinsci.plugins.workflow.job.WorkflowJob": "ignored",
+    }})
+    jobs, errs = i.FetchJobs()
+    if len(errs) != 0 {
+        t.Fatalf("unexpected errors: %v", errs)
+    }
+    if names, want := jobNames(jobs), []string{"freestyle", "custom"}; !reflect.DeepEqual(names, want) {
+        t.Errorf("jobs with custom classes are %v, want %v", names, want)
+    }
+    if n := len(jobs[1].Builds); n != 1 {
+        t.Errorf("custom job has %d builds, want 1", n)
+    }
+}
+
+func TestFetchJobsMatrix(t *testing.T) {
+    s := newServer(t, map[string]interface{}{
+        "/job/f/api/json": object{"name": "f", "jobs": array{
+            listed("hudson.matrix.MatrixProject", "matrix", "job/f/job/matrix/"),
+        }},
+        "/job/f/job/matrix/api/json": func() object {
+            p := project("hudson.matrix.MatrixProject", "matrix", "job/f/job/matrix/", "hudson.matrix.MatrixBuild", 2)
+            p["activeConfigurations"] = array{
+                object{"name": "os=linux", "url": jenkinsUrl("job/f/job/matrix/os=linux/")},
+                object{"name": "os=windows", "url": jenkinsUrl("job/f/job/matrix/os=windows/")},
+            }
+            return p
+        }(),
+        "/job/f/job/matrix/os=linux/api/json": project("hudson.matrix.MatrixConfiguration", "os=linux",
+            "job/f/job/matrix/os=linux/", "hudson.matrix.MatrixRun", 2),
+        "/job/f/job/matrix/os=windows/api/json": project("hudson.matrix.MatrixConfiguration", "os=windows",
+            "job/f/job/matrix/os=windows/", "hudson.matrix.MatrixRun", 1),
+    })
+
+    jobs, errs := newInstance(t, s, object{"folders": array{"job/f/"}}).FetchJobs()
+    if len(errs) != 0 {
+        t.Fatalf("unexpected errors: %v", errs)
+    }
+    if names, want := jobNames(jobs), []string{"matrix"}; !reflect.DeepEqual(names, want) {
+        t.Errorf("jobs are %v, want %v", names, want)
+    }
+
+    jobs, errs = newInstance(t, s, object{"folders": array{"job/f/"}, "expandMatrix": true}).FetchJobs()
+    if len(errs) != 0 {
+        t.Fatalf("unexpected errors: %v", errs)
+    }
+    if names, want := jobNames(jobs), []string{"matrix/os=linux", "matrix/os=windows"}; !reflect.DeepEqual(names, want) {
+        t.Fatalf("expanded jobs are %v, want %v", names, want)
+    }
+    if n := len(jobs[0].Builds); n != 2 {
+        t.Errorf("configuration %s has %d builds, want 2", jobs[0].Name, n)
+    }
+}
+
+func TestFetchJobsMultibranch(t *testing.T) {
+    branch := func(name string, primary bool) object {
+        b := listed("org.jenkinsci.plugins.workflow.job.WorkflowJob", name, "job/f/job/repo/job/" + name + "/")
+        if primary {
+            b["actions"] = array{object{"_class": "jenkins.scm.api.metadata.PrimaryInstanceMetadataAction"}}
+        }
+        return b
+    }
+
+    fixtures := map[string]interface{}{
+        "/job/f/api/json": object{"name": "f", "jobs": array{
+            listed("org.jenkinsci.plugins.workflow.multibranch.WorkflowMultiBranchProject", "repo", "job/f/job/repo/"),
+        }},
+        "/job/f/job/repo/api/json": object{"jobs": array{branch("main", false), branch("release", true), branch("feature", false)}},
+    }
+    for _, name := range []string{"main", "release", "feature"} {
+        path := "job/f/job/repo/job/" + name + "/"
+        fixtures["/" + path + "api/json"] = project("org.jenkinsci.plugins.workflow.job.WorkflowJob", name, path,
+            "org.jenkinsci.plugins.workflow.job.WorkflowRun", 1)
+    }
+    s := newServer(t, fixtures)
+
+    // Only the branch marked as the default branch is shown unless branches are included by name.
+    jobs, errs := newInstance(t, s, object{"folders": array{"job/f/"}}).FetchJobs()
+    if len(errs) != 0 {
+        t.Fatalf("unexpected errors: %v", errs)
+    }
+    if names, want := jobNames(jobs), []string{"repo/release"}; !reflect.DeepEqual(names, want) {
+        t.Errorf("jobs are %v, want %v", names, want)
+    }
+
+    jobs, errs = newInstance(t, s, object{"folders": array{"job/f/"}, "includeBranches": array{"^(main|feature)$"}}).FetchJobs()
+    if len(errs) != 0 {
+        t.Fatalf("unexpected errors: %v", errs)
+    }
+    if names, want := jobNames(jobs), []string{"repo/main", "repo/feature"}; !reflect.DeepEqual(names, want) {
+        t.Errorf("included branches are %v, want %v", names, want)
+    }
+}
+
+func TestFetchJobsUnreachableJob(t *testing.T) {
+    s := newServer(t, map[string]interface{}{
+        "/job/f/api/json": object{"name": "f", "jobs": array{
+            listed("hudson.model.FreeStyleProject", "ok", "job/f/job/ok/"),
+            listed("hudson.model.FreeStyleProject", "gone", "job/f/job/gone/"),
+        }},
+        "/job/f/job/ok/api/json": project("hudson.model.FreeStyleProject", "ok", "job/f/job/ok/",
+            "hudson.model.FreeStyleBuild", 1),
+    })
+
+    jobs, errs := newInstance(t, s, object{"folders": array{"job/f/"}}).FetchJobs()
+    if names, want := jobNames(jobs), []string{"ok"}; !reflect.DeepEqual(names, want) {
+        t.Errorf("jobs are %v, want %v", names, want)
+    }
+    if len(errs) != 1 || errs[0].Job != "gone" {
+        t.Errorf("errors are %v, want an error for job gone", errs)
+    }
+}
@@ -0,0 +1,186 @@
+package jenkins
+
+import (
+    "crypto/tls"
+    "crypto/x509"
+    "errors"
+    "fmt"
+    "net/http"
+    "net/url"
+    "os"
+    "strings"
+    "time"
+
+    "github.com/pgavlin/jitdash/pkg/secrets"
+)
+
+// ClientOptions configures the HTTP client used to talk to an instance.
+type ClientOptions struct {
+    Name string // the name of the instance, by which the client's metrics are labeled
+    Workers int // the number of build details fetched from the instance in parallel, or zero for the default
+    Proxy string // the URL of the HTTP proxy to use; if empty, the proxy is taken from the environment
+    CAFile string // the path to a PEM bundle of CA certificates to trust in addition to the system roots
+    CertFile string // the path to a PEM client certificate
+    KeyFile string // the path to the PEM private key for CertFile
+    InsecureSkipVerify bool // true to skip verification of the server's certificate chain and host name
+    Concurrency int // the maximum number of requests in flight, or zero for no limit
+    RateLimit float64 // the maximum number of requests per second, or zero for no limit
+    ConditionalRequests bool // true to make repeated requests conditional on the ETag or Last-Modified of the last response
+    Timeout time.Duration // the time limit for each request, including reading the response body, or zero for no limit
+    Username string // the user name with which to authenticate requests
+    Token secrets.Source // the API token with which to authenticate requests, or nil to make anonymous requests
+    Headers http.Header // additional headers to send with every request, e.g. for gateways in front of the instance
+    Redirects string // the redirect policy: RedirectSameHost (the default), RedirectFollow, or RedirectNone
+}
+
+// NewClient creates an HTTP client with the given options. The client keeps enough idle connections open to serve every
+// concurrent fetch without reconnecting, negotiates HTTP/2 where the server supports it, and requests gzip-compressed
+// responses.
+func NewClient(options ClientOptions) (*http.Client, error) {
+    transport := http.DefaultTransport.(*http.Transport).Clone()
+    transport.MaxIdleConns = 0
+    transport.MaxIdleConnsPerHost = DefaultFetchWorkers
+    if options.Workers > 0 {
+        transport.MaxIdleConnsPerHost = options.Workers
+    }
+    if options.Concurrency > 0 && options.Concurrency < transport.MaxIdleConnsPerHost {
+        transport.MaxIdleConnsPerHost = options.Concurrency
+    }
+    transport.ForceAttemptHTTP2 = true
+    transport.DisableCompression = false
+
+    if options.Proxy != "" {
+        proxyUrl, err := url.Parse(options.Proxy)
+        if err != nil {
+            return nil, errors.New(fmt.Sprintf("invalid proxy %s: %s", options.Proxy, err))
+        }
+        transport.Proxy = http.ProxyURL(proxyUrl)
+    }
+
+    tlsConfig := &tls.Config{InsecureSkipVerify: options.InsecureSkipVerify}
+
+    if options.CAFile != "" {
+        pem, err := os.ReadFile(options.CAFile)
+        if err != nil {
+            return nil, errors.New(fmt.Sprintf("could not read CA bundle: %s", err))
+        }
+
+        roots, err := x509.SystemCertPool()
+        if err != nil {
+            roots = x509.NewCertPool()
+        }
+        if !roots.AppendCertsFromPEM(pem) {
+            return nil, errors.New(fmt.Sprintf("CA bundle %s contains no certificates", options.CAFile))
+        }
+        tlsConfig.RootCAs = roots
+    }
+
+    if options.CertFile != "" || options.KeyFile != "" {
+        cert, err := tls.LoadX509KeyPair(options.CertFile, options.KeyFile)
+        if err != nil {
+            return nil, errors.New(fmt.Sprintf("could not load client certificate: %s", err))
+        }
+        tlsConfig.Certificates = []tls.Certificate{cert}
+    }
+
+    transport.TLSClientConfig = tlsConfig
+
+    var roundTripper http.RoundTripper = &metricsTransport{options.Name, transport}
+    if options.Token != nil {
+        roundTripper = &credentialsTransport{options.Username, options.Token, roundTripper}
+    }
+    if len(options.Headers) != 0 {
+        roundTripper = &headerTransport{options.Headers, roundTripper}
+    }
+    if options.Concurrency > 0 || options.RateLimit > 0 {
+        roundTripper = NewThrottle(options.Concurrency, options.RateLimit).Transport(roundTripper)
+    }
+    if options.ConditionalRequests {
+        roundTripper = NewConditionalCache(options.Name).Transport(roundTripper)
+    }
+    redirects, err := checkRedirect(options.Redirects)
+    if err != nil {
+        return nil, err
+    }
+    return &http.Client{Transport: roundTripper, CheckRedirect: redirects, Timeout: options.Timeout}, nil
+}
+
+// FetchJson fetches the resource at the given URL with the given additional request headers and decodes it as JSON
+// into v. Responses with a status other than 200 OK are treated as errors.
+func FetchJson(client *http.Client, url string, header http.Header, v interface{}) error {
+    req, err := http.NewRequest("GET", url, nil)
+    if err != nil {
+        return err
+    }
+    for k, vs := range header {
+        req.Header[k] = vs
+    }
+
+    r, err := client.Do(req)
+    if err != nil {
+        return err
+    }
+    defer r.Body.Close()
+
+    if r.StatusCode != http.StatusOK {
+        return &StatusError{StatusCode: r.StatusCode, Status: r.Status}
+    }
+
+    // Jenkins answers some unauthenticated requests by redirecting to its login page rather than with a 401 or a 403.
+    if strings.HasPrefix(r.Header.Get("Content-Type"), "text/html") {
+        return &StatusError{StatusCode: http.StatusUnauthorized, Status: r.Status, LoginPage: true}
+    }
+
+    // Objects decoded from cached bodies are copied rather than decoded again.
+    if cb, ok := r.Body.(*cachedBody); ok {
+        if p, ok := v.(*JsonObject); ok {
+            if object := cb.decodedObject(); object != nil {
+                *p = object
+                return nil
+            }
+            if err := DecodeJson(r.Body, p); err != nil {
+                return err
+            }
+            cb.setDecodedObject(*p)
+            return nil
+        }
+    }
+
+    return DecodeJson(r.Body, v)
+}
+
+// A StatusError records a response with an unexpected status.
+type StatusError struct {
+    StatusCode int
+    Status string
+    LoginPage bool // true if the response was an HTML page, which Jenkins serves in place of JSON to anonymous users
+}
+
+func (e *StatusError) Error() string {
+    switch {
+    case e.LoginPage:
+        return "received an HTML page instead of JSON; the instance may require credentials"
+    case e.StatusCode == http.StatusUnauthorized:
+        return e.Status + ": the instance requires valid credentials"
+    case e.StatusCode == http.StatusForbidden:
+        return e.Status + ": the instance's credentials do not grant access"
+    case e.StatusCode == http.StatusNotFound:
+        return e.Status + ": check that the job, folder, or view exists"
+    default:
+        return e.Status
+    }
+}
+
+// headerTransport adds a fixed set of headers to each request.
+type headerTransport struct {
+    header http.Header
+    next http.RoundTripper
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+    req = req.Clone(req.Context())
+    for k, vs := range t.header {
+        req.Header[k] = vs
+    }
+    return t.next.RoundTrip(req)
+}
@@ -0,0 +1,640 @@
+package jenkins
+
+import (
+    "errors"
+    "log/slog"
+    "net/http"
+    "regexp"
+    "sort"
+    "time"
+
+    "github.com/pgavlin/jitdash/pkg/tracing"
+)
+
+type GroupRule struct {
+    Name string
+    Match *regexp.Regexp
+}
+
+// LimitRule overrides the number of builds fetched and rendered for the jobs whose names match a regular expression.
+// Limits of zero are not overridden.
+type LimitRule struct {
+    Match *regexp.Regexp
+    MaxBuilds int
+    MaxHistory int
+}
+
+// AliasRule gives the jobs whose names match a regular expression a display name. The display name is a template that
+// may refer to the match's capture groups (e.g. "$1 nightly"); see regexp.Regexp.Expand.
+type AliasRule struct {
+    Match *regexp.Regexp
+    Template string
+}
+
+type Instance struct {
+    Name string
+    Folders []string // list of folder URLs of the form "/abs/path/to/job/"
+    Views []string // list of view URLs of the form "/abs/path/to/view/"
+    Jobs []string // list of job URLs of the form "/abs/path/to/job/" to fetch directly
+    Exclude []*regexp.Regexp // list of REs for jobs to exclude
+    Groups []GroupRule // list of rules that assign jobs to groups
+    ExpandMatrix bool // true to show each matrix configuration as its own job
+    Recurse bool // true to list the jobs in nested folders, which are named by their paths relative to the listed folder
+    Branches BranchFilter // selects the repositories and branches of organization folders and multibranch projects
+    Client *http.Client // the client used to talk to the instance
+    Backend Backend // the backend for non-Jenkins instances, or nil for Jenkins instances
+    RefreshInterval time.Duration // in serve mode, the interval between refreshes, or zero to use the default
+    RefreshJitter time.Duration // in serve mode, the maximum random delay added to each interval between refreshes
+    Tier string // the name of the instance's refresh tier, which gives its refresh interval unless it sets its own
+    Workers int // the number of build details to fetch from the instance in parallel, or zero for no per-instance limit
+    FlagDuplicates bool // true to show jobs listed in multiple folders or views once per listing and flag the repeats
+    MaxBuilds int // the number of most recent builds to fetch per job, or zero to use the configuration's limit
+    MaxHistory int // the number of most recent builds to render per job, or zero to use the configuration's limit
+    Limits []LimitRule // list of rules that override the instance's limits for particular jobs
+    Window time.Duration // if non-zero, only builds that started within this long ago are shown
+    Aliases []AliasRule // list of rules that give jobs display names
+    ShowAgents bool // true to fetch and show the status of the instance's agents
+    Scale *Scale // the instance's sparkline scale, or nil to use the configuration's scale
+    Scales []ScaleRule // list of rules that override the instance's scale for particular jobs
+    KnownFailureRules []KnownFailure // list of annotations for known test failures
+    Parameters []string // the names of the build parameters to show, e.g. "ENV"
+    ConsoleTail int // the number of bytes at the end of the console output of failed builds to capture, or zero
+    Classifications []ClassificationRule // list of rules that assign failed builds to categories
+    UrlRewrites []UrlRewrite // list of rules that map fetched URLs to the URLs shown to users
+    DeepLinks []DeepLinkRule // list of rules that link builds to their test reports or console output
+    Classes ClassMap // the kinds of the classes that the instance's plugins introduce, in addition to DefaultClasses
+    Columns []Column // the extra columns of the instance's job tables
+    Artifacts []string // glob patterns that select the artifacts of each job's last successful build to link to
+}
+
+// Api returns a client for the instance's Jenkins API.
+func (i *Instance) Api() *Client {
+    return &Client{HTTP: i.Client, Classes: i.Classes}
+}
+
+// Logger returns a logger that annotates its records with the instance name.
+func (i *Instance) Logger() *slog.Logger {
+    return slog.With("instance", i.Name)
+}
+
+// IsExcluded returns true if the given job matches any of the instance's exclusions.
+func (i *Instance) IsExcluded(name string) bool {
+    for _, ex := range i.Exclude {
+        if ex.MatchString(name) {
+            i.Logger().Debug("excluded job", "job", name)
+            return true
+        }
+    }
+    return false
+}
+
+// JobLimits returns the number of builds to fetch and to render for the given job. The instance's limits override the
+// given defaults, and the first limit rule that matches the job overrides the instance's limits. The number of builds
+// to render never exceeds the number to fetch.
+func (i *Instance) JobLimits(name string, maxBuilds, maxHistory int) (int, int) {
+    if i.MaxBuilds != 0 {
+        maxBuilds = i.MaxBuilds
+    }
+    if i.MaxHistory != 0 {
+        maxHistory = i.MaxHistory
+    }
+    for _, l := range i.Limits {
+        if l.Match.MatchString(name) {
+            if l.MaxBuilds != 0 {
+                maxBuilds = l.MaxBuilds
+            }
+            if l.MaxHistory != 0 {
+                maxHistory = l.MaxHistory
+            }
+            break
+        }
+    }
+
+    if maxHistory > maxBuilds {
+        maxHistory = maxBuilds
+    }
+    return maxBuilds, maxHistory
+}
+
+// limitBuilds returns the most recent of the given builds, which are ordered oldest first, that the named job's history
+// includes. Without a window, the job's build limit applies; maxBuilds is the default limit (see JobLimits). With a
+// window, every build that was listed with a start time within the window is kept, however many there are. If some
+// builds were listed without start times, the limit applies instead, and the window is applied once their details have
+// been fetched.
+func (i *Instance) limitBuilds(name string, builds []*Build, maxBuilds int) []*Build {
+    if i.Window != 0 && listedTimestamps(builds) {
+        return i.windowBuilds(builds)
+    }
+    if limit, _ := i.JobLimits(name, maxBuilds, 0); len(builds) > limit {
+        return builds[len(builds) - limit:]
+    }
+    return builds
+}
+
+// listedTimestamps returns true if the start times of all of the given builds are known.
+func listedTimestamps(builds []*Build) bool {
+    for _, b := range builds {
+        if b.Timestamp.IsZero() {
+            return false
+        }
+    }
+    return true
+}
+
+// windowBuilds returns the given builds that started within the instance's window. Builds whose start time is unknown
+// are kept.
+func (i *Instance) windowBuilds(builds []*Build) []*Build {
+    if i.Window == 0 {
+        return builds
+    }
+
+    cutoff := time.Now().Add(-i.Window)
+    var windowed []*Build
+    for _, b := range builds {
+        if b.Timestamp.IsZero() || !b.Timestamp.Before(cutoff) {
+            windowed = append(windowed, b)
+        }
+    }
+    return windowed
+}
+
+// processBuilds processes the builds listed in the details of the job at the given URL.
+func (i *Instance) processBuilds(jobUrl string, details JsonObject) ([]*Build, bool) {
+    buildObjects, ok := details.GetArray("builds")
+    if !ok {
+        return nil, false
+    }
+
+    var builds []*Build
+    for _, b := range buildObjects {
+        build, ok := i.ProcessBuildObject(jobUrl, b)
+        if ok {
+            builds = append(builds, build)
+        }
+    }
+
+    sort.Sort(BuildSorter(builds))
+    return builds, true
+}
+
+// processMatrixConfigurations returns one job per active configuration of the given matrix project.
+func (i *Instance) processMatrixConfigurations(name, url string, details JsonObject) ([]*Job, []*FetchError) {
+    configObjects, ok := details.GetArray("activeConfigurations")
+    if !ok {
+        return nil, nil
+    }
+
+    var jobs []*Job
+    var errs []*FetchError
+    for _, c := range configObjects {
+        config, ok := AsJsonObject(c)
+        if !ok {
+            continue
+        }
+
+        configName, ok := config.GetString("name")
+        if !ok {
+            continue
+        }
+        configName = name + "/" + configName
+        if i.IsExcluded(configName) {
+            continue
+        }
+
+        configUrl, ok := config.GetString("url")
+        if !ok {
+            continue
+        }
+        configUrl = ResolveUrl(url, configUrl)
+
+        configDetails, err := i.Api().Object(apiUrl(configUrl, "api/json?tree=" + jobDetailsTree))
+        if err != nil {
+            errs = append(errs, i.NewFetchError(configName, configUrl, err))
+            continue
+        }
+
+        i.Logger().Debug("processing builds for configuration", "job", configName)
+
+        builds, ok := i.processBuilds(configUrl, configDetails)
+        if !ok {
+            continue
+        }
+        jobs = append(jobs, &Job{Name: configName, Url: configUrl, Builds: builds})
+    }
+
+    return jobs, errs
+}
+
+// ProcessJobObject processes a job listed in the folder or view at the given URL. Most jobs produce a single result,
+// but matrix projects produce one job per configuration if the instance is configured to expand them. Objects that are
+// not jobs or that are excluded produce neither jobs nor errors.
+func (i *Instance) ProcessJobObject(listUrl string, jobIf interface{}) ([]*Job, []*FetchError) {
+    return i.processJobObject(listUrl, "", jobIf)
+}
+
+// processJobObject processes a job listed in a folder or view. The job's name is prefixed with the given path of the
+// nested folder it was listed in, if any, and exclusions are matched against the prefixed name.
+func (i *Instance) processJobObject(listUrl, prefix string, jobIf interface{}) ([]*Job, []*FetchError) {
+    job, ok := AsJsonObject(jobIf)
+    if !ok {
+        return nil, nil
+    }
+
+    class, ok := job.GetString("_class")
+    if !ok || !i.Classes.IsJob(class) {
+        return nil, nil
+    }
+
+    name, ok := job.GetString("name")
+    if !ok {
+        return nil, nil
+    }
+    name = prefix + name
+
+    if i.IsExcluded(name) {
+        return nil, nil
+    }
+
+    url, ok := job.GetString("url")
+    if !ok {
+        return nil, nil
+    }
+    url = ResolveUrl(listUrl, url)
+
+    details, err := i.Api().Object(apiUrl(url, "api/json?tree=" + jobDetailsTree))
+    if err != nil {
+        return nil, []*FetchError{i.NewFetchError(name, url, err)}
+    }
+
+    return i.processJobDetails(class, name, url, details)
+}
+
+var unsupportedJobError = errors.New("unsupported job type")
+var missingNameError = errors.New("missing name")
+
+// FetchJob fetches the job at the given URL directly rather than through a folder or view. Exclusions do not apply to
+// jobs that are fetched directly.
+func (i *Instance) FetchJob(url string) ([]*Job, []*FetchError) {
+    details, err := i.Api().Object(apiUrl(url, "api/json?tree=" + jobDetailsTree))
+    if err != nil {
+        return nil, []*FetchError{i.NewFetchError("", url, err)}
+    }
+
+    class, ok := details.GetString("_class")
+    if !ok || !i.Classes.IsJob(class) {
+        return nil, []*FetchError{i.NewFetchError("", url, unsupportedJobError)}
+    }
+
+    name, ok := details.GetString("name")
+    if !ok {
+        return nil, []*FetchError{i.NewFetchError("", url, missingNameError)}
+    }
+
+    return i.processJobDetails(class, name, url, details)
+}
+
+// processJobDetails processes the details of a job of the given class.
+func (i *Instance) processJobDetails(class, name, url string, details JsonObject) ([]*Job, []*FetchError) {
+    if i.Classes.Kind(class) == ClassMatrix && i.ExpandMatrix {
+        return i.processMatrixConfigurations(name, url, details)
+    }
+
+    i.Logger().Debug("processing builds for job", "job", name)
+
+    builds, ok := i.processBuilds(url, details)
+    if !ok {
+        return nil, []*FetchError{i.NewFetchError(name, url, missingBuildsError)}
+    }
+    upstream, downstream := projectUrls(url, details, "upstreamProjects"), projectUrls(url, details, "downstreamProjects")
+    return []*Job{&Job{Name: name, Url: url, Builds: builds, Upstream: upstream, Downstream: downstream}}, nil
+}
+
+// GroupFor returns the name of the group the given job belongs to. Grouping rules are checked in order and take
+// precedence over the group implied by the job's source (e.g. the view it was listed in).
+func (i *Instance) GroupFor(name, sourceGroup string) string {
+    for _, g := range i.Groups {
+        if g.Match.MatchString(name) {
+            return g.Name
+        }
+    }
+    return sourceGroup
+}
+
+// DisplayNameFor returns the display name given to the named job by the first matching alias rule, or the empty string
+// if no rule matches.
+func (i *Instance) DisplayNameFor(name string) string {
+    for _, a := range i.Aliases {
+        if match := a.Match.FindStringSubmatchIndex(name); match != nil {
+            return string(a.Match.ExpandString(nil, a.Template, name, match))
+        }
+    }
+    return ""
+}
+
+// GroupNames returns the names of the non-empty groups among the given jobs in display order: views first, then
+// grouping rules. Jobs that belong to no group are collected under the empty name, which always comes last.
+func (i *Instance) GroupNames(jobs []*Job) []string {
+    present := make(map[string]bool)
+    for _, j := range jobs {
+        present[j.Group] = true
+    }
+
+    var names []string
+    add := func(name string) {
+        if present[name] {
+            delete(present, name)
+            names = append(names, name)
+        }
+    }
+
+    for _, j := range jobs {
+        if !i.isRuleGroup(j.Group) && j.Group != "" {
+            add(j.Group)
+        }
+    }
+    for _, g := range i.Groups {
+        add(g.Name)
+    }
+    add("")
+
+    return names
+}
+
+func (i *Instance) isRuleGroup(name string) bool {
+    for _, g := range i.Groups {
+        if g.Name == name {
+            return true
+        }
+    }
+    return false
+}
+
+// jobDetailsTree selects the fields of a job's details that are used, including the start time of each listed build so
+// that windows can be applied before the job's history is truncated.
+const jobDetailsTree = "_class,name,url,builds[_class,number,url,timestamp],activeConfigurations[name,url]," +
+    "upstreamProjects[name,url],downstreamProjects[name,url]"
+
+var missingBuildsError = errors.New("missing builds")
+var missingJobsError = errors.New("missing jobs")
+
+// jobListTree selects the fields of folders and views that are used to list their jobs, including the number of each
+// job's last build so that unchanged jobs can be reused.
+const jobListTree = "name,jobs[_class,name,url,lastBuild[number]]"
+
+// fetchJobList fetches the job list at the given folder or view URL. If the list is a view, the view's name is
+// returned along with its jobs.
+func (i *Instance) fetchJobList(listUrl string) (string, []interface{}, *FetchError) {
+    list, err := i.Api().Object(listUrl)
+    if err != nil {
+        return "", nil, i.NewFetchError("", listUrl, err)
+    }
+
+    jobObjects, ok := list.GetArray("jobs")
+    if !ok {
+        return "", nil, i.NewFetchError("", listUrl, missingJobsError)
+    }
+
+    name, _ := list.GetString("name")
+    return name, jobObjects, nil
+}
+
+// FetchJobs fetches the instance's jobs. Any errors encountered along the way are returned alongside the jobs that
+// were successfully fetched.
+func (i *Instance) FetchJobs() ([]*Job, []*FetchError) {
+    return i.fetchJobs(nil, nil)
+}
+
+// fetchJobs fetches the instance's jobs, tracing the fetches of Jenkins folders, views, and jobs as children of the
+// given span. If previous is non-nil, Jenkins jobs that have not built since they were previously fetched are reused
+// rather than fetched again.
+func (i *Instance) fetchJobs(span *tracing.Span, previous []*Job) ([]*Job, []*FetchError) {
+    i.Logger().Info("fetching jobs")
+
+    if i.Backend == nil {
+        return i.fetchJenkinsJobs(span, previous)
+    }
+
+    jobs, errs := i.Backend.FetchJobs(i)
+    for _, job := range jobs {
+        job.Group = i.GroupFor(job.Name, job.Group)
+        job.DisplayName = i.DisplayNameFor(job.Name)
+    }
+    return jobs, errs
+}
+
+// FetchDetails fetches the details of the given build of one of the instance's jobs, along with the end of its console
+// output if it failed and the instance captures console output. Any error is also recorded in the build.
+func (i *Instance) FetchDetails(b *Build) error {
+    var err error
+    if i.Backend == nil {
+        err = i.Api().BuildDetails(b)
+        b.Artifacts = i.ShownArtifacts(b.Artifacts)
+        if err == nil && i.ConsoleTail > 0 && b.Complete && b.Result == ResultFailure {
+            if b.ConsoleTail, err = i.Api().ConsoleTail(b.Url, i.ConsoleTail); err != nil {
+                i.Logger().Debug("error fetching console output", "build", b.Url, "err", err)
+                err = nil
+            }
+        }
+    } else {
+        err = i.Backend.FetchDetails(i, b)
+    }
+    b.Err = err
+    return err
+}
+
+// fetchJenkinsJobs fetches the jobs in the instance's folders and views, followed by the instance's individual jobs,
+// and then checks which of the jobs are queued. Listed jobs that are unchanged since the given previous fetch are
+// reused.
+func (i *Instance) fetchJenkinsJobs(span *tracing.Span, previous []*Job) ([]*Job, []*FetchError) {
+    l := &jobLister{instance: i, seen: make(map[string][]*Job), previous: make(map[string]*Job)}
+    for _, j := range previous {
+        if !j.Duplicate {
+            l.previous[j.Url] = j
+        }
+    }
+    for _, folderUrl := range i.Folders {
+        i.Logger().Info("fetching folder", "url", folderUrl)
+
+        l.span = span.Start("folder", "url", folderUrl)
+        _, jobObjects, err := i.fetchJobList(folderUrl + "?tree=" + jobListTree)
+        if err != nil {
+            l.errs = append(l.errs, err)
+            l.span.SetError(err)
+        } else {
+            l.add(folderUrl, "", jobObjects, "")
+        }
+        l.span.End()
+    }
+
+    for _, viewUrl := range i.Views {
+        i.Logger().Info("fetching view", "url", viewUrl)
+
+        l.span = span.Start("view", "url", viewUrl)
+        viewName, jobObjects, err := i.fetchJobList(viewUrl + "?tree=" + jobListTree)
+        if err != nil {
+            l.errs = append(l.errs, err)
+            l.span.SetError(err)
+        } else {
+            l.add(viewUrl, "", jobObjects, viewName)
+        }
+        l.span.End()
+    }
+
+    l.span = span
+    for _, jobUrl := range i.Jobs {
+        i.Logger().Info("fetching job", "url", jobUrl)
+
+        l.addJob(jobUrl, "", func() ([]*Job, []*FetchError) {
+            return i.FetchJob(jobUrl)
+        })
+    }
+
+    if l.reused != 0 {
+        i.Logger().Info("reused unchanged jobs", "jobs", l.reused)
+    }
+
+    queueSpan := span.Start("queue")
+    errs := i.fetchQueue(l.jobs)
+    if len(errs) != 0 {
+        queueSpan.SetError(errs[0])
+    }
+    queueSpan.End()
+
+    l.errs = append(l.errs, errs...)
+    return l.jobs, l.errs
+}
+
+// jobLister accumulates the jobs listed in an instance's folders and views. Jobs that are listed more than once are
+// only fetched once and are then merged or flagged according to the instance's configuration.
+type jobLister struct {
+    instance *Instance
+    span *tracing.Span // the span of the folder or view being listed
+    seen map[string][]*Job // the jobs produced by each listed job URL
+    previous map[string]*Job // the jobs of the previous fetch, by URL
+    reused int // the number of previous jobs that were reused
+    jobs []*Job
+    errs []*FetchError
+}
+
+// unchanged returns a copy of the previously fetched job at the given URL if the job's last build, as listed by the
+// given job object, is the last build of the previous job and that and all of the previous job's other builds were
+// complete and fetched without errors. Otherwise, unchanged returns nil.
+func (l *jobLister) unchanged(url string, jobObject JsonObject) *Job {
+    p, ok := l.previous[url]
+    if !ok || len(p.Builds) == 0 {
+        return nil
+    }
+    lastBuild, ok := jobObject.GetObject("lastBuild")
+    if !ok {
+        return nil
+    }
+    number, ok := lastBuild.GetInt64("number")
+    if !ok || number != p.Builds[len(p.Builds) - 1].Id {
+        return nil
+    }
+    for _, b := range p.Builds {
+        if !b.Complete || b.Err != nil {
+            return nil
+        }
+    }
+
+    // The previous job may still be in use, so the copy does not share its builds.
+    l.reused++
+    job := *p
+    job.Builds = append([]*Build(nil), p.Builds...)
+    job.Queued, job.QueuedWhy = false, ""
+    return &job
+}
+
+// add adds the jobs listed in the folder or view at the given URL. prefix is the path of the folder relative to the
+// configured folder that it is nested in, if any. If the instance recurses into nested folders, the jobs of those
+// folders that are not excluded are added as well.
+func (l *jobLister) add(listUrl, prefix string, jobObjects []interface{}, sourceGroup string) {
+    for _, j := range jobObjects {
+        url := ""
+        job, isObject := AsJsonObject(j)
+        if isObject {
+            if u, ok := job.GetString("url"); ok {
+                url = ResolveUrl(listUrl, u)
+            }
+
+            class, _ := job.GetString("_class")
+            if name, ok := job.GetString("name"); ok && url != "" {
+                switch kind := l.instance.Classes.Kind(class); {
+                case l.instance.Recurse && kind == ClassFolder:
+                    l.addFolder(url, prefix + name, sourceGroup)
+                    continue
+                case kind == ClassOrganizationFolder:
+                    l.addOrgFolder(url, prefix + name, sourceGroup)
+                    continue
+                case kind == ClassMultibranch:
+                    if l.instance.Branches.ShowRepo(name) {
+                        l.addBranches(url, prefix + name, sourceGroup)
+                    }
+                    continue
+                }
+            }
+        }
+
+        l.addJob(url, sourceGroup, func() ([]*Job, []*FetchError) {
+            if isObject {
+                if unchanged := l.unchanged(url, job); unchanged != nil {
+                    return []*Job{unchanged}, nil
+                }
+            }
+            return l.instance.processJobObject(listUrl, prefix, j)
+        })
+    }
+}
+
+// addFolder adds the jobs in the nested folder with the given URL and path unless the folder is excluded.
+func (l *jobLister) addFolder(url, path, sourceGroup string) {
+    i := l.instance
+    if i.IsExcluded(path) {
+        return
+    }
+
+    i.Logger().Info("fetching folder", "url", url)
+    listUrl := apiUrl(url, "api/json?tree=" + jobListTree)
+    _, jobObjects, err := i.fetchJobList(listUrl)
+    if err != nil {
+        l.errs = append(l.errs, err)
+        return
+    }
+    l.add(listUrl, path + "/", jobObjects, sourceGroup)
+}
+
+// addJob adds the jobs produced by the job listed at the given URL. The jobs are only processed if the URL has not
+// been listed before.
+func (l *jobLister) addJob(url, sourceGroup string, process func() ([]*Job, []*FetchError)) {
+    i := l.instance
+    if previous, ok := l.seen[url]; ok && url != "" {
+        for _, p := range previous {
+            i.Logger().Debug("duplicate job", "job", p.Name, "url", url)
+            if i.FlagDuplicates {
+                duplicate := *p
+                duplicate.Group = i.GroupFor(p.Name, sourceGroup)
+                duplicate.Duplicate = true
+                l.jobs = append(l.jobs, &duplicate)
+            } else if p.Group == "" {
+                p.Group = i.GroupFor(p.Name, sourceGroup)
+            }
+        }
+        return
+    }
+
+    span := l.span.Start("job", "url", url)
+    processed, jobErrs := process()
+    if len(jobErrs) != 0 {
+        span.SetError(jobErrs[0])
+    }
+    span.End()
+
+    l.errs = append(l.errs, jobErrs...)
+    for _, job := range processed {
+        job.Group = i.GroupFor(job.Name, sourceGroup)
+        job.DisplayName = i.DisplayNameFor(job.Name)
+        l.jobs = append(l.jobs, job)
+    }
+    l.seen[url] = processed
+}
@@ -0,0 +1,57 @@
+package jenkins
+
+import (
+    "errors"
+    "fmt"
+    "os"
+    "regexp"
+)
+
+// variableRE matches a reference to an environment variable, ${NAME}, or an escaped reference, $${NAME}.
+var variableRE = regexp.MustCompile(`\$?\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// interpolate replaces references of the form ${NAME} in the string values of the given JSON value with the values of
+// the named environment variables, so that secrets can be injected into a config without being written into it.
+// References to unset variables are errors. A reference can be escaped by doubling its dollar sign, as in $${NAME}.
+func interpolate(v interface{}) (interface{}, error) {
+    switch v := v.(type) {
+    case string:
+        var err error
+        result := variableRE.ReplaceAllStringFunc(v, func(ref string) string {
+            if ref[1] == '$' {
+                return ref[1:]
+            }
+
+            name := ref[2:len(ref) - 1]
+            value, ok := os.LookupEnv(name)
+            if !ok && err == nil {
+                err = errors.New(fmt.Sprintf("environment variable %s is not set", name))
+            }
+            return value
+        })
+        return result, err
+    case []interface{}:
+        for i, e := range v {
+            ie, err := interpolate(e)
+            if err != nil {
+                return nil, err
+            }
+            v[i] = ie
+        }
+        return v, nil
+    case map[string]interface{}:
+        for k, e := range v {
+            ie, err := interpolate(e)
+            if err != nil {
+                return nil, err
+            }
+            v[k] = ie
+        }
+        return v, nil
+    case JsonObject:
+        _, err := interpolate(map[string]interface{}(v))
+        return v, err
+    default:
+        return v, nil
+    }
+}
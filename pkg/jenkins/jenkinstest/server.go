@@ -0,0 +1,158 @@
+// Package jenkinstest provides a fake Jenkins server for exercising the jenkins package without a live instance. The
+// server answers requests from fixtures: canned responses keyed by URL path, which may be added in code or loaded from
+// a directory of recorded responses.
+//
+// A fixture directory mirrors the URL paths it serves, with an extension that gives each response's content type:
+//
+//     fixtures/job/f/api/json.json            served at /job/f/api/json as application/json
+//     fixtures/job/f/job/a/1/consoleText.txt  served at /job/f/job/a/1/consoleText as text/plain
+//
+// Query strings are ignored when matching requests to fixtures, and occurrences of ${JENKINS_URL} in fixtures are
+// replaced with the server's URL followed by a slash, so that fixtures can contain the absolute URLs Jenkins reports.
+// The responses that jenkins.RecordingTransport records for a host use the same layout, so a host's directory of a
+// recording can be loaded as fixtures.
+package jenkinstest
+
+import (
+    "bytes"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io/fs"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "path/filepath"
+    "strings"
+    "sync"
+
+    "github.com/pgavlin/jitdash/pkg/jenkins"
+)
+
+// A Fixture is a canned response.
+type Fixture struct {
+    Status int // the response's status, or zero for 200 OK
+    ContentType string
+    Body []byte
+}
+
+// contentTypes maps the extensions of fixture files to the content types of their responses.
+var contentTypes = map[string]string{
+    ".json": "application/json",
+    ".txt": "text/plain",
+    ".html": "text/html",
+}
+
+// A Server is a fake Jenkins server.
+type Server struct {
+    *httptest.Server
+
+    m sync.Mutex
+    fixtures map[string]Fixture
+    requests map[string]int
+}
+
+// NewServer starts a server without fixtures. Requests for paths without fixtures are answered with 404 Not Found.
+// The caller should call Close when finished.
+func NewServer() *Server {
+    s := &Server{fixtures: map[string]Fixture{}, requests: map[string]int{}}
+    s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+    return s
+}
+
+// Handle serves the given fixture at the given path.
+func (s *Server) Handle(path string, f Fixture) {
+    s.m.Lock()
+    defer s.m.Unlock()
+    s.fixtures[path] = f
+}
+
+// HandleJson serves the JSON encoding of the given value at the given path.
+func (s *Server) HandleJson(path string, v interface{}) error {
+    body, err := json.Marshal(v)
+    if err != nil {
+        return err
+    }
+    s.Handle(path, Fixture{ContentType: contentTypes[".json"], Body: body})
+    return nil
+}
+
+// HandleStatus answers requests for the given path with the given status and an empty body.
+func (s *Server) HandleStatus(path string, status int) {
+    s.Handle(path, Fixture{Status: status, ContentType: contentTypes[".txt"]})
+}
+
+// LoadDir adds the fixtures in the given directory and its subdirectories. Files whose extensions do not give a content
+// type are an error.
+func (s *Server) LoadDir(dir string) error {
+    return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+        if err != nil || d.IsDir() {
+            return err
+        }
+
+        ext := filepath.Ext(path)
+        contentType, ok := contentTypes[ext]
+        if !ok {
+            return errors.New(fmt.Sprintf("fixture %s has an unknown extension", path))
+        }
+        body, err := os.ReadFile(path)
+        if err != nil {
+            return err
+        }
+
+        rel, err := filepath.Rel(dir, strings.TrimSuffix(path, ext))
+        if err != nil {
+            return err
+        }
+        s.Handle("/" + filepath.ToSlash(rel), Fixture{ContentType: contentType, Body: body})
+        return nil
+    })
+}
+
+// Requests returns the number of requests the server has received for the given path.
+func (s *Server) Requests(path string) int {
+    s.m.Lock()
+    defer s.m.Unlock()
+    return s.requests[path]
+}
+
+// Client returns a client for the server's API.
+func (s *Server) Client() *jenkins.Client {
+    return &jenkins.Client{HTTP: s.Server.Client()}
+}
+
+// Instance returns a Jenkins instance with the given name that is configured by the given instance object, as in a
+// configuration's "instances" section, and talks to the server. Folder, view, and job URLs in the object are relative
+// to the server's URL, e.g. {"folders": ["job/f/"]}.
+func (s *Server) Instance(name string, config jenkins.JsonObject) (*jenkins.Instance, error) {
+    instanceObject := jenkins.JsonObject{"url": s.URL + "/"}
+    for k, v := range config {
+        instanceObject[k] = v
+    }
+    i, err := jenkins.ProcessInstanceObject(map[string]interface{}(instanceObject), name)
+    if err != nil {
+        return nil, err
+    }
+    i.Client = s.Server.Client()
+    return i, nil
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+    s.m.Lock()
+    s.requests[r.URL.Path]++
+    f, ok := s.fixtures[r.URL.Path]
+    s.m.Unlock()
+
+    if !ok {
+        http.NotFound(w, r)
+        return
+    }
+
+    status := f.Status
+    if status == 0 {
+        status = http.StatusOK
+    }
+    w.Header().Set("Content-Type", f.ContentType)
+    w.WriteHeader(status)
+    w.Write(bytes.ReplaceAll(f.Body, []byte(jenkins.RecordedUrl), []byte(s.URL + "/")))
+}
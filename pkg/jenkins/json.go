@@ -0,0 +1,110 @@
+// Package jenkins fetches job and build information from Jenkins instances and aggregates it into a model that can
+// be sorted, filtered, and rendered.
+package jenkins
+
+import (
+    "encoding/json"
+    "io"
+)
+
+type JsonObject map[string]interface{}
+
+// DecodeJson decodes the JSON value read from r into v. Numbers are decoded as json.Number rather than float64 so that
+// large build numbers and timestamps keep their precision.
+func DecodeJson(r io.Reader, v interface{}) error {
+    decoder := json.NewDecoder(r)
+    decoder.UseNumber()
+    return decoder.Decode(v)
+}
+
+func AsJsonObject(i interface{}) (JsonObject, bool) {
+    if o, ok := i.(map[string]interface{}); ok {
+        return JsonObject(o), true
+    }
+    return nil, false
+}
+
+func (o JsonObject) GetString(key string) (string, bool) {
+    val, ok := o[key]
+    if !ok {
+        return "", false
+    }
+
+    strVal, ok := val.(string)
+    return strVal, ok
+}
+
+func AsInt64(i interface{}) (int64, bool) {
+    switch v := i.(type) {
+    case json.Number:
+        i64, err := v.Int64()
+        if err != nil {
+            return 0, false
+        }
+        return i64, true
+
+    case float64:
+        return int64(v), true
+    }
+
+    return 0, false
+}
+
+func (o JsonObject) GetInt64(key string) (int64, bool) {
+    val, ok := o[key]
+    if !ok {
+        return 0, false
+    }
+    return AsInt64(val)
+}
+
+func (o JsonObject) GetFloat64(key string) (float64, bool) {
+    val, ok := o[key]
+    if !ok {
+        return 0, false
+    }
+
+    switch v := val.(type) {
+    case json.Number:
+        f64, err := v.Float64()
+        if err != nil {
+            return 0, false
+        }
+        return f64, true
+
+    case float64:
+        return v, true
+    }
+
+    return 0, false
+}
+
+func (o JsonObject) GetBool(key string) (bool, bool) {
+    val, ok := o[key]
+    if !ok {
+        return false, false
+    }
+
+    bVal, ok := val.(bool)
+    return bVal, ok
+}
+
+func (o JsonObject) GetObject(key string) (JsonObject, bool) {
+    val, ok := o[key]
+    if !ok {
+        return nil, false
+    }
+
+    oVal, ok := AsJsonObject(val)
+    return oVal, ok
+}
+
+func (o JsonObject) GetArray(key string) ([]interface{}, bool) {
+    val, ok := o[key]
+    if !ok {
+        return nil, false
+    }
+
+    aVal, ok := val.([]interface{})
+    return aVal, ok
+}
@@ -0,0 +1,43 @@
+package jenkins
+
+import (
+    "regexp"
+)
+
+// KnownFailure annotates the failures of the tests whose names match Test in the jobs whose names match Job as a
+// known issue.
+type KnownFailure struct {
+    Job *regexp.Regexp
+    Test *regexp.Regexp
+    Reason string
+    Ticket string // the URL of the issue that tracks the failure, if any
+}
+
+// KnownFailures returns the known failures that account for every failed test of the given build of the named job. If
+// any failed test is not a known failure, or if the build's failed tests are unknown, KnownFailures returns nil.
+func (i *Instance) KnownFailures(job string, b *Build) []*KnownFailure {
+    if !b.Failed() || len(b.FailedTests) == 0 {
+        return nil
+    }
+
+    var known []*KnownFailure
+    seen := make(map[*KnownFailure]bool)
+    for _, t := range b.FailedTests {
+        var match *KnownFailure
+        for n := range i.KnownFailureRules {
+            k := &i.KnownFailureRules[n]
+            if k.Job.MatchString(job) && k.Test.MatchString(t) {
+                match = k
+                break
+            }
+        }
+        if match == nil {
+            return nil
+        }
+        if !seen[match] {
+            seen[match] = true
+            known = append(known, match)
+        }
+    }
+    return known
+}
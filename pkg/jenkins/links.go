@@ -0,0 +1,35 @@
+package jenkins
+
+import (
+    "regexp"
+)
+
+// DeepLinkRule links the builds of the jobs whose names match a regular expression to the page most useful for triage
+// rather than to the build itself.
+type DeepLinkRule struct {
+    Match *regexp.Regexp
+    TestReport bool // true to link builds with test failures to their test reports
+    Console bool // true to link builds that failed without test failures, e.g. due to infrastructure, to their console output
+}
+
+// BuildLink returns the URL to which the given build of the named job links. The first deep link rule that matches the
+// job applies; builds of jobs that match no rule, of non-Jenkins instances, and builds that the rule does not cover
+// link to the build itself.
+func (i *Instance) BuildLink(job string, b *Build) string {
+    if i.Backend != nil || !b.Complete || b.Err != nil {
+        return b.Url
+    }
+    for _, r := range i.DeepLinks {
+        if !r.Match.MatchString(job) {
+            continue
+        }
+        if r.TestReport && b.Failures > 0 {
+            return apiUrl(b.Url, "testReport/")
+        }
+        if r.Console && b.Failures < 0 {
+            return apiUrl(b.Url, "console")
+        }
+        return b.Url
+    }
+    return b.Url
+}
@@ -0,0 +1,88 @@
+package jenkins
+
+import (
+    "net/http"
+    "strconv"
+    "time"
+
+    "github.com/pgavlin/jitdash/pkg/metrics"
+)
+
+var (
+    fetchDuration = metrics.NewSummaryVec("jitdash_fetch_duration_seconds",
+        "The time taken to fetch an instance's jobs and the details of their builds.", "instance")
+    httpRequests = metrics.NewCounterVec("jitdash_http_requests_total",
+        "The number of HTTP requests made to an instance, by response status code.", "instance", "code")
+    httpErrors = metrics.NewCounterVec("jitdash_http_errors_total",
+        "The number of HTTP requests made to an instance that failed or returned an error status.", "instance")
+    cacheRequests = metrics.NewCounterVec("jitdash_conditional_cache_requests_total",
+        "The number of conditional requests made to an instance, by whether the cached response was reused.", "instance", "result")
+    fetchQueueDepth = metrics.NewGaugeVec("jitdash_fetch_queue_depth",
+        "The number of builds whose details are waiting for a fetch worker.")
+    fetchWorkersBusy = metrics.NewGaugeVec("jitdash_fetch_workers_busy",
+        "The number of fetch workers that are fetching build details.")
+)
+
+// metricsTransport counts the requests made to an instance and their outcomes.
+type metricsTransport struct {
+    instance string
+    base http.RoundTripper
+}
+
+func (mt *metricsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+    resp, err := mt.base.RoundTrip(req)
+    if err != nil {
+        httpRequests.With(mt.instance, "error").Inc()
+        httpErrors.With(mt.instance).Inc()
+        return nil, err
+    }
+
+    httpRequests.With(mt.instance, strconv.Itoa(resp.StatusCode)).Inc()
+    if resp.StatusCode >= 400 {
+        httpErrors.With(mt.instance).Inc()
+    }
+    return resp, nil
+}
+
+// JobSamples returns samples that describe the state of the given instances' jobs for publishing after each fetch:
+// whether each job is failing and the failures, duration, and start time of its most recent completed build, along
+// with the number of fetch errors per instance. Duplicate listings of a job are omitted.
+func JobSamples(instances []*InstanceJobs) []metrics.Sample {
+    var samples []metrics.Sample
+    add := func(name string, labels []metrics.Label, value float64) {
+        samples = append(samples, metrics.Sample{Name: name, Labels: labels, Value: value})
+    }
+
+    for _, ij := range instances {
+        instanceLabel := metrics.Label{Name: "instance", Value: ij.Instance.Name}
+        add("jitdash_instance_fetch_errors", []metrics.Label{instanceLabel}, float64(len(ij.Errors)))
+
+        for _, j := range ij.Jobs {
+            if j.Duplicate {
+                continue
+            }
+            labels := []metrics.Label{instanceLabel, {Name: "job", Value: j.Name}}
+
+            failing := 0.0
+            if j.Failing() {
+                failing = 1
+            }
+            add("jitdash_job_failing", labels, failing)
+
+            last := j.LastCompletedBuild()
+            if last == nil {
+                continue
+            }
+            if last.Failures >= 0 {
+                add("jitdash_job_last_build_failures", labels, float64(last.Failures))
+            }
+            if last.Duration != 0 {
+                add("jitdash_job_last_build_duration_seconds", labels, last.Duration.Seconds())
+            }
+            if !last.Timestamp.IsZero() {
+                add("jitdash_job_last_build_timestamp_seconds", labels, float64(last.Timestamp.UnixNano()) / float64(time.Second))
+            }
+        }
+    }
+    return samples
+}
@@ -0,0 +1,319 @@
+package jenkins
+
+import (
+    "sort"
+    "time"
+)
+
+type Build struct {
+    Id int64
+    Url string
+    Timestamp time.Time
+    Duration time.Duration // how long the build took, or zero if it is still running or its duration is unknown
+    EstimatedDuration time.Duration // how long the build is expected to take, or zero if unknown
+    Failures int64
+    Tests int64 // the number of tests the build ran, or zero if unknown
+    Skipped int64 // the number of tests the build skipped
+    FailedTests []string // the names of the build's failed tests, or nil if unknown
+    Coverage *Coverage // the code coverage of the build's tests, or nil if the build reports none
+    Warnings map[string]int64 // the number of static-analysis warnings per analysis tool, or nil if the build reports none
+    Artifacts []string // the relative paths of the build's artifacts that the instance shows; see Instance.ShownArtifacts
+    Complete bool
+    Result Result
+    Commit string // the revision that was built, if known
+    Change string // the first line of the most recent change's message, if known
+    Culprits []string // the names of the users whose changes may have caused the build's result
+    Parameters []Parameter // the build's parameters, in the order in which Jenkins reports them
+    FailedStage string // for pipeline builds that failed or are unstable, the name of the first stage that did, if known
+    ConsoleTail string // the end of the console output of a failed build, if the instance captures it
+    Columns map[string]string // the build's values in its instance's columns, keyed by column name, once computed
+    BackendId string // identifies the build to its instance's backend; unused for Jenkins builds
+    Err error // the error encountered while fetching the build's details, if any; the build's state is then unknown
+}
+
+// A Parameter is a parameter of a build.
+type Parameter struct {
+    Name string
+    Value string
+}
+
+type Job struct {
+    Name string
+    DisplayName string // the name to show for the job, if it differs from the job's name
+    Url string
+    Group string
+    Builds []*Build
+    Duplicate bool // true if the job was also listed by an earlier folder or view of the same instance
+    Queued bool // true if a build of the job is waiting in the build queue
+    QueuedWhy string // if the job is queued, the reason its build has not yet started
+    Upstream []string // the URLs of the jobs that trigger this job
+    Downstream []string // the URLs of the jobs this job triggers
+}
+
+type BuildSorter []*Build
+
+func (s BuildSorter) Len() int {
+    return len(s)
+}
+
+func (s BuildSorter) Swap(i, j int) {
+    s[i], s[j] = s[j], s[i]
+}
+
+func (s BuildSorter) Less(i, j int) bool {
+    return s[i].Id < s[j].Id
+}
+
+// Failed returns true if the build completed with a failure.
+func (b *Build) Failed() bool {
+    return b.Complete && b.Failures != 0
+}
+
+// LastCompletedBuild returns the most recent completed build of the job, if any.
+func (job *Job) LastCompletedBuild() *Build {
+    for i := len(job.Builds) - 1; i >= 0; i-- {
+        if job.Builds[i].Complete {
+            return job.Builds[i]
+        }
+    }
+    return nil
+}
+
+// Label returns the name to show for the job.
+func (job *Job) Label() string {
+    if job.DisplayName != "" {
+        return job.DisplayName
+    }
+    return job.Name
+}
+
+// Passed returns true if the build completed without failures. Unstable and aborted builds and builds that were not
+// built did not pass.
+func (b *Build) Passed() bool {
+    return b.Complete && !b.Failed() && b.Result != ResultUnstable && b.Result != ResultAborted &&
+        b.Result != ResultNotBuilt
+}
+
+// LastSuccess returns the most recent completed build of the job that passed, if any.
+func (job *Job) LastSuccess() *Build {
+    for i := len(job.Builds) - 1; i >= 0; i-- {
+        if b := job.Builds[i]; b.Passed() {
+            return b
+        }
+    }
+    return nil
+}
+
+// LastFailure returns the most recent completed build of the job that failed, if any.
+func (job *Job) LastFailure() *Build {
+    for i := len(job.Builds) - 1; i >= 0; i-- {
+        if b := job.Builds[i]; b.Failed() {
+            return b
+        }
+    }
+    return nil
+}
+
+// Progress estimates the fraction of a running build that has completed from the time since it started and its
+// estimated duration. Builds that are running longer than estimated have a progress of 1. Progress returns false if the
+// build is complete or if there is no estimate.
+func (b *Build) Progress() (float64, bool) {
+    if b.Complete || b.EstimatedDuration <= 0 || b.Timestamp.IsZero() {
+        return 0, false
+    }
+    progress := float64(time.Since(b.Timestamp)) / float64(b.EstimatedDuration)
+    if progress > 1 {
+        progress = 1
+    }
+    return progress, true
+}
+
+// Finished returns the time at which the build finished. Builds whose duration is unknown are treated as finishing
+// when they started.
+func (b *Build) Finished() time.Time {
+    return b.Timestamp.Add(b.Duration)
+}
+
+// failedTestsKnown returns true if the build's failed tests are known. Builds without test failures are known to have
+// no failed tests.
+func (b *Build) failedTestsKnown() bool {
+    return b.FailedTests != nil || (b.Complete && b.Failures == 0)
+}
+
+// NewFailures returns the number of tests that failed in the nth build of the job but not in the previous completed
+// build. NewFailures returns false if there is no previous completed build or if the failed tests of either build are
+// unknown.
+func (job *Job) NewFailures(n int) (int, bool) {
+    b := job.Builds[n]
+    if !b.failedTestsKnown() {
+        return 0, false
+    }
+
+    for p := n - 1; p >= 0; p-- {
+        previous := job.Builds[p]
+        if !previous.Complete {
+            continue
+        }
+        if !previous.failedTestsKnown() {
+            return 0, false
+        }
+
+        failed := make(map[string]bool)
+        for _, t := range previous.FailedTests {
+            failed[t] = true
+        }
+        count := 0
+        for _, t := range b.FailedTests {
+            if !failed[t] {
+                count++
+            }
+        }
+        return count, true
+    }
+    return 0, false
+}
+
+// FailureCount returns the number of failed builds in the job's history.
+func (job *Job) FailureCount() int {
+    count := 0
+    for _, b := range job.Builds {
+        if b.Failed() {
+            count++
+        }
+    }
+    return count
+}
+
+// PassRate returns the fraction of the job's completed builds that passed. Aborted builds and builds that were not
+// built neither passed nor failed, so they are not counted. Jobs with no such builds have a pass rate of 1.
+func (job *Job) PassRate() float64 {
+    complete, passed := 0, 0
+    for _, b := range job.Builds {
+        if !b.Complete || b.Result == ResultAborted || b.Result == ResultNotBuilt {
+            continue
+        }
+        complete++
+        if b.Passed() {
+            passed++
+        }
+    }
+    if complete == 0 {
+        return 1
+    }
+    return float64(passed) / float64(complete)
+}
+
+// LastBuildTime returns the start time of the job's most recent build.
+func (job *Job) LastBuildTime() time.Time {
+    if len(job.Builds) == 0 {
+        return time.Time{}
+    }
+    return job.Builds[len(job.Builds) - 1].Timestamp
+}
+
+// FirstFailingBuild returns the first build in the job's current run of failures. If the job's most recent completed
+// build passed, FirstFailingBuild returns nil. Note that the run may have started before the oldest fetched build.
+func (job *Job) FirstFailingBuild() *Build {
+    var first *Build
+    for i := len(job.Builds) - 1; i >= 0; i-- {
+        b := job.Builds[i]
+        if !b.Complete {
+            continue
+        }
+        if !b.Failed() {
+            break
+        }
+        first = b
+    }
+    return first
+}
+
+// FailureStreak returns the number of completed builds in the job's current run of failures. Like FirstFailingBuild,
+// FailureStreak only counts fetched builds.
+func (job *Job) FailureStreak() int {
+    streak := 0
+    for i := len(job.Builds) - 1; i >= 0; i-- {
+        b := job.Builds[i]
+        if !b.Complete {
+            continue
+        }
+        if !b.Failed() {
+            break
+        }
+        streak++
+    }
+    return streak
+}
+
+// BrokenSince returns the start time of the first build in the job's current run of failures. If the job's most
+// recent completed build passed, BrokenSince returns false.
+func (job *Job) BrokenSince() (time.Time, bool) {
+    if first := job.FirstFailingBuild(); first != nil {
+        return first.Timestamp, true
+    }
+    return time.Time{}, false
+}
+
+// Stale returns true if the job's most recent build started longer ago than the given threshold. Jobs are never stale
+// if the threshold is zero or if they have no fetched builds.
+func (job *Job) Stale(threshold time.Duration) bool {
+    last := job.LastBuildTime()
+    return threshold != 0 && !last.IsZero() && time.Since(last) > threshold
+}
+
+// Failing returns true if the job's most recent completed build failed.
+func (job *Job) Failing() bool {
+    last := job.LastCompletedBuild()
+    return last != nil && last.Failed()
+}
+
+// JobOrders maps the names of the supported job orderings to their comparison functions. Each comparison reports
+// whether the first job should sort before the second; jobs that compare equal are ordered by label.
+var JobOrders = map[string]func(a, b *Job) bool{
+    "name": func(a, b *Job) bool {
+        return false
+    },
+    "failures": func(a, b *Job) bool {
+        return a.FailureCount() > b.FailureCount()
+    },
+    "passRate": func(a, b *Job) bool {
+        return a.PassRate() < b.PassRate()
+    },
+    "lastBuild": func(a, b *Job) bool {
+        return a.LastBuildTime().After(b.LastBuildTime())
+    },
+    "recentlyBroken": func(a, b *Job) bool {
+        aSince, aBroken := a.BrokenSince()
+        bSince, bBroken := b.BrokenSince()
+        if aBroken != bBroken {
+            return aBroken
+        }
+        return aSince.After(bSince)
+    },
+}
+
+type JobSorter struct {
+    Jobs []*Job
+    Order func(a, b *Job) bool
+}
+
+func (s JobSorter) Len() int {
+    return len(s.Jobs)
+}
+
+func (s JobSorter) Swap(i, j int) {
+    s.Jobs[i], s.Jobs[j] = s.Jobs[j], s.Jobs[i]
+}
+
+func (s JobSorter) Less(i, j int) bool {
+    return s.Order(s.Jobs[i], s.Jobs[j])
+}
+
+// SortJobs sorts the given jobs using the named ordering. Jobs are first sorted by label so that the result is stable
+// regardless of the order in which Jenkins returned them.
+func SortJobs(jobs []*Job, order string) {
+    sort.Sort(JobSorter{jobs, func(a, b *Job) bool { return a.Label() < b.Label() }})
+    if less, ok := JobOrders[order]; ok {
+        sort.Stable(JobSorter{jobs, less})
+    }
+}
@@ -0,0 +1,182 @@
+package jenkins
+
+import (
+    "errors"
+    "fmt"
+    "regexp"
+)
+
+// branchListTree selects the jobs of a multibranch project along with their actions, which identify the default branch,
+// and their last builds, which identify unchanged branches.
+const branchListTree = "jobs[_class,name,url,actions[_class],lastBuild[number]]"
+
+// BranchFilter selects the repositories of organization folders and the branches of multibranch projects to show.
+type BranchFilter struct {
+    IncludeRepos []*regexp.Regexp // if non-empty, only repositories that match one of these are shown
+    ExcludeRepos []*regexp.Regexp
+    IncludeBranches []*regexp.Regexp // if non-empty, the branches that match one of these are shown instead of the default branch
+    ExcludeBranches []*regexp.Regexp
+}
+
+// matchesAny returns true if the name matches any of the given REs.
+func matchesAny(res []*regexp.Regexp, name string) bool {
+    for _, re := range res {
+        if re.MatchString(name) {
+            return true
+        }
+    }
+    return false
+}
+
+// ShowRepo returns true if the repository with the given name should be shown.
+func (f *BranchFilter) ShowRepo(name string) bool {
+    return (len(f.IncludeRepos) == 0 || matchesAny(f.IncludeRepos, name)) && !matchesAny(f.ExcludeRepos, name)
+}
+
+// ShowBranch returns true if the branch with the given name should be shown. Unless the filter includes branches by
+// name, only default branches are shown.
+func (f *BranchFilter) ShowBranch(name string, primary bool) bool {
+    if matchesAny(f.ExcludeBranches, name) {
+        return false
+    }
+    if len(f.IncludeBranches) == 0 {
+        return primary
+    }
+    return matchesAny(f.IncludeBranches, name)
+}
+
+// parseBranchFilter parses the instance keys that filter repositories and branches.
+func parseBranchFilter(o JsonObject) (BranchFilter, error) {
+    var f BranchFilter
+    for _, list := range []struct {
+        key string
+        res *[]*regexp.Regexp
+    }{
+        {"includeRepos", &f.IncludeRepos},
+        {"excludeRepos", &f.ExcludeRepos},
+        {"includeBranches", &f.IncludeBranches},
+        {"excludeBranches", &f.ExcludeBranches},
+    } {
+        array, _ := o.GetArray(list.key)
+        for _, e := range array {
+            s, ok := e.(string)
+            if !ok {
+                return f, errors.New(fmt.Sprintf("contains an invalid %s: %v", list.key, e))
+            }
+            re, err := regexp.Compile(s)
+            if err != nil {
+                return f, errors.New(fmt.Sprintf("contains an invalid %s %s: %s", list.key, s, err))
+            }
+            *list.res = append(*list.res, re)
+        }
+    }
+    return f, nil
+}
+
+// addOrgFolder adds the branches of the repositories in the organization folder with the given URL and path.
+func (l *jobLister) addOrgFolder(url, path, sourceGroup string) {
+    i := l.instance
+    if path != "" && i.IsExcluded(path) {
+        return
+    }
+
+    i.Logger().Info("fetching organization folder", "url", url)
+    listUrl := apiUrl(url, "api/json")
+    _, jobObjects, err := i.fetchJobList(listUrl)
+    if err != nil {
+        l.errs = append(l.errs, err)
+        return
+    }
+    l.addRepos(listUrl, path, jobObjects, sourceGroup)
+}
+
+// addRepos adds the branches of the repositories listed in an organization folder.
+func (l *jobLister) addRepos(listUrl, path string, jobObjects []interface{}, sourceGroup string) {
+    prefix := ""
+    if path != "" {
+        prefix = path + "/"
+    }
+
+    for _, j := range jobObjects {
+        job, ok := AsJsonObject(j)
+        if !ok {
+            continue
+        }
+        class, _ := job.GetString("_class")
+        name, hasName := job.GetString("name")
+        url, hasUrl := job.GetString("url")
+        if l.instance.Classes.Kind(class) != ClassMultibranch || !hasName || !hasUrl || !l.instance.Branches.ShowRepo(name) {
+            continue
+        }
+        l.addBranches(ResolveUrl(listUrl, url), prefix + name, sourceGroup)
+    }
+}
+
+// addBranches adds the branches of the multibranch project with the given URL and path that pass the instance's branch
+// filter. If no branch is marked as the default branch, a branch named main or master is assumed to be the default.
+func (l *jobLister) addBranches(url, path, sourceGroup string) {
+    i := l.instance
+    if i.IsExcluded(path) {
+        return
+    }
+
+    i.Logger().Info("fetching branches", "url", url)
+    listUrl := apiUrl(url, "api/json?tree=" + branchListTree)
+    _, jobObjects, err := i.fetchJobList(listUrl)
+    if err != nil {
+        l.errs = append(l.errs, err)
+        return
+    }
+
+    primary, hasPrimary := "", false
+    for _, j := range jobObjects {
+        if job, ok := AsJsonObject(j); ok && isPrimaryBranch(i.Classes, job) {
+            primary, _ = job.GetString("name")
+            hasPrimary = true
+        }
+    }
+    if !hasPrimary {
+        for _, j := range jobObjects {
+            if job, ok := AsJsonObject(j); ok {
+                if name, _ := job.GetString("name"); name == "main" || (name == "master" && primary == "") {
+                    primary = name
+                }
+            }
+        }
+    }
+
+    for _, j := range jobObjects {
+        job, ok := AsJsonObject(j)
+        if !ok {
+            continue
+        }
+        name, _ := job.GetString("name")
+        if !i.Branches.ShowBranch(name, name == primary) {
+            continue
+        }
+
+        branchUrl := ""
+        if u, ok := job.GetString("url"); ok {
+            branchUrl = ResolveUrl(listUrl, u)
+        }
+        l.addJob(branchUrl, sourceGroup, func() ([]*Job, []*FetchError) {
+            if unchanged := l.unchanged(branchUrl, job); unchanged != nil {
+                return []*Job{unchanged}, nil
+            }
+            return i.processJobObject(listUrl, path + "/", j)
+        })
+    }
+}
+
+// isPrimaryBranch returns true if the given branch job is marked as its project's default branch.
+func isPrimaryBranch(classes ClassMap, job JsonObject) bool {
+    actions, _ := job.GetArray("actions")
+    for _, a := range actions {
+        if action, ok := AsJsonObject(a); ok {
+            if class, _ := action.GetString("_class"); classes.Kind(class) == ClassPrimaryBranch {
+                return true
+            }
+        }
+    }
+    return false
+}
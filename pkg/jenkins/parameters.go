@@ -0,0 +1,16 @@
+package jenkins
+
+// ShownParameters returns the parameters of the given build that the instance is configured to show, in the order in
+// which they are configured.
+func (i *Instance) ShownParameters(b *Build) []Parameter {
+    var shown []Parameter
+    for _, name := range i.Parameters {
+        for _, p := range b.Parameters {
+            if p.Name == name {
+                shown = append(shown, p)
+                break
+            }
+        }
+    }
+    return shown
+}
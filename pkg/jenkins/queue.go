@@ -0,0 +1,64 @@
+package jenkins
+
+import (
+    "strings"
+)
+
+// RootUrls returns the root URLs of the Jenkins servers that host the instance's folders, views, and jobs. The root of
+// an item is the part of its URL that precedes its first "job" or "view" path segment.
+func (i *Instance) RootUrls() []string {
+    var roots []string
+    seen := make(map[string]bool)
+    for _, urls := range [][]string{i.Folders, i.Views, i.Jobs} {
+        for _, url := range urls {
+            root := url
+            for _, marker := range []string{"/job/", "/view/"} {
+                if n := strings.Index(root, marker); n != -1 {
+                    root = root[:n + 1]
+                }
+            }
+            if !seen[root] {
+                seen[root] = true
+                roots = append(roots, root)
+            }
+        }
+    }
+    return roots
+}
+
+// fetchQueue marks the given jobs that have builds waiting in the build queues of the instance's servers.
+func (i *Instance) fetchQueue(jobs []*Job) []*FetchError {
+    byUrl := make(map[string][]*Job)
+    for _, j := range jobs {
+        byUrl[j.Url] = append(byUrl[j.Url], j)
+    }
+
+    var errs []*FetchError
+    for _, root := range i.RootUrls() {
+        queueUrl := apiUrl(root, "queue/api/json")
+        queue, err := i.Api().Object(queueUrl)
+        if err != nil {
+            errs = append(errs, i.NewFetchError("", queueUrl, err))
+            continue
+        }
+
+        items, _ := queue.GetArray("items")
+        for _, it := range items {
+            item, ok := AsJsonObject(it)
+            if !ok {
+                continue
+            }
+            task, ok := item.GetObject("task")
+            if !ok {
+                continue
+            }
+            url, _ := task.GetString("url")
+            url = ResolveUrl(queueUrl, url)
+            why, _ := item.GetString("why")
+            for _, j := range byUrl[url] {
+                j.Queued, j.QueuedWhy = true, why
+            }
+        }
+    }
+    return errs
+}
@@ -0,0 +1,127 @@
+package jenkins
+
+import (
+    "bytes"
+    "fmt"
+    "io"
+    "log/slog"
+    "net/http"
+    "net/url"
+    "os"
+    "path/filepath"
+    "strings"
+)
+
+// RecordedUrl stands for the origin of the instance, e.g. "https://jenkins.example.com/", in recorded responses, so
+// that recordings can be replayed from another origin.
+const RecordedUrl = "${JENKINS_URL}"
+
+// recordedTypes lists the extensions of recorded responses and the content types they stand for, in the order in which
+// they are looked for.
+var recordedTypes = []struct {
+    ext string
+    contentType string
+}{
+    {".json", "application/json"},
+    {".txt", "text/plain"},
+    {".html", "text/html"},
+}
+
+// recordingPath returns the path of the recording of the response to the given URL, without its extension. Recordings
+// are grouped by host and mirror the URL's path; query strings are ignored.
+func recordingPath(dir string, u *url.URL) string {
+    return filepath.Join(dir, strings.ReplaceAll(u.Host, ":", "_"), filepath.FromSlash(u.Path))
+}
+
+// origin returns the scheme and host of the given URL followed by a slash.
+func origin(u *url.URL) string {
+    return u.Scheme + "://" + u.Host + "/"
+}
+
+// RecordingTransport returns a transport that sends requests with next and saves each successful response under dir,
+// where ReplayTransport can serve it. A recording directory holds a subdirectory per host that mirrors the paths of the
+// host's resources, e.g. dir/jenkins.example.com/job/f/api/json.json, and can also be loaded by a jenkinstest.Server.
+// Responses that cannot be saved are still returned.
+func RecordingTransport(dir string, next http.RoundTripper) http.RoundTripper {
+    if next == nil {
+        next = http.DefaultTransport
+    }
+    return &recordingTransport{dir, next}
+}
+
+type recordingTransport struct {
+    dir string
+    next http.RoundTripper
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+    resp, err := t.next.RoundTrip(req)
+    if err != nil || (resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent) {
+        return resp, err
+    }
+
+    body, err := io.ReadAll(resp.Body)
+    resp.Body.Close()
+    if err != nil {
+        return nil, err
+    }
+    resp.Body = io.NopCloser(bytes.NewReader(body))
+
+    ext := ".txt"
+    for _, rt := range recordedTypes {
+        if strings.HasPrefix(resp.Header.Get("Content-Type"), rt.contentType) {
+            ext = rt.ext
+            break
+        }
+    }
+    path := recordingPath(t.dir, req.URL) + ext
+    recorded := bytes.ReplaceAll(body, []byte(origin(req.URL)), []byte(RecordedUrl))
+    if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+        slog.Warn("could not record response", "url", req.URL.String(), "err", err)
+    } else if err := os.WriteFile(path, recorded, 0644); err != nil {
+        slog.Warn("could not record response", "url", req.URL.String(), "err", err)
+    }
+    return resp, nil
+}
+
+// ReplayTransport returns a transport that answers requests with the responses recorded under dir by
+// RecordingTransport rather than sending them. Requests without recorded responses are answered with 404 Not Found.
+func ReplayTransport(dir string) http.RoundTripper {
+    return &replayTransport{dir}
+}
+
+type replayTransport struct {
+    dir string
+}
+
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+    if req.Body != nil {
+        req.Body.Close()
+    }
+
+    path := recordingPath(t.dir, req.URL)
+    for _, rt := range recordedTypes {
+        body, err := os.ReadFile(path + rt.ext)
+        if err != nil {
+            continue
+        }
+        body = bytes.ReplaceAll(body, []byte(RecordedUrl), []byte(origin(req.URL)))
+        return replayResponse(req, http.StatusOK, rt.contentType, body), nil
+    }
+    return replayResponse(req, http.StatusNotFound, "text/plain", nil), nil
+}
+
+// replayResponse returns a response to the given request with the given status and body.
+func replayResponse(req *http.Request, status int, contentType string, body []byte) *http.Response {
+    return &http.Response{
+        Status: fmt.Sprintf("%d %s", status, http.StatusText(status)),
+        StatusCode: status,
+        Proto: "HTTP/1.1",
+        ProtoMajor: 1,
+        ProtoMinor: 1,
+        Header: http.Header{"Content-Type": {contentType}},
+        Body: io.NopCloser(bytes.NewReader(body)),
+        ContentLength: int64(len(body)),
+        Request: req,
+    }
+}
@@ -0,0 +1,67 @@
+package jenkins
+
+import (
+    "errors"
+)
+
+var notJenkinsError = errors.New("jobs can only be refreshed on Jenkins instances")
+
+// FindJob returns the index of the job with the given URL, or -1 if there is no such job.
+func (ij *InstanceJobs) FindJob(url string) int {
+    for n, j := range ij.Jobs {
+        if j.Url == url {
+            return n
+        }
+    }
+    return -1
+}
+
+// RefreshJob re-fetches the build list of the given job and returns an updated copy of the job that is limited to its
+// instance's window or, without a window, to its most recent builds, of which maxBuilds is the default number (see
+// Instance.JobLimits). The details of builds that were already complete are reused; the details of all other builds are
+// re-fetched, and the instance's custom columns are computed for the newest completed build. The original job is not
+// modified.
+func (i *Instance) RefreshJob(job *Job, maxBuilds int) (*Job, error) {
+    if i.Backend != nil {
+        return nil, notJenkinsError
+    }
+
+    details, err := i.Api().Object(apiUrl(job.Url, "api/json?tree=" + jobDetailsTree))
+    if err != nil {
+        return nil, err
+    }
+
+    builds, ok := i.processBuilds(job.Url, details)
+    if !ok {
+        return nil, missingBuildsError
+    }
+    builds = i.limitBuilds(job.Name, builds, maxBuilds)
+
+    complete := make(map[int64]*Build)
+    for _, b := range job.Builds {
+        if b.Complete {
+            complete[b.Id] = b
+        }
+    }
+
+    for n, b := range builds {
+        if c, ok := complete[b.Id]; ok {
+            builds[n] = c
+            continue
+        }
+        if err := i.FetchDetails(b); err != nil {
+            i.Logger().Debug("error fetching build details", "job", job.Name, "build", b.Url, "err", err)
+        }
+    }
+
+    updated := *job
+    updated.Builds = i.windowBuilds(builds)
+
+    // As in Refetch, column values come from the most recent completed build. Reused builds already have theirs and may
+    // still be in use, so they are left alone.
+    if b := updated.LastCompletedBuild(); len(i.Columns) != 0 && b != nil && b.Err == nil && b.Columns == nil &&
+        complete[b.Id] != b {
+        b.Columns = i.ColumnValues(job.Name, b)
+    }
+    return &updated, nil
+}
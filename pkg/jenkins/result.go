@@ -0,0 +1,49 @@
+package jenkins
+
+// Result is the outcome of a completed build. The values mirror Jenkins' build results; other backends map their
+// outcomes onto the closest equivalent.
+type Result int
+
+const (
+    ResultUnknown Result = iota // the build is still running or its backend does not report a result
+    ResultSuccess
+    ResultUnstable // the build completed, but some tests failed or a quality gate was not met
+    ResultFailure
+    ResultAborted
+    ResultNotBuilt
+)
+
+var resultNames = []string{
+    ResultUnknown: "UNKNOWN",
+    ResultSuccess: "SUCCESS",
+    ResultUnstable: "UNSTABLE",
+    ResultFailure: "FAILURE",
+    ResultAborted: "ABORTED",
+    ResultNotBuilt: "NOT_BUILT",
+}
+
+// ParseResult parses the name of a Jenkins build result. Unrecognized names parse as ResultUnknown.
+func ParseResult(name string) Result {
+    for r, n := range resultNames {
+        if n == name {
+            return Result(r)
+        }
+    }
+    return ResultUnknown
+}
+
+func (r Result) String() string {
+    if r < 0 || int(r) >= len(resultNames) {
+        return resultNames[ResultUnknown]
+    }
+    return resultNames[r]
+}
+
+func (r Result) MarshalText() ([]byte, error) {
+    return []byte(r.String()), nil
+}
+
+func (r *Result) UnmarshalText(text []byte) error {
+    *r = ParseResult(string(text))
+    return nil
+}
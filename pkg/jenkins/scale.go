@@ -0,0 +1,63 @@
+package jenkins
+
+import (
+    "errors"
+    "fmt"
+    "math"
+    "regexp"
+)
+
+// Scale controls how the failure counts of builds map to the heights of their sparkline cells.
+type Scale struct {
+    Max int64 // the failure count that fills a cell, or zero to scale relative to the most failures in the sparkline
+    Log bool // true to map failure counts logarithmically, so that small counts remain distinguishable from large ones
+}
+
+// ScaleRule overrides the scale of the jobs whose names match a regular expression.
+type ScaleRule struct {
+    Match *regexp.Regexp
+    Scale Scale
+}
+
+// Fraction returns the fraction of a cell that the given failure count fills, where max is the failure count that
+// fills a cell. Counts above max fill the cell.
+func (s Scale) Fraction(failures, max int64) float64 {
+    if max <= 0 {
+        return 1
+    }
+    if failures > max {
+        failures = max
+    }
+    if s.Log {
+        return math.Log1p(float64(failures)) / math.Log1p(float64(max))
+    }
+    return float64(failures) / float64(max)
+}
+
+// JobScale returns the scale of the given job. The instance's scale overrides the given default, and the first scale
+// rule that matches the job overrides the instance's scale.
+func (i *Instance) JobScale(name string, scale Scale) Scale {
+    if i.Scale != nil {
+        scale = *i.Scale
+    }
+    for _, s := range i.Scales {
+        if s.Match.MatchString(name) {
+            return s.Scale
+        }
+    }
+    return scale
+}
+
+// ParseScale parses a scale object of the form {"max": 100, "log": true}. Both properties are optional.
+func ParseScale(o JsonObject) (Scale, error) {
+    var scale Scale
+    if _, ok := o["max"]; ok {
+        max, ok := o.GetInt64("max")
+        if !ok || max < 0 {
+            return Scale{}, errors.New(fmt.Sprintf("invalid max %v", o["max"]))
+        }
+        scale.Max = max
+    }
+    scale.Log, _ = o.GetBool("log")
+    return scale, nil
+}
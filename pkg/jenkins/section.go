@@ -0,0 +1,138 @@
+package jenkins
+
+import (
+    "errors"
+    "fmt"
+    "regexp"
+    "strings"
+)
+
+// A Section is a curated part of the dashboard with its own heading. It lists the jobs that its selectors match,
+// regardless of which instance, folder, or view they were listed by, so that the dashboard can read like a report
+// (e.g. "Release blockers", "Nightlies") rather than being organized by instance.
+type Section struct {
+    Name string
+    Instances []string // the names of the instances whose jobs may be listed, or empty for every instance
+    Folders []string // the URLs of the folders whose jobs may be listed, or empty for every folder
+    Jobs []*regexp.Regexp // the patterns of which a job's name must match one, or empty to match every job
+    OnlyFailing bool // true to only list jobs whose most recent completed build failed
+    Sort string // the name of the section's job ordering, or empty to use the dashboard's
+    MaxHistory int // the number of most recent builds to render per job, or zero to use the dashboard's
+}
+
+// Selects returns true if the section lists the given job of the given instance.
+func (s *Section) Selects(i *Instance, job *Job) bool {
+    if len(s.Instances) != 0 && !contains(s.Instances, i.Name) {
+        return false
+    }
+    if len(s.Folders) != 0 {
+        inFolder := false
+        for _, f := range s.Folders {
+            if strings.HasPrefix(job.Url, f) {
+                inFolder = true
+                break
+            }
+        }
+        if !inFolder {
+            return false
+        }
+    }
+    if len(s.Jobs) != 0 {
+        for _, re := range s.Jobs {
+            if re.MatchString(job.Name) {
+                return !s.OnlyFailing || job.Failing()
+            }
+        }
+        return false
+    }
+    return !s.OnlyFailing || job.Failing()
+}
+
+// contains returns true if the given strings include s.
+func contains(strs []string, s string) bool {
+    for _, str := range strs {
+        if str == s {
+            return true
+        }
+    }
+    return false
+}
+
+// parseSections parses the dashboard's sections, which are rendered in the order in which they are given.
+func parseSections(sectionsArray []interface{}) ([]*Section, error) {
+    var sections []*Section
+    for _, v := range sectionsArray {
+        sectionObject, ok := AsJsonObject(v)
+        if !ok {
+            return nil, errors.New(fmt.Sprintf("invalid section: %v", v))
+        }
+
+        name, ok := sectionObject.GetString("name")
+        if !ok || name == "" {
+            return nil, errors.New("a section specifies no name")
+        }
+
+        section, err := parseSection(name, sectionObject)
+        if err != nil {
+            return nil, err
+        }
+        sections = append(sections, section)
+    }
+    return sections, nil
+}
+
+// parseSection parses the selectors and display options of the named section.
+func parseSection(name string, sectionObject JsonObject) (*Section, error) {
+    strs := func(key string) ([]string, error) {
+        array, _ := sectionObject.GetArray(key)
+        var result []string
+        for _, e := range array {
+            str, ok := e.(string)
+            if !ok {
+                return nil, errors.New(fmt.Sprintf("section %s has an invalid %s entry: %v", name, key, e))
+            }
+            result = append(result, str)
+        }
+        return result, nil
+    }
+
+    instances, err := strs("instances")
+    if err != nil {
+        return nil, err
+    }
+    folders, err := strs("folders")
+    if err != nil {
+        return nil, err
+    }
+    for n, f := range folders {
+        if !strings.HasSuffix(f, "/") {
+            folders[n] = f + "/"
+        }
+    }
+    patterns, err := strs("jobs")
+    if err != nil {
+        return nil, err
+    }
+    var jobs []*regexp.Regexp
+    for _, p := range patterns {
+        re, err := regexp.Compile(p)
+        if err != nil {
+            return nil, errors.New(fmt.Sprintf("section %s has an invalid job pattern %s: %s", name, p, err))
+        }
+        jobs = append(jobs, re)
+    }
+
+    onlyFailing, _ := sectionObject.GetBool("onlyFailing")
+
+    order, _ := sectionObject.GetString("sort")
+    if _, ok := JobOrders[order]; order != "" && !ok {
+        return nil, errors.New(fmt.Sprintf("section %s has an unknown sort order %s", name, order))
+    }
+
+    maxHistory, _ := sectionObject.GetInt64("maxHistory")
+    if maxHistory < 0 {
+        return nil, errors.New(fmt.Sprintf("section %s has an invalid maxHistory %d", name, maxHistory))
+    }
+
+    return &Section{name, instances, folders, jobs, onlyFailing, order, int(maxHistory)}, nil
+}
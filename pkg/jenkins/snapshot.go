@@ -0,0 +1,131 @@
+package jenkins
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "time"
+)
+
+// snapshotVersion is the version of the snapshot format written by WriteSnapshot.
+const snapshotVersion = 1
+
+// A snapshot is the serialized form of a fetched model. Instances are identified by name; their configuration is not
+// part of the snapshot.
+type snapshot struct {
+    Version int
+    Instances []*snapshotInstance
+}
+
+type snapshotInstance struct {
+    Name string
+    Jobs []*snapshotJob
+    Errors []*snapshotError
+    Agents *AgentStatus `json:",omitempty"`
+    FetchedAt time.Time
+}
+
+// jobFields and buildFields have the fields of Job and Build, which the snapshot types embed so that they can replace
+// the fields that do not serialize as-is.
+type jobFields Job
+type buildFields Build
+
+type snapshotJob struct {
+    jobFields
+    Builds []*snapshotBuild
+}
+
+type snapshotBuild struct {
+    buildFields
+    Err string `json:",omitempty"`
+}
+
+type snapshotError struct {
+    Job string `json:",omitempty"`
+    Url string
+    Err string
+    Status *StatusError `json:",omitempty"` // the response that caused the error, if any, so that it can be classified
+}
+
+// WriteSnapshot writes the given fetched instances to w as JSON so that they can later be rendered without fetching
+// them again. See ReadSnapshot.
+func WriteSnapshot(w io.Writer, instances []*InstanceJobs) error {
+    s := snapshot{Version: snapshotVersion}
+    for _, ij := range instances {
+        si := &snapshotInstance{Name: ij.Instance.Name, Agents: ij.Agents, FetchedAt: ij.FetchedAt}
+        for _, j := range ij.Jobs {
+            sj := &snapshotJob{jobFields: jobFields(*j)}
+            for _, b := range j.Builds {
+                sb := &snapshotBuild{buildFields: buildFields(*b)}
+                if b.Err != nil {
+                    sb.Err = b.Err.Error()
+                }
+                sj.Builds = append(sj.Builds, sb)
+            }
+            si.Jobs = append(si.Jobs, sj)
+        }
+        for _, e := range ij.Errors {
+            var status *StatusError
+            errors.As(e.Err, &status)
+            si.Errors = append(si.Errors, &snapshotError{e.Job, e.Url, e.Err.Error(), status})
+        }
+        s.Instances = append(s.Instances, si)
+    }
+
+    encoder := json.NewEncoder(w)
+    encoder.SetIndent("", "  ")
+    return encoder.Encode(s)
+}
+
+// ReadSnapshot reads instances written by WriteSnapshot. Each instance in the snapshot is matched by name with one of
+// the given configured instances, whose configuration then applies when the snapshot is rendered. If no instances are
+// given, each instance in the snapshot is given an empty configuration.
+func ReadSnapshot(r io.Reader, instances []*Instance) ([]*InstanceJobs, error) {
+    var s snapshot
+    if err := json.NewDecoder(r).Decode(&s); err != nil {
+        return nil, err
+    }
+    if s.Version != snapshotVersion {
+        return nil, errors.New(fmt.Sprintf("unsupported snapshot version %d", s.Version))
+    }
+
+    byName := make(map[string]*Instance)
+    for _, i := range instances {
+        byName[i.Name] = i
+    }
+
+    var result []*InstanceJobs
+    for _, si := range s.Instances {
+        i, ok := byName[si.Name]
+        if instances == nil {
+            i, ok = &Instance{Name: si.Name}, true
+        }
+        if !ok {
+            return nil, errors.New(fmt.Sprintf("snapshot instance %s is not configured", si.Name))
+        }
+
+        ij := &InstanceJobs{Instance: i, Agents: si.Agents, FetchedAt: si.FetchedAt}
+        for _, sj := range si.Jobs {
+            j := Job(sj.jobFields)
+            j.Builds = nil
+            for _, sb := range sj.Builds {
+                b := Build(sb.buildFields)
+                if sb.Err != "" {
+                    b.Err = errors.New(sb.Err)
+                }
+                j.Builds = append(j.Builds, &b)
+            }
+            ij.Jobs = append(ij.Jobs, &j)
+        }
+        for _, se := range si.Errors {
+            err := errors.New(se.Err)
+            if se.Status != nil {
+                err = se.Status
+            }
+            ij.Errors = append(ij.Errors, &FetchError{Instance: i.Name, Job: se.Job, Url: se.Url, Err: err})
+        }
+        result = append(result, ij)
+    }
+    return result, nil
+}
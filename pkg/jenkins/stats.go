@@ -0,0 +1,58 @@
+package jenkins
+
+import (
+    "time"
+)
+
+// JobStats summarizes the health of a job over a period of time.
+type JobStats struct {
+    Builds int // the number of completed builds
+    Failures int // the number of failed builds
+    FailureRate float64 // the fraction of completed builds that failed
+    Recoveries int // the number of runs of failures that ended with a passing build
+    MTTR time.Duration // the mean time from the start of a run of failures to the end of the build that ended it
+    LongestRedStreak int // the largest number of consecutive failed builds
+}
+
+// Stats computes the job's statistics over the builds that started at or after the given time. Builds that neither
+// passed nor failed (e.g. aborted builds) do not end runs of failures. Only fetched builds are considered, so the
+// period is effectively bounded by the job's history.
+func (job *Job) Stats(since time.Time) JobStats {
+    var s JobStats
+    var totalRecovery time.Duration
+    var runStart *Build
+    streak := 0
+    for _, b := range job.Builds {
+        if !b.Complete || b.Timestamp.Before(since) {
+            continue
+        }
+        s.Builds++
+
+        switch {
+        case b.Failed():
+            s.Failures++
+            if runStart == nil {
+                runStart = b
+            }
+            if streak++; streak > s.LongestRedStreak {
+                s.LongestRedStreak = streak
+            }
+
+        case b.Passed():
+            if runStart != nil {
+                s.Recoveries++
+                totalRecovery += b.Finished().Sub(runStart.Timestamp)
+                runStart = nil
+            }
+            streak = 0
+        }
+    }
+
+    if s.Builds != 0 {
+        s.FailureRate = float64(s.Failures) / float64(s.Builds)
+    }
+    if s.Recoveries != 0 {
+        s.MTTR = totalRecovery / time.Duration(s.Recoveries)
+    }
+    return s
+}
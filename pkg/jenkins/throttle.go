@@ -0,0 +1,94 @@
+package jenkins
+
+import (
+    "io"
+    "net/http"
+    "sync"
+    "time"
+)
+
+// A Throttle bounds the number of requests that may be in flight to a single instance and the rate at which new
+// requests are issued. Throttles are installed in an instance's HTTP client, so they apply to every backend.
+type Throttle struct {
+    slots chan struct{} // nil if concurrency is unbounded
+    interval time.Duration // the minimum time between requests, or zero if the rate is unbounded
+
+    m sync.Mutex
+    next time.Time // the earliest time at which the next request may be issued
+}
+
+// NewThrottle creates a throttle that allows at most concurrency requests in flight and at most rate requests per
+// second. A non-positive concurrency or rate leaves the corresponding dimension unbounded.
+func NewThrottle(concurrency int, rate float64) *Throttle {
+    t := &Throttle{}
+    if concurrency > 0 {
+        t.slots = make(chan struct{}, concurrency)
+    }
+    if rate > 0 {
+        t.interval = time.Duration(float64(time.Second) / rate)
+    }
+    return t
+}
+
+// Acquire blocks until a request may be issued. Each call must be paired with a call to Release.
+func (t *Throttle) Acquire() {
+    if t.slots != nil {
+        t.slots <- struct{}{}
+    }
+
+    if t.interval != 0 {
+        t.m.Lock()
+        now := time.Now()
+        wait := t.next.Sub(now)
+        if wait < 0 {
+            wait, t.next = 0, now
+        }
+        t.next = t.next.Add(t.interval)
+        t.m.Unlock()
+
+        time.Sleep(wait)
+    }
+}
+
+// Release marks the end of a request.
+func (t *Throttle) Release() {
+    if t.slots != nil {
+        <-t.slots
+    }
+}
+
+// Transport wraps the given round tripper so that each request holds a slot in the throttle until its response body
+// is closed.
+func (t *Throttle) Transport(base http.RoundTripper) http.RoundTripper {
+    return &throttledTransport{t, base}
+}
+
+type throttledTransport struct {
+    throttle *Throttle
+    base http.RoundTripper
+}
+
+func (tt *throttledTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+    tt.throttle.Acquire()
+
+    resp, err := tt.base.RoundTrip(req)
+    if err != nil {
+        tt.throttle.Release()
+        return nil, err
+    }
+
+    resp.Body = &throttledBody{ReadCloser: resp.Body, throttle: tt.throttle}
+    return resp, nil
+}
+
+type throttledBody struct {
+    io.ReadCloser
+    throttle *Throttle
+    once sync.Once
+}
+
+func (b *throttledBody) Close() error {
+    err := b.ReadCloser.Close()
+    b.once.Do(b.throttle.Release)
+    return err
+}
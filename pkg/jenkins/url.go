@@ -0,0 +1,88 @@
+package jenkins
+
+import (
+    "errors"
+    "fmt"
+    "net/http"
+    "net/url"
+    "strings"
+)
+
+// ResolveUrl resolves a URL reported by Jenkins against the URL of the resource that reported it. Jenkins normally
+// reports absolute URLs, but a Jenkins behind a reverse proxy may report URLs that are relative to the proxy's path
+// prefix. Absolute URLs are returned unchanged, as are URLs that cannot be parsed.
+func ResolveUrl(base, ref string) string {
+    b, err := url.Parse(base)
+    if err != nil {
+        return ref
+    }
+    r, err := url.Parse(ref)
+    if err != nil {
+        return ref
+    }
+    return b.ResolveReference(r).String()
+}
+
+// A UrlRewrite maps URLs under the prefix through which an instance is fetched to the prefix through which users reach
+// it, e.g. from an internal hostname to an external one.
+type UrlRewrite struct {
+    Fetch string
+    Display string
+}
+
+// DisplayUrl returns the URL to show to users for the given URL fetched from the instance. The first rewrite whose
+// fetch prefix matches applies; URLs that match no rewrite are returned unchanged.
+func (i *Instance) DisplayUrl(u string) string {
+    for _, r := range i.UrlRewrites {
+        if strings.HasPrefix(u, r.Fetch) {
+            return r.Display + u[len(r.Fetch):]
+        }
+    }
+    return u
+}
+
+// apiUrl returns the URL of the resource at the given path relative to the given Jenkins item (e.g. "api/json"). The
+// item URL is treated as a directory whether or not it ends in a slash.
+func apiUrl(itemUrl, path string) string {
+    if u, err := url.Parse(itemUrl); err == nil {
+        u.RawQuery, u.Fragment = "", ""
+        if !strings.HasSuffix(u.Path, "/") {
+            u.Path += "/"
+            u.RawPath = ""
+        }
+        itemUrl = u.String()
+    }
+    return ResolveUrl(itemUrl, path)
+}
+
+// Redirect policies.
+const (
+    RedirectSameHost = "sameHost" // follow redirects to the same host only
+    RedirectFollow = "follow" // follow all redirects
+    RedirectNone = "none" // do not follow redirects
+)
+
+// checkRedirect returns a function that enforces the given redirect policy for an http.Client. Redirects to other hosts
+// are refused by default because the instance's credentials are sent with every request.
+func checkRedirect(policy string) (func(req *http.Request, via []*http.Request) error, error) {
+    switch policy {
+    case "", RedirectSameHost:
+        return func(req *http.Request, via []*http.Request) error {
+            if len(via) >= 10 {
+                return errors.New("stopped after 10 redirects")
+            }
+            if req.URL.Host != via[0].URL.Host {
+                return errors.New(fmt.Sprintf("redirected to %s, which is on another host; configure the instance with URLs on that host or set redirects to follow", req.URL))
+            }
+            return nil
+        }, nil
+    case RedirectFollow:
+        return nil, nil
+    case RedirectNone:
+        return func(req *http.Request, via []*http.Request) error {
+            return errors.New(fmt.Sprintf("redirected to %s; configure the instance with the redirect target's URLs", req.URL))
+        }, nil
+    default:
+        return nil, errors.New(fmt.Sprintf("unknown redirect policy %s", policy))
+    }
+}
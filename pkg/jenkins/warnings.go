@@ -0,0 +1,38 @@
+package jenkins
+
+// BuildWarnings fetches the number of static-analysis warnings that the Warnings Next Generation plugin recorded for
+// the build at the given URL, per analysis tool. The plugin summarizes the results of every tool that analyzed the
+// build, e.g. {"tools": [{"id": "java", "name": "Java Compiler", "size": 12}, {"id": "checkstyle", ...}]}.
+func (c *Client) BuildWarnings(buildUrl string) (map[string]int64, error) {
+    summary, err := c.Object(apiUrl(buildUrl, "warnings-ng/api/json"))
+    if err != nil {
+        return nil, err
+    }
+
+    warnings := make(map[string]int64)
+    tools, _ := summary.GetArray("tools")
+    for _, t := range tools {
+        tool, ok := AsJsonObject(t)
+        if !ok {
+            continue
+        }
+        name, ok := tool.GetString("name")
+        if !ok {
+            if name, ok = tool.GetString("id"); !ok {
+                continue
+            }
+        }
+        size, _ := tool.GetInt64("size")
+        warnings[name] += size
+    }
+    return warnings, nil
+}
+
+// WarningCount returns the total number of static-analysis warnings of the build across all tools.
+func (b *Build) WarningCount() int64 {
+    var count int64
+    for _, n := range b.Warnings {
+        count += n
+    }
+    return count
+}
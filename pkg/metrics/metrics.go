@@ -0,0 +1,223 @@
+// Package metrics collects jitdash's own operational metrics, such as how long fetches take and how many requests
+// fail, and exposes them in the Prometheus text format. These describe jitdash itself rather than the builds it tracks.
+package metrics
+
+import (
+    "bytes"
+    "fmt"
+    "io"
+    "math"
+    "sort"
+    "strings"
+    "sync"
+)
+
+// A Counter is a value that only increases.
+type Counter struct {
+    m sync.Mutex
+    value float64
+}
+
+// Add adds the given non-negative amount to the counter.
+func (c *Counter) Add(delta float64) {
+    c.m.Lock()
+    defer c.m.Unlock()
+    c.value += delta
+}
+
+// Inc adds one to the counter.
+func (c *Counter) Inc() {
+    c.Add(1)
+}
+
+// A Gauge is a value that may go up and down.
+type Gauge struct {
+    m sync.Mutex
+    value float64
+}
+
+// Set sets the gauge's value.
+func (g *Gauge) Set(value float64) {
+    g.m.Lock()
+    defer g.m.Unlock()
+    g.value = value
+}
+
+// Add adds the given amount, which may be negative, to the gauge.
+func (g *Gauge) Add(delta float64) {
+    g.m.Lock()
+    defer g.m.Unlock()
+    g.value += delta
+}
+
+// A Summary tracks the count and sum of a series of observations, e.g. of durations in seconds.
+type Summary struct {
+    m sync.Mutex
+    count uint64
+    sum float64
+}
+
+// Observe records an observation.
+func (s *Summary) Observe(value float64) {
+    s.m.Lock()
+    defer s.m.Unlock()
+    s.count++
+    s.sum += value
+}
+
+// family is a named metric with a fixed set of label names and a child metric per combination of label values.
+type family struct {
+    name string
+    help string
+    kind string // the Prometheus type of the metric: counter, gauge, or summary
+    labels []string
+    newChild func() interface{}
+
+    m sync.Mutex
+    children map[string]interface{} // keyed by the joined label values
+}
+
+func (f *family) with(values []string) interface{} {
+    if len(values) != len(f.labels) {
+        panic(fmt.Sprintf("metric %s takes %d labels, not %d", f.name, len(f.labels), len(values)))
+    }
+
+    key := strings.Join(values, "\x00")
+
+    f.m.Lock()
+    defer f.m.Unlock()
+    c, ok := f.children[key]
+    if !ok {
+        c = f.newChild()
+        f.children[key] = c
+    }
+    return c
+}
+
+var (
+    registryM sync.Mutex
+    registry []*family
+)
+
+func register(name, help, kind string, labels []string, newChild func() interface{}) *family {
+    f := &family{name: name, help: help, kind: kind, labels: labels, newChild: newChild, children: make(map[string]interface{})}
+
+    registryM.Lock()
+    defer registryM.Unlock()
+    registry = append(registry, f)
+    return f
+}
+
+// CounterVec is a family of counters that are distinguished by their label values.
+type CounterVec struct {
+    f *family
+}
+
+// NewCounterVec registers a family of counters with the given name, help text, and label names.
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+    return &CounterVec{register(name, help, "counter", labels, func() interface{} { return &Counter{} })}
+}
+
+// With returns the counter with the given label values, creating it if necessary.
+func (v *CounterVec) With(values ...string) *Counter {
+    return v.f.with(values).(*Counter)
+}
+
+// GaugeVec is a family of gauges that are distinguished by their label values.
+type GaugeVec struct {
+    f *family
+}
+
+// NewGaugeVec registers a family of gauges with the given name, help text, and label names.
+func NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+    return &GaugeVec{register(name, help, "gauge", labels, func() interface{} { return &Gauge{} })}
+}
+
+// With returns the gauge with the given label values, creating it if necessary.
+func (v *GaugeVec) With(values ...string) *Gauge {
+    return v.f.with(values).(*Gauge)
+}
+
+// SummaryVec is a family of summaries that are distinguished by their label values.
+type SummaryVec struct {
+    f *family
+}
+
+// NewSummaryVec registers a family of summaries with the given name, help text, and label names.
+func NewSummaryVec(name, help string, labels ...string) *SummaryVec {
+    return &SummaryVec{register(name, help, "summary", labels, func() interface{} { return &Summary{} })}
+}
+
+// With returns the summary with the given label values, creating it if necessary.
+func (v *SummaryVec) With(values ...string) *Summary {
+    return v.f.with(values).(*Summary)
+}
+
+// labelEscaper escapes label values as required by the text format.
+var labelEscaper = strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\n", "\\n")
+
+// formatValue formats a sample value as required by the text format.
+func formatValue(v float64) string {
+    switch {
+    case math.IsInf(v, 1):
+        return "+Inf"
+    case math.IsInf(v, -1):
+        return "-Inf"
+    default:
+        return fmt.Sprintf("%g", v)
+    }
+}
+
+// WriteText writes every registered metric to the given writer in the Prometheus text exposition format. Metrics are
+// written in order of name, and each metric's samples in order of their label values.
+func WriteText(w io.Writer) error {
+    registryM.Lock()
+    families := make([]*family, len(registry))
+    copy(families, registry)
+    registryM.Unlock()
+    sort.Slice(families, func(i, j int) bool { return families[i].name < families[j].name })
+
+    b := new(bytes.Buffer)
+    for _, f := range families {
+        fmt.Fprintf(b, "# HELP %s %s\n", f.name, f.help)
+        fmt.Fprintf(b, "# TYPE %s %s\n", f.name, f.kind)
+
+        f.m.Lock()
+        keys := make([]string, 0, len(f.children))
+        for k := range f.children {
+            keys = append(keys, k)
+        }
+        sort.Strings(keys)
+
+        for _, k := range keys {
+            var labels string
+            if len(f.labels) != 0 {
+                pairs := make([]string, len(f.labels))
+                for n, v := range strings.Split(k, "\x00") {
+                    pairs[n] = fmt.Sprintf("%s=\"%s\"", f.labels[n], labelEscaper.Replace(v))
+                }
+                labels = "{" + strings.Join(pairs, ",") + "}"
+            }
+
+            switch c := f.children[k].(type) {
+            case *Counter:
+                c.m.Lock()
+                fmt.Fprintf(b, "%s%s %s\n", f.name, labels, formatValue(c.value))
+                c.m.Unlock()
+            case *Gauge:
+                c.m.Lock()
+                fmt.Fprintf(b, "%s%s %s\n", f.name, labels, formatValue(c.value))
+                c.m.Unlock()
+            case *Summary:
+                c.m.Lock()
+                fmt.Fprintf(b, "%s_sum%s %s\n", f.name, labels, formatValue(c.sum))
+                fmt.Fprintf(b, "%s_count%s %d\n", f.name, labels, c.count)
+                c.m.Unlock()
+            }
+        }
+        f.m.Unlock()
+    }
+
+    _, err := w.Write(b.Bytes())
+    return err
+}
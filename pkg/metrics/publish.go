@@ -0,0 +1,226 @@
+package metrics
+
+import (
+    "bytes"
+    "encoding/binary"
+    "errors"
+    "fmt"
+    "io"
+    "math"
+    "net/http"
+    "net/url"
+    "sort"
+    "strings"
+    "time"
+)
+
+// A Label is a name and value that distinguishes a sample from others of the same metric.
+type Label struct {
+    Name string
+    Value string
+}
+
+// A Sample is a value of a metric at the time it is published. Samples describe values that are computed on demand,
+// such as the state of the jobs fetched by a batch run, rather than values of registered metrics.
+type Sample struct {
+    Name string
+    Labels []Label
+    Value float64
+}
+
+// Gather returns the current values of every registered metric as samples. Summaries produce a "_sum" and a "_count"
+// sample.
+func Gather() []Sample {
+    registryM.Lock()
+    families := make([]*family, len(registry))
+    copy(families, registry)
+    registryM.Unlock()
+
+    var samples []Sample
+    for _, f := range families {
+        f.m.Lock()
+        for k, child := range f.children {
+            var labels []Label
+            if len(f.labels) != 0 {
+                for n, v := range strings.Split(k, "\x00") {
+                    labels = append(labels, Label{f.labels[n], v})
+                }
+            }
+
+            switch c := child.(type) {
+            case *Counter:
+                c.m.Lock()
+                samples = append(samples, Sample{f.name, labels, c.value})
+                c.m.Unlock()
+            case *Gauge:
+                c.m.Lock()
+                samples = append(samples, Sample{f.name, labels, c.value})
+                c.m.Unlock()
+            case *Summary:
+                c.m.Lock()
+                samples = append(samples, Sample{f.name + "_sum", labels, c.sum}, Sample{f.name + "_count", labels, float64(c.count)})
+                c.m.Unlock()
+            }
+        }
+        f.m.Unlock()
+    }
+    return samples
+}
+
+// WriteSamples writes the given samples to w in the Prometheus text exposition format, ordered by name and labels.
+// The samples are untyped.
+func WriteSamples(w io.Writer, samples []Sample) error {
+    lines := make([]string, 0, len(samples))
+    for _, s := range samples {
+        var labels string
+        if len(s.Labels) != 0 {
+            pairs := make([]string, len(s.Labels))
+            for n, l := range s.Labels {
+                pairs[n] = fmt.Sprintf("%s=\"%s\"", l.Name, labelEscaper.Replace(l.Value))
+            }
+            labels = "{" + strings.Join(pairs, ",") + "}"
+        }
+        lines = append(lines, fmt.Sprintf("%s%s %s\n", s.Name, labels, formatValue(s.Value)))
+    }
+    sort.Strings(lines)
+
+    _, err := io.WriteString(w, strings.Join(lines, ""))
+    return err
+}
+
+// A Sink receives samples and forwards them to a monitoring system.
+type Sink interface {
+    Publish(samples []Sample) error
+}
+
+// A Publisher sends samples to a Prometheus Pushgateway, to a Prometheus remote-write endpoint, or to both. It is meant
+// for batch runs, which exit before they could be scraped.
+type Publisher struct {
+    PushgatewayUrl string // the base URL of the Pushgateway, or empty
+    Job string // the job label under which samples are pushed to the Pushgateway
+    RemoteWriteUrl string // the URL of the remote-write endpoint, or empty
+    Headers map[string]string // additional headers to send with each request, e.g. for authentication
+    Client *http.Client
+}
+
+// Publish sends the given samples to the publisher's destinations. Pushed samples replace those previously pushed under
+// the publisher's job.
+func (p *Publisher) Publish(samples []Sample) error {
+    if p.PushgatewayUrl != "" {
+        b := new(bytes.Buffer)
+        if err := WriteSamples(b, samples); err != nil {
+            return err
+        }
+        pushUrl := strings.TrimSuffix(p.PushgatewayUrl, "/") + "/metrics/job/" + url.PathEscape(p.Job)
+        if err := p.send("PUT", pushUrl, "text/plain; version=0.0.4", nil, b.Bytes()); err != nil {
+            return errors.New(fmt.Sprintf("pushing to %s: %s", pushUrl, err))
+        }
+    }
+
+    if p.RemoteWriteUrl != "" {
+        headers := map[string]string{"Content-Encoding": "snappy", "X-Prometheus-Remote-Write-Version": "0.1.0"}
+        body := snappyEncode(encodeWriteRequest(samples, time.Now()))
+        if err := p.send("POST", p.RemoteWriteUrl, "application/x-protobuf", headers, body); err != nil {
+            return errors.New(fmt.Sprintf("writing to %s: %s", p.RemoteWriteUrl, err))
+        }
+    }
+    return nil
+}
+
+func (p *Publisher) send(method, url, contentType string, headers map[string]string, body []byte) error {
+    req, err := http.NewRequest(method, url, bytes.NewReader(body))
+    if err != nil {
+        return err
+    }
+    req.Header.Set("Content-Type", contentType)
+    for k, v := range headers {
+        req.Header.Set(k, v)
+    }
+    for k, v := range p.Headers {
+        req.Header.Set(k, v)
+    }
+
+    resp, err := p.Client.Do(req)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+    io.Copy(io.Discard, resp.Body)
+
+    if resp.StatusCode/100 != 2 {
+        return errors.New(resp.Status)
+    }
+    return nil
+}
+
+// appendVarint appends a protobuf varint.
+func appendVarint(b []byte, v uint64) []byte {
+    return binary.AppendUvarint(b, v)
+}
+
+// appendBytes appends a length-delimited protobuf field.
+func appendBytes(b []byte, field int, data []byte) []byte {
+    b = appendVarint(b, uint64(field << 3 | 2))
+    b = appendVarint(b, uint64(len(data)))
+    return append(b, data...)
+}
+
+// encodeWriteRequest encodes the given samples as a remote-write WriteRequest protobuf message with a time series per
+// sample, each stamped with the given time:
+//
+//     message WriteRequest { repeated TimeSeries timeseries = 1; }
+//     message TimeSeries { repeated Label labels = 1; repeated Sample samples = 2; }
+//     message Label { string name = 1; string value = 2; }
+//     message Sample { double value = 1; int64 timestamp = 2; }
+//
+// Labels are sorted by name, as remote-write receivers require, with the metric name as the "__name__" label.
+func encodeWriteRequest(samples []Sample, now time.Time) []byte {
+    var request []byte
+    for _, s := range samples {
+        labels := append([]Label{{"__name__", s.Name}}, s.Labels...)
+        sort.Slice(labels, func(i, j int) bool { return labels[i].Name < labels[j].Name })
+
+        var series []byte
+        for _, l := range labels {
+            var label []byte
+            label = appendBytes(label, 1, []byte(l.Name))
+            label = appendBytes(label, 2, []byte(l.Value))
+            series = appendBytes(series, 1, label)
+        }
+
+        var sample []byte
+        sample = appendVarint(sample, 1 << 3 | 1)
+        sample = binary.LittleEndian.AppendUint64(sample, math.Float64bits(s.Value))
+        sample = appendVarint(sample, 2 << 3 | 0)
+        sample = appendVarint(sample, uint64(now.UnixMilli()))
+        series = appendBytes(series, 2, sample)
+
+        request = appendBytes(request, 1, series)
+    }
+    return request
+}
+
+// snappyEncode encodes data in the Snappy block format without compressing it, as a sequence of literals. This is
+// valid input for any Snappy decoder, and the requests are small enough that compression would gain little.
+func snappyEncode(data []byte) []byte {
+    b := appendVarint(nil, uint64(len(data)))
+    for len(data) != 0 {
+        n := len(data)
+        if n > 65536 {
+            n = 65536
+        }
+
+        // Literal tags store the length minus one, inline if it is less than 60 and in one or two bytes otherwise.
+        switch l := n - 1; {
+        case l < 60:
+            b = append(b, byte(l << 2))
+        case l < 256:
+            b = append(b, 60 << 2, byte(l))
+        default:
+            b = append(b, 61 << 2, byte(l), byte(l >> 8))
+        }
+        b = append(b, data[:n]...)
+        data = data[n:]
+    }
+    return b
+}
@@ -0,0 +1,76 @@
+package metrics
+
+import (
+    "bytes"
+    "net"
+    "strconv"
+    "strings"
+)
+
+// maxDatagramSize bounds the size of the UDP datagrams sent to StatsD so that they are not fragmented.
+const maxDatagramSize = 1432
+
+// statsdNameEscaper replaces the characters that have special meaning in the StatsD line protocol.
+var statsdNameEscaper = strings.NewReplacer(":", "_", "|", "_", "@", "_", "#", "_", ",", "_", " ", "_", "\n", "_")
+
+// StatsD sends samples as gauges to a StatsD or DogStatsD agent over UDP. DogStatsD agents receive labels as tags;
+// plain StatsD has no tags, so the label values are instead appended to the metric's name, separated by dots.
+type StatsD struct {
+    Address string // the host and port of the agent, e.g. "localhost:8125"
+    Prefix string // prepended to the name of each metric, e.g. "ci."
+    DogStatsD bool // true to send labels as DogStatsD tags
+    Tags []string // additional DogStatsD tags to send with each sample, e.g. "env:prod"
+}
+
+// line formats the given sample as a StatsD gauge.
+func (s *StatsD) line(sample Sample) string {
+    name := s.Prefix + sample.Name
+    var tags []string
+    for _, l := range sample.Labels {
+        if s.DogStatsD {
+            tags = append(tags, statsdNameEscaper.Replace(l.Name) + ":" + statsdNameEscaper.Replace(l.Value))
+        } else {
+            name += "." + strings.ReplaceAll(statsdNameEscaper.Replace(l.Value), ".", "_")
+        }
+    }
+
+    line := statsdNameEscaper.Replace(name) + ":" + strconv.FormatFloat(sample.Value, 'f', -1, 64) + "|g"
+    if s.DogStatsD {
+        if tags = append(tags, s.Tags...); len(tags) != 0 {
+            line += "|#" + strings.Join(tags, ",")
+        }
+    }
+    return line
+}
+
+// Publish sends the given samples to the agent, packing as many as fit into each datagram.
+func (s *StatsD) Publish(samples []Sample) error {
+    conn, err := net.Dial("udp", s.Address)
+    if err != nil {
+        return err
+    }
+    defer conn.Close()
+
+    b := new(bytes.Buffer)
+    flush := func() error {
+        if b.Len() == 0 {
+            return nil
+        }
+        _, err := conn.Write(b.Bytes())
+        b.Reset()
+        return err
+    }
+    for _, sample := range samples {
+        line := s.line(sample)
+        if b.Len() != 0 && b.Len() + 1 + len(line) > maxDatagramSize {
+            if err := flush(); err != nil {
+                return err
+            }
+        }
+        if b.Len() != 0 {
+            b.WriteByte('\n')
+        }
+        b.WriteString(line)
+    }
+    return flush()
+}
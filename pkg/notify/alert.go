@@ -0,0 +1,224 @@
+package notify
+
+import (
+    "errors"
+    "fmt"
+    "log/slog"
+    "net/http"
+    "regexp"
+    "sync"
+    "time"
+
+    "github.com/pgavlin/jitdash/pkg/jenkins"
+)
+
+// An Incident describes a job that has been failing for long enough to alert on.
+type Incident struct {
+    Key string // identifies the job's incident to the incident service so that repeated triggers are deduplicated
+    Summary string
+    Instance string
+    Job *jenkins.Job
+    Builds int // the number of consecutive failed builds
+    Since time.Time // the start time of the first failed build
+}
+
+// An IncidentService opens and resolves incidents.
+type IncidentService interface {
+    Trigger(incident *Incident) error
+    Resolve(key string) error
+}
+
+// An AlertRule opens an incident for each job it selects that has failed for at least Builds consecutive builds or for
+// at least For, whichever comes first. A threshold of zero is ignored.
+type AlertRule struct {
+    Jobs *regexp.Regexp // matched against each job's label; nil selects every job
+    Instances []string // the names of the instances whose jobs the rule selects, or nil for every instance
+    Builds int
+    For time.Duration
+    Service IncidentService
+}
+
+// Selects returns true if the rule applies to the given job of the given instance.
+func (r *AlertRule) Selects(instance string, job *jenkins.Job) bool {
+    if len(r.Instances) != 0 {
+        found := false
+        for _, i := range r.Instances {
+            found = found || i == instance
+        }
+        if !found {
+            return false
+        }
+    }
+    return r.Jobs == nil || r.Jobs.MatchString(job.Label())
+}
+
+// Sustained returns true if the given job has failed for long enough to alert on.
+func (r *AlertRule) Sustained(job *jenkins.Job, now time.Time) bool {
+    since, failing := job.BrokenSince()
+    if !failing {
+        return false
+    }
+    return r.Builds > 0 && job.FailureStreak() >= r.Builds || r.For > 0 && now.Sub(since) >= r.For
+}
+
+// An Alerter evaluates alert rules against the state of each job, triggering an incident when a job's failures are
+// sustained and resolving it once the job passes again. The first rule that selects a job applies to it. An Alerter
+// remembers the incidents that it has opened so that it only sends each trigger and resolution once; incidents that
+// were opened by a previous process are deduplicated by the incident service but are not resolved.
+type Alerter struct {
+    Rules []*AlertRule
+
+    m sync.Mutex
+    open map[string]IncidentService // the services of the open incidents, by key
+}
+
+// Adopt takes over the open incidents of a previous alerter, e.g. one for a configuration that has since been reloaded,
+// so that they are resolved by the services that opened them.
+func (a *Alerter) Adopt(previous *Alerter) {
+    previous.m.Lock()
+    defer previous.m.Unlock()
+    a.m.Lock()
+    defer a.m.Unlock()
+
+    if a.open == nil {
+        a.open = make(map[string]IncidentService)
+    }
+    for k, s := range previous.open {
+        a.open[k] = s
+    }
+}
+
+// Evaluate triggers and resolves incidents for the jobs of the given instances. Failures to reach an incident service
+// are logged and retried by the next evaluation.
+func (a *Alerter) Evaluate(instances []*jenkins.InstanceJobs) {
+    a.m.Lock()
+    defer a.m.Unlock()
+    if a.open == nil {
+        a.open = make(map[string]IncidentService)
+    }
+
+    now := time.Now()
+    for _, ij := range instances {
+        for _, job := range ij.Jobs {
+            if job.Duplicate || job.LastCompletedBuild() == nil {
+                continue
+            }
+            key := "jitdash:" + ij.Instance.Name + ":" + job.Url
+
+            if !job.Failing() {
+                if service, ok := a.open[key]; ok {
+                    if err := service.Resolve(key); err != nil {
+                        slog.Warn("could not resolve incident", "key", key, "err", err)
+                        continue
+                    }
+                    delete(a.open, key)
+                }
+                continue
+            }
+            if _, ok := a.open[key]; ok {
+                continue
+            }
+
+            for _, r := range a.Rules {
+                if !r.Selects(ij.Instance.Name, job) {
+                    continue
+                }
+                if r.Sustained(job, now) {
+                    since, _ := job.BrokenSince()
+                    incident := &Incident{Key: key, Instance: ij.Instance.Name, Job: job, Builds: job.FailureStreak(), Since: since}
+                    incident.Summary = fmt.Sprintf("%s/%s has failed %d consecutive builds since %s", ij.Instance.Name, job.Label(),
+                        incident.Builds, since.Format(time.RFC3339))
+                    if err := r.Service.Trigger(incident); err != nil {
+                        slog.Warn("could not trigger incident", "key", key, "err", err)
+                        break
+                    }
+                    a.open[key] = r.Service
+                }
+                break
+            }
+        }
+    }
+}
+
+// ParseAlerts parses the "alerts" section of the given configuration object, a list of rules:
+//
+//     "alerts": [
+//         {"jobs": "^deploy-", "instances": ["prod"], "builds": 3, "for": "1h",
+//          "pagerduty": {"routingKey": "...", "severity": "critical"}},
+//         {"builds": 5, "opsgenie": {"apiKey": "...", "priority": "P3"}}
+//     ]
+//
+// Each rule names one incident service. If there is no such section, ParseAlerts returns nil.
+func ParseAlerts(config jenkins.JsonObject) (*Alerter, error) {
+    alertsArray, ok := config.GetArray("alerts")
+    if !ok {
+        return nil, nil
+    }
+
+    client := &http.Client{Timeout: 10 * time.Second}
+    a := &Alerter{}
+    for n, v := range alertsArray {
+        o, ok := jenkins.AsJsonObject(v)
+        if !ok {
+            return nil, errors.New(fmt.Sprintf("alert rule %d is not an object", n))
+        }
+
+        r := &AlertRule{}
+        if jobs, ok := o.GetString("jobs"); ok {
+            re, err := regexp.Compile(jobs)
+            if err != nil {
+                return nil, errors.New(fmt.Sprintf("alert rule %d has an invalid job pattern: %s", n, err))
+            }
+            r.Jobs = re
+        }
+        instancesArray, _ := o.GetArray("instances")
+        for _, i := range instancesArray {
+            name, ok := i.(string)
+            if !ok {
+                return nil, errors.New(fmt.Sprintf("alert rule %d has an instance %v that is not a string", n, i))
+            }
+            r.Instances = append(r.Instances, name)
+        }
+
+        builds, _ := o.GetInt64("builds")
+        r.Builds = int(builds)
+        if f, ok := o.GetString("for"); ok {
+            d, err := time.ParseDuration(f)
+            if err != nil {
+                return nil, errors.New(fmt.Sprintf("alert rule %d has an invalid duration: %s", n, err))
+            }
+            r.For = d
+        }
+        if r.Builds <= 0 && r.For <= 0 {
+            return nil, errors.New(fmt.Sprintf("alert rule %d needs a number of builds or a duration", n))
+        }
+
+        pagerDuty, isPagerDuty := o.GetObject("pagerduty")
+        opsgenie, isOpsgenie := o.GetObject("opsgenie")
+        switch {
+        case isPagerDuty && isOpsgenie:
+            return nil, errors.New(fmt.Sprintf("alert rule %d names more than one incident service", n))
+        case isPagerDuty:
+            p := &PagerDuty{Client: client}
+            if p.RoutingKey, ok = pagerDuty.GetString("routingKey"); !ok {
+                return nil, errors.New(fmt.Sprintf("alert rule %d has no PagerDuty routing key", n))
+            }
+            p.Url, _ = pagerDuty.GetString("url")
+            p.Severity, _ = pagerDuty.GetString("severity")
+            r.Service = p
+        case isOpsgenie:
+            g := &Opsgenie{Client: client}
+            if g.ApiKey, ok = opsgenie.GetString("apiKey"); !ok {
+                return nil, errors.New(fmt.Sprintf("alert rule %d has no Opsgenie API key", n))
+            }
+            g.Url, _ = opsgenie.GetString("url")
+            g.Priority, _ = opsgenie.GetString("priority")
+            r.Service = g
+        default:
+            return nil, errors.New(fmt.Sprintf("alert rule %d has no incident service", n))
+        }
+
+        a.Rules = append(a.Rules, r)
+    }
+    return a, nil
+}
@@ -0,0 +1,194 @@
+// Package notify alerts chat and incident tools when jobs break, recover, or change their failure counts. Notifiers are
+// configured by the "notify" section of the jitdash configuration, a list of destinations:
+//
+//     "notify": [
+//         {"type": "teams", "url": "https://example.webhook.office.com/..."},
+//         {"type": "webhook", "url": "https://example.com/hook", "kinds": ["broken", "fixed"],
+//          "headers": {"Authorization": "Bearer ..."}, "template": "{\"text\": {{json .Summary}}}"}
+//     ]
+//
+// Microsoft Teams destinations receive an Adaptive Card that lists the changes. Generic webhooks receive a JSON
+// payload, which may be customized with a Go text/template; see Webhook. Each destination may limit the kinds of
+// changes it is sent to some of "broken", "fixed", and "failures".
+//
+// Destinations may also throttle their notifications. During "quietHours", e.g. {"from": "22:00", "to": "07:00"} in
+// the config's timezone, nothing is sent. A "cooldown" duration limits each job to one notification per cooldown, and
+// "maxFlips" suppresses notifications about jobs that broke or recovered more than that many times in the past day.
+//
+// Separately, the "alerts" section opens PagerDuty or Opsgenie incidents for jobs whose failures are sustained, and
+// resolves them when the jobs recover; see ParseAlerts.
+package notify
+
+import (
+    "bytes"
+    "errors"
+    "fmt"
+    "io"
+    "log/slog"
+    "net/http"
+    "time"
+
+    "github.com/pgavlin/jitdash/pkg/jenkins"
+)
+
+// A Notifier sends a notification about a set of job changes.
+type Notifier interface {
+    Notify(changes []*jenkins.JobChange) error
+}
+
+// ParseConfig parses the "notify" section of the given configuration object. If there is no such section, ParseConfig
+// returns no notifiers.
+func ParseConfig(config jenkins.JsonObject) ([]Notifier, error) {
+    notifyArray, _ := config.GetArray("notify")
+
+    location := time.UTC
+    if tz, ok := config.GetString("timezone"); ok {
+        l, err := time.LoadLocation(tz)
+        if err != nil {
+            return nil, errors.New(fmt.Sprintf("invalid timezone %s: %s", tz, err))
+        }
+        location = l
+    }
+
+    var notifiers []Notifier
+    for n, v := range notifyArray {
+        o, ok := jenkins.AsJsonObject(v)
+        if !ok {
+            return nil, errors.New(fmt.Sprintf("notifier %d is not an object", n))
+        }
+
+        url, ok := o.GetString("url")
+        if !ok {
+            return nil, errors.New(fmt.Sprintf("notifier %d has no url", n))
+        }
+        headers := make(map[string]string)
+        headersObject, _ := o.GetObject("headers")
+        for k := range headersObject {
+            v, ok := headersObject.GetString(k)
+            if !ok {
+                return nil, errors.New(fmt.Sprintf("notifier %d has a header %s that is not a string", n, k))
+            }
+            headers[k] = v
+        }
+        client := &http.Client{Timeout: 10 * time.Second}
+
+        var notifier Notifier
+        switch kind, _ := o.GetString("type"); kind {
+        case "teams":
+            notifier = &Teams{Url: url, Headers: headers, Client: client}
+        case "webhook":
+            template, _ := o.GetString("template")
+            w, err := NewWebhook(url, template)
+            if err != nil {
+                return nil, errors.New(fmt.Sprintf("notifier %d has an invalid template: %s", n, err))
+            }
+            w.Headers, w.Client = headers, client
+            notifier = w
+        default:
+            return nil, errors.New(fmt.Sprintf("notifier %d has an unknown type %q", n, kind))
+        }
+
+        r := &rule{notifier: notifier}
+        if kindsArray, ok := o.GetArray("kinds"); ok {
+            kinds := make(map[jenkins.ChangeKind]bool)
+            for _, k := range kindsArray {
+                switch k {
+                case "broken":
+                    kinds[jenkins.ChangeBroken] = true
+                case "fixed":
+                    kinds[jenkins.ChangeFixed] = true
+                case "failures":
+                    kinds[jenkins.ChangeFailures] = true
+                default:
+                    return nil, errors.New(fmt.Sprintf("notifier %d has an unknown kind of change %v", n, k))
+                }
+            }
+            r.kinds = kinds
+        }
+        if err := r.parseThrottling(o, location); err != nil {
+            return nil, errors.New(fmt.Sprintf("notifier %d %s", n, err))
+        }
+
+        notifiers = append(notifiers, r)
+    }
+    return notifiers, nil
+}
+
+// Notify sends the given changes to each of the given notifiers. Failures are logged.
+func Notify(notifiers []Notifier, changes []*jenkins.JobChange) {
+    if len(changes) == 0 {
+        return
+    }
+    for _, n := range notifiers {
+        if err := n.Notify(changes); err != nil {
+            slog.Warn("could not send notification", "err", err)
+        }
+    }
+}
+
+// describe describes the state of the given build.
+func describe(b *jenkins.Build) string {
+    switch {
+    case b == nil || !b.Failed():
+        return "passing"
+    case b.Failures == -1 && b.Result == jenkins.ResultUnstable:
+        return "unstable"
+    case b.Failures == -1:
+        return "failed"
+    case b.Failures == 1:
+        return "1 failure"
+    default:
+        return fmt.Sprintf("%d failures", b.Failures)
+    }
+}
+
+// Summary summarizes the given changes, e.g. "2 jobs broke, 1 job recovered".
+func Summary(changes []*jenkins.JobChange) string {
+    var counts [3]int
+    for _, c := range changes {
+        counts[c.Kind]++
+    }
+
+    jobs := func(n int) string {
+        if n == 1 {
+            return "1 job"
+        }
+        return fmt.Sprintf("%d jobs", n)
+    }
+
+    var summary string
+    for kind, verb := range []string{"broke", "recovered", "changed failure counts"} {
+        if counts[kind] == 0 {
+            continue
+        }
+        if summary != "" {
+            summary += ", "
+        }
+        summary += jobs(counts[kind]) + " " + verb
+    }
+    return summary
+}
+
+// post sends the given body to the given URL.
+func post(client *http.Client, url, contentType string, headers map[string]string, body []byte) error {
+    req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+    if err != nil {
+        return err
+    }
+    req.Header.Set("Content-Type", contentType)
+    for k, v := range headers {
+        req.Header.Set(k, v)
+    }
+
+    resp, err := client.Do(req)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+    io.Copy(io.Discard, resp.Body)
+
+    if resp.StatusCode / 100 != 2 {
+        return errors.New(fmt.Sprintf("%s: %s", url, resp.Status))
+    }
+    return nil
+}
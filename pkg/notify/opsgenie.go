@@ -0,0 +1,61 @@
+package notify
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/url"
+    "strconv"
+)
+
+// Opsgenie opens and closes alerts with the Opsgenie Alert API. Incident keys are used as the alerts' aliases, which
+// Opsgenie uses to deduplicate them.
+type Opsgenie struct {
+    ApiKey string
+    Url string // the API's base URL, or empty for https://api.opsgenie.com; EU accounts use https://api.eu.opsgenie.com
+    Priority string // P1 through P5; empty means P3
+    Client *http.Client
+}
+
+// send posts a request to the given path of the API.
+func (g *Opsgenie) send(path string, request map[string]interface{}) error {
+    base := g.Url
+    if base == "" {
+        base = "https://api.opsgenie.com"
+    }
+
+    body, err := json.Marshal(request)
+    if err != nil {
+        return err
+    }
+    return post(g.Client, base + path, "application/json", map[string]string{"Authorization": "GenieKey " + g.ApiKey}, body)
+}
+
+func (g *Opsgenie) Trigger(incident *Incident) error {
+    // Opsgenie truncates messages to 130 characters; the description carries the rest.
+    message := incident.Summary
+    if len(message) > 130 {
+        message = message[:127] + "..."
+    }
+    last := incident.Job.LastCompletedBuild()
+
+    request := map[string]interface{}{
+        "message": message,
+        "alias": incident.Key,
+        "description": incident.Summary + "\n\nJob: " + incident.Job.Url + "\nLast build: " + last.Url,
+        "source": "jitdash",
+        "entity": incident.Instance,
+        "details": map[string]string{
+            "job": incident.Job.Label(),
+            "failedBuilds": strconv.Itoa(incident.Builds),
+            "lastBuild": describe(last),
+        },
+    }
+    if g.Priority != "" {
+        request["priority"] = g.Priority
+    }
+    return g.send("/v2/alerts", request)
+}
+
+func (g *Opsgenie) Resolve(key string) error {
+    return g.send("/v2/alerts/" + url.PathEscape(key) + "/close?identifierType=alias", map[string]interface{}{"source": "jitdash"})
+}
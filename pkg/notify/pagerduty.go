@@ -0,0 +1,61 @@
+package notify
+
+import (
+    "encoding/json"
+    "net/http"
+    "time"
+)
+
+// PagerDuty opens and resolves incidents with the PagerDuty Events API v2. Incidents are deduplicated by their keys.
+type PagerDuty struct {
+    RoutingKey string // the integration key of the PagerDuty service
+    Url string // the Events API endpoint, or empty for https://events.pagerduty.com/v2/enqueue
+    Severity string // critical, error, warning, or info; empty means error
+    Client *http.Client
+}
+
+// send sends an event to the Events API.
+func (p *PagerDuty) send(event map[string]interface{}) error {
+    url := p.Url
+    if url == "" {
+        url = "https://events.pagerduty.com/v2/enqueue"
+    }
+    event["routing_key"] = p.RoutingKey
+
+    body, err := json.Marshal(event)
+    if err != nil {
+        return err
+    }
+    return post(p.Client, url, "application/json", nil, body)
+}
+
+func (p *PagerDuty) Trigger(incident *Incident) error {
+    severity := p.Severity
+    if severity == "" {
+        severity = "error"
+    }
+    last := incident.Job.LastCompletedBuild()
+    return p.send(map[string]interface{}{
+        "event_action": "trigger",
+        "dedup_key": incident.Key,
+        "payload": map[string]interface{}{
+            "summary": incident.Summary,
+            "source": incident.Instance,
+            "severity": severity,
+            "component": incident.Job.Label(),
+            "timestamp": incident.Since.Format(time.RFC3339),
+            "custom_details": map[string]interface{}{
+                "failedBuilds": incident.Builds,
+                "lastBuild": describe(last),
+            },
+        },
+        "links": []interface{}{
+            map[string]interface{}{"href": incident.Job.Url, "text": incident.Job.Label()},
+            map[string]interface{}{"href": last.Url, "text": "Last build"},
+        },
+    })
+}
+
+func (p *PagerDuty) Resolve(key string) error {
+    return p.send(map[string]interface{}{"event_action": "resolve", "dedup_key": key})
+}
@@ -0,0 +1,146 @@
+package notify
+
+import (
+    "errors"
+    "fmt"
+    "sync"
+    "time"
+
+    "github.com/pgavlin/jitdash/pkg/jenkins"
+)
+
+// flapWindow is the period over which a job's flips between passing and failing are counted.
+const flapWindow = 24 * time.Hour
+
+// A rule passes on changes to a notifier, filtering them by kind and throttling them. A rule keeps track of the
+// notifications it has sent and of each job's recent flips in memory, so its throttling starts afresh when the
+// configuration is reloaded.
+type rule struct {
+    notifier Notifier
+    kinds map[jenkins.ChangeKind]bool // the kinds of changes to send, or nil for every kind
+
+    quietFrom, quietTo int // the start and end of the quiet hours, in minutes since midnight; equal if there are none
+    location *time.Location // the timezone of the quiet hours
+    cooldown time.Duration // the minimum time between notifications about a job
+    maxFlips int // the number of flips per flapWindow above which a job is considered flapping, or 0 to ignore flips
+
+    m sync.Mutex
+    lastSent map[string]time.Time // the time of the last notification about each job
+    flips map[string][]time.Time // the times at which each job broke or recovered within the last flapWindow
+}
+
+// parseClock parses a time of day of the form "15:04" and returns it in minutes since midnight.
+func parseClock(s string) (int, error) {
+    t, err := time.Parse("15:04", s)
+    if err != nil {
+        return 0, errors.New(fmt.Sprintf("invalid time of day %q", s))
+    }
+    return t.Hour() * 60 + t.Minute(), nil
+}
+
+// parseThrottling parses the rule's quiet hours, cooldown, and flap suppression from its configuration. Quiet hours
+// are in the given timezone unless they specify their own.
+func (r *rule) parseThrottling(o jenkins.JsonObject, location *time.Location) error {
+    r.location = location
+    if quietHours, ok := o.GetObject("quietHours"); ok {
+        from, _ := quietHours.GetString("from")
+        to, _ := quietHours.GetString("to")
+        var err error
+        if r.quietFrom, err = parseClock(from); err != nil {
+            return errors.New(fmt.Sprintf("has invalid quiet hours: %s", err))
+        }
+        if r.quietTo, err = parseClock(to); err != nil {
+            return errors.New(fmt.Sprintf("has invalid quiet hours: %s", err))
+        }
+        if tz, ok := quietHours.GetString("timezone"); ok {
+            if r.location, err = time.LoadLocation(tz); err != nil {
+                return errors.New(fmt.Sprintf("has an invalid timezone %s: %s", tz, err))
+            }
+        }
+    }
+
+    if cooldown, ok := o.GetString("cooldown"); ok {
+        d, err := time.ParseDuration(cooldown)
+        if err != nil {
+            return errors.New(fmt.Sprintf("has an invalid cooldown: %s", err))
+        }
+        r.cooldown = d
+    }
+
+    maxFlips, _ := o.GetInt64("maxFlips")
+    if maxFlips < 0 {
+        return errors.New(fmt.Sprintf("has an invalid maxFlips %d", maxFlips))
+    }
+    r.maxFlips = int(maxFlips)
+    return nil
+}
+
+// quiet returns true if the given time falls within the rule's quiet hours. Quiet hours may span midnight.
+func (r *rule) quiet(t time.Time) bool {
+    if r.quietFrom == r.quietTo {
+        return false
+    }
+    t = t.In(r.location)
+    m := t.Hour() * 60 + t.Minute()
+    if r.quietFrom < r.quietTo {
+        return m >= r.quietFrom && m < r.quietTo
+    }
+    return m >= r.quietFrom || m < r.quietTo
+}
+
+// Notify records the flips among the given changes and then sends those changes that pass the rule's filters.
+// Changes that are suppressed are dropped rather than sent later.
+func (r *rule) Notify(changes []*jenkins.JobChange) error {
+    r.m.Lock()
+    if r.lastSent == nil {
+        r.lastSent, r.flips = make(map[string]time.Time), make(map[string][]time.Time)
+    }
+
+    now := time.Now()
+    for key, flips := range r.flips {
+        for len(flips) != 0 && now.Sub(flips[0]) > flapWindow {
+            flips = flips[1:]
+        }
+        if len(flips) == 0 {
+            delete(r.flips, key)
+        } else {
+            r.flips[key] = flips
+        }
+    }
+    for key, t := range r.lastSent {
+        if now.Sub(t) >= r.cooldown {
+            delete(r.lastSent, key)
+        }
+    }
+
+    var send []*jenkins.JobChange
+    for _, c := range changes {
+        key := c.Instance + ":" + c.Job.Url
+        if c.Kind != jenkins.ChangeFailures {
+            r.flips[key] = append(r.flips[key], now)
+        }
+
+        if r.kinds != nil && !r.kinds[c.Kind] {
+            continue
+        }
+        if r.maxFlips > 0 && len(r.flips[key]) > r.maxFlips {
+            continue
+        }
+        if _, ok := r.lastSent[key]; ok {
+            continue
+        }
+        send = append(send, c)
+    }
+    if len(send) == 0 || r.quiet(now) {
+        r.m.Unlock()
+        return nil
+    }
+    if r.cooldown > 0 {
+        for _, c := range send {
+            r.lastSent[c.Instance + ":" + c.Job.Url] = now
+        }
+    }
+    r.m.Unlock()
+
+    return r.notifier.Notify(send)
+}
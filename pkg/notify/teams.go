@@ -0,0 +1,57 @@
+package notify
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+
+    "github.com/pgavlin/jitdash/pkg/jenkins"
+)
+
+// Teams posts changes to a Microsoft Teams incoming webhook as an Adaptive Card with a line per change. Broken jobs are
+// highlighted in red and recovered jobs in green.
+type Teams struct {
+    Url string
+    Headers map[string]string
+    Client *http.Client
+}
+
+// cardColors maps kinds of changes to Adaptive Card text colors.
+var cardColors = map[jenkins.ChangeKind]string{
+    jenkins.ChangeBroken: "Attention",
+    jenkins.ChangeFixed: "Good",
+    jenkins.ChangeFailures: "Warning",
+}
+
+func (t *Teams) Notify(changes []*jenkins.JobChange) error {
+    body := []interface{}{
+        map[string]interface{}{"type": "TextBlock", "text": "jitdash: " + Summary(changes), "weight": "Bolder", "size": "Medium", "wrap": true},
+    }
+    for _, c := range changes {
+        status := describe(c.After)
+        if c.Kind == jenkins.ChangeFailures {
+            status = describe(c.Before) + " → " + status
+        }
+        text := fmt.Sprintf("[%s/%s #%d](%s): %s", c.Instance, c.Job.Label(), c.After.Id, c.After.Url, status)
+        body = append(body, map[string]interface{}{"type": "TextBlock", "text": text, "color": cardColors[c.Kind], "wrap": true})
+    }
+
+    payload, err := json.Marshal(map[string]interface{}{
+        "type": "message",
+        "attachments": []interface{}{
+            map[string]interface{}{
+                "contentType": "application/vnd.microsoft.card.adaptive",
+                "content": map[string]interface{}{
+                    "$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+                    "type": "AdaptiveCard",
+                    "version": "1.4",
+                    "body": body,
+                },
+            },
+        },
+    })
+    if err != nil {
+        return err
+    }
+    return post(t.Client, t.Url, "application/json", t.Headers, payload)
+}
@@ -0,0 +1,72 @@
+package notify
+
+import (
+    "bytes"
+    "encoding/json"
+    "net/http"
+    "text/template"
+
+    "github.com/pgavlin/jitdash/pkg/jenkins"
+)
+
+// defaultWebhookTemplate is the payload sent to webhooks that do not specify their own template.
+const defaultWebhookTemplate = `{"summary": {{json .Summary}}, "changes": {{json .Changes}}}`
+
+// Webhook posts changes to a URL as JSON. The payload is produced by a text/template that is executed with a
+// WebhookData and may use the "json" function to encode values.
+type Webhook struct {
+    Url string
+    Headers map[string]string
+    Client *http.Client
+    Template *template.Template
+}
+
+// WebhookData is the data with which a webhook's template is executed.
+type WebhookData struct {
+    Summary string // a summary of the changes; see Summary
+    Changes []WebhookChange
+}
+
+// A WebhookChange describes a change to a job.
+type WebhookChange struct {
+    Kind string `json:"kind"` // broken, fixed, or failures
+    Instance string `json:"instance"`
+    Job string `json:"job"`
+    JobUrl string `json:"jobUrl"`
+    Build int64 `json:"build"`
+    BuildUrl string `json:"buildUrl"`
+    Status string `json:"status"` // e.g. "3 failures" or "passing"
+    PreviousStatus string `json:"previousStatus"`
+}
+
+// NewWebhook creates a webhook notifier for the given URL. If the template is empty, the payload is a JSON object with
+// the summary and the list of changes.
+func NewWebhook(url, text string) (*Webhook, error) {
+    if text == "" {
+        text = defaultWebhookTemplate
+    }
+    t, err := template.New("webhook").Funcs(template.FuncMap{
+        "json": func(v interface{}) (string, error) {
+            b, err := json.Marshal(v)
+            return string(b), err
+        },
+    }).Parse(text)
+    if err != nil {
+        return nil, err
+    }
+    return &Webhook{Url: url, Client: http.DefaultClient, Template: t}, nil
+}
+
+func (w *Webhook) Notify(changes []*jenkins.JobChange) error {
+    data := WebhookData{Summary: Summary(changes)}
+    for _, c := range changes {
+        data.Changes = append(data.Changes, WebhookChange{c.Kind.String(), c.Instance, c.Job.Label(), c.Job.Url, c.After.Id, c.After.Url,
+            describe(c.After), describe(c.Before)})
+    }
+
+    b := new(bytes.Buffer)
+    if err := w.Template.Execute(b, data); err != nil {
+        return err
+    }
+    return post(w.Client, w.Url, "application/json", w.Headers, b.Bytes())
+}
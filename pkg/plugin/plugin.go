@@ -0,0 +1,292 @@
+// Package plugin implements a jitdash backend that fetches jobs and builds by running a source plugin: an executable
+// that speaks a JSON protocol over its standard input and output. Plugins let jitdash show builds from CI systems it
+// does not support, such as in-house ones, without changes to jitdash itself.
+//
+// Importing this package registers the backend for instances of type "exec":
+//
+//     "my-ci": {
+//         "type": "exec",
+//         "command": ["/usr/local/bin/my-ci-source", "--project", "widgets"],
+//         "env": {"MY_CI_TOKEN": "..."},
+//         "settings": {"branch": "main"},
+//         "timeout": "30s"
+//     }
+//
+// The plugin is run once for each request, with the request as a JSON object on its standard input, and must write a
+// JSON object to its standard output and exit with status zero. A plugin that exits with another status fails the
+// request, and the end of its standard error is reported as the error. Each request carries the instance's name and
+// the instance's "settings" object, which jitdash does not interpret:
+//
+//     {"method": "fetchJobs", "instance": "my-ci", "settings": {"branch": "main"}}
+//
+// is answered with the instance's jobs and their most recent builds, along with any errors that affected only some
+// jobs:
+//
+//     {
+//         "jobs": [{
+//             "name": "build",
+//             "url": "https://ci.example.com/build",
+//             "builds": [{"id": 42, "url": "...", "backendId": "b-42"}]
+//         }],
+//         "errors": [{"job": "deploy", "url": "https://ci.example.com/deploy", "message": "forbidden"}]
+//     }
+//
+// Jobs may also have a "displayName", a "group", and "queued" and "queuedWhy" properties. The details of each build
+// are then requested separately:
+//
+//     {
+//         "method": "fetchDetails", "instance": "my-ci", "settings": {...},
+//         "build": {"id": 42, "url": "...", "backendId": "b-42"}
+//     }
+//
+// which is answered with the build's details, or with {"error": "..."} if they cannot be fetched:
+//
+//     {
+//         "build": {
+//             "complete": true, "result": "UNSTABLE", "timestamp": "2017-10-15T10:10:10Z", "duration": 120.5,
+//             "failures": 2, "tests": 300, "skipped": 4, "failedTests": ["TestA", "TestB"],
+//             "commit": "abc123", "change": "Fix the widget", "culprits": ["alice"], "failedStage": "Test"
+//         }
+//     }
+//
+// Results are Jenkins result names (SUCCESS, UNSTABLE, FAILURE, NOT_BUILT, or ABORTED) and durations are in seconds.
+// Builds may also have an "estimatedDuration" and a "consoleTail". All properties but "complete" are optional.
+package plugin
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "os"
+    "os/exec"
+    "sort"
+    "strings"
+    "time"
+
+    "github.com/pgavlin/jitdash/pkg/jenkins"
+)
+
+// stderrLimit is the number of bytes at the end of a failed plugin's standard error that are reported as its error.
+const stderrLimit = 512
+
+func init() {
+    jenkins.RegisterBackend("exec", newBackend)
+}
+
+type backend struct {
+    command []string
+    env []string // the plugin's environment in addition to jitdash's own
+    settings jenkins.JsonObject
+    timeout time.Duration // how long a request may take, or zero for no limit
+}
+
+func newBackend(i *jenkins.Instance, config jenkins.JsonObject) (jenkins.Backend, error) {
+    b := &backend{timeout: time.Minute}
+
+    commandArray, ok := config.GetArray("command")
+    if !ok || len(commandArray) == 0 {
+        return nil, errors.New("no command")
+    }
+    for _, a := range commandArray {
+        arg, ok := a.(string)
+        if !ok {
+            return nil, errors.New(fmt.Sprintf("invalid command argument: %v", a))
+        }
+        b.command = append(b.command, arg)
+    }
+
+    envObject, _ := config.GetObject("env")
+    for k := range envObject {
+        value, ok := envObject.GetString(k)
+        if !ok {
+            return nil, errors.New(fmt.Sprintf("invalid value for environment variable %s", k))
+        }
+        b.env = append(b.env, k + "=" + value)
+    }
+    sort.Strings(b.env)
+
+    b.settings, _ = config.GetObject("settings")
+
+    // The instance's timeout has already been validated.
+    if timeout, ok := config.GetString("timeout"); ok {
+        b.timeout, _ = time.ParseDuration(timeout)
+    }
+
+    return b, nil
+}
+
+// A request is a request to a plugin.
+type request struct {
+    Method string `json:"method"`
+    Instance string `json:"instance"`
+    Settings jenkins.JsonObject `json:"settings,omitempty"`
+    Build *buildRef `json:"build,omitempty"`
+}
+
+// A buildRef identifies a build to a plugin.
+type buildRef struct {
+    Id int64 `json:"id"`
+    Url string `json:"url"`
+    BackendId string `json:"backendId,omitempty"`
+}
+
+type jobsResponse struct {
+    Jobs []struct {
+        Name string `json:"name"`
+        DisplayName string `json:"displayName"`
+        Url string `json:"url"`
+        Group string `json:"group"`
+        Queued bool `json:"queued"`
+        QueuedWhy string `json:"queuedWhy"`
+        Builds []buildRef `json:"builds"`
+    } `json:"jobs"`
+    Errors []struct {
+        Job string `json:"job"`
+        Url string `json:"url"`
+        Message string `json:"message"`
+    } `json:"errors"`
+}
+
+type detailsResponse struct {
+    Build *struct {
+        Complete bool `json:"complete"`
+        Result string `json:"result"`
+        Timestamp time.Time `json:"timestamp"`
+        Duration float64 `json:"duration"`
+        EstimatedDuration float64 `json:"estimatedDuration"`
+        Failures int64 `json:"failures"`
+        Tests int64 `json:"tests"`
+        Skipped int64 `json:"skipped"`
+        FailedTests []string `json:"failedTests"`
+        Commit string `json:"commit"`
+        Change string `json:"change"`
+        Culprits []string `json:"culprits"`
+        FailedStage string `json:"failedStage"`
+        ConsoleTail string `json:"consoleTail"`
+    } `json:"build"`
+    Error string `json:"error"`
+}
+
+// call runs the plugin with the given request and decodes its response into v.
+func (b *backend) call(i *jenkins.Instance, req *request, v interface{}) error {
+    req.Instance, req.Settings = i.Name, b.settings
+    input, err := json.Marshal(req)
+    if err != nil {
+        return err
+    }
+
+    ctx := context.Background()
+    if b.timeout != 0 {
+        var cancel context.CancelFunc
+        ctx, cancel = context.WithTimeout(ctx, b.timeout)
+        defer cancel()
+    }
+
+    var stdout, stderr bytes.Buffer
+    cmd := exec.CommandContext(ctx, b.command[0], b.command[1:]...)
+    cmd.Env = append(os.Environ(), b.env...)
+    cmd.Stdin = bytes.NewReader(input)
+    cmd.Stdout, cmd.Stderr = &stdout, &stderr
+    if err := cmd.Run(); err != nil {
+        if ctx.Err() != nil {
+            return errors.New(fmt.Sprintf("%s timed out after %v", req.Method, b.timeout))
+        }
+        message := strings.TrimSpace(stderr.String())
+        if len(message) > stderrLimit {
+            message = "..." + message[len(message) - stderrLimit:]
+        }
+        if message == "" {
+            return errors.New(fmt.Sprintf("%s: %s", req.Method, err))
+        }
+        return errors.New(fmt.Sprintf("%s: %s: %s", req.Method, err, message))
+    }
+
+    if err := json.Unmarshal(stdout.Bytes(), v); err != nil {
+        return errors.New(fmt.Sprintf("%s: invalid response: %s", req.Method, err))
+    }
+    return nil
+}
+
+// source returns a description of the plugin for fetch errors that are not tied to a URL.
+func (b *backend) source() string {
+    return "exec:" + b.command[0]
+}
+
+func (b *backend) FetchJobs(i *jenkins.Instance) ([]*jenkins.Job, []*jenkins.FetchError) {
+    var response jobsResponse
+    if err := b.call(i, &request{Method: "fetchJobs"}, &response); err != nil {
+        return nil, []*jenkins.FetchError{i.NewFetchError("", b.source(), err)}
+    }
+
+    var jobs []*jenkins.Job
+    for _, j := range response.Jobs {
+        if j.Name == "" || i.IsExcluded(j.Name) {
+            continue
+        }
+
+        var builds []*jenkins.Build
+        for _, br := range j.Builds {
+            builds = append(builds, &jenkins.Build{Id: br.Id, Url: br.Url, BackendId: br.BackendId})
+        }
+        sort.Sort(jenkins.BuildSorter(builds))
+
+        jobs = append(jobs, &jenkins.Job{
+            Name: j.Name,
+            DisplayName: j.DisplayName,
+            Url: j.Url,
+            Group: j.Group,
+            Builds: builds,
+            Queued: j.Queued,
+            QueuedWhy: j.QueuedWhy,
+        })
+    }
+
+    var errs []*jenkins.FetchError
+    for _, e := range response.Errors {
+        url := e.Url
+        if url == "" {
+            url = b.source()
+        }
+        errs = append(errs, i.NewFetchError(e.Job, url, errors.New(e.Message)))
+    }
+    return jobs, errs
+}
+
+func (b *backend) FetchDetails(i *jenkins.Instance, build *jenkins.Build) error {
+    req := &request{Method: "fetchDetails", Build: &buildRef{Id: build.Id, Url: build.Url, BackendId: build.BackendId}}
+
+    var response detailsResponse
+    if err := b.call(i, req, &response); err != nil {
+        return err
+    }
+    if response.Error != "" {
+        return errors.New(response.Error)
+    }
+    details := response.Build
+    if details == nil {
+        return errors.New("missing build")
+    }
+
+    build.Complete = details.Complete
+    if build.Complete {
+        build.Result = jenkins.ParseResult(details.Result)
+    }
+    build.Timestamp = details.Timestamp.UTC()
+    build.Duration = time.Duration(details.Duration * float64(time.Second))
+    build.EstimatedDuration = time.Duration(details.EstimatedDuration * float64(time.Second))
+    build.Failures = details.Failures
+    if build.Failures == 0 && build.Result == jenkins.ResultFailure {
+        build.Failures = -1
+    }
+    build.Tests = details.Tests
+    build.Skipped = details.Skipped
+    build.FailedTests = details.FailedTests
+    build.Commit = details.Commit
+    build.Change = details.Change
+    build.Culprits = details.Culprits
+    build.FailedStage = details.FailedStage
+    build.ConsoleTail = details.ConsoleTail
+    return nil
+}
@@ -0,0 +1,52 @@
+package render
+
+import (
+    "fmt"
+    "time"
+
+    "github.com/pgavlin/jitdash/pkg/jenkins"
+)
+
+// Humanize formats a duration using its two most significant units, e.g. "3d 4h" or "12m".
+func Humanize(d time.Duration) string {
+    switch {
+    case d < time.Minute:
+        return "<1m"
+    case d < time.Hour:
+        return fmt.Sprintf("%dm", int(d / time.Minute))
+    case d < 24 * time.Hour:
+        return fmt.Sprintf("%dh %dm", int(d / time.Hour), int(d % time.Hour / time.Minute))
+    default:
+        return fmt.Sprintf("%dd %dh", int(d / (24 * time.Hour)), int(d % (24 * time.Hour) / time.Hour))
+    }
+}
+
+// timeLayout is the layout of timestamps displayed in tooltips.
+const timeLayout = "2006-01-02 15:04 MST"
+
+// staleNote describes how out of date the jobs of an instance that could not be reached are, e.g. "stale since
+// 2024-05-01 09:30 UTC (2h 5m ago)". It returns the empty string for instances that are up to date.
+func staleNote(ij *jenkins.InstanceJobs, options Options) string {
+    if !ij.Stale {
+        return ""
+    }
+    return options.text("staleSince", ij.FetchedAt.In(options.location()).Format(timeLayout), Humanize(time.Since(ij.FetchedAt)))
+}
+
+// localTime formats the time at which the given build started in the given timezone. Builds that are not in the job's
+// fetched history are formatted as the empty string.
+func localTime(b *jenkins.Build, location *time.Location) string {
+    if b == nil || b.Timestamp.IsZero() {
+        return ""
+    }
+    return b.Timestamp.In(location).Format(timeLayout)
+}
+
+// since describes how long ago the given build finished. Builds that are not in the job's fetched history are
+// described as never having happened.
+func since(b *jenkins.Build, options Options) string {
+    if b == nil || b.Timestamp.IsZero() {
+        return options.text("never")
+    }
+    return options.text("ago", Humanize(time.Since(b.Finished())))
+}
@@ -0,0 +1,33 @@
+package render
+
+import (
+    "path"
+
+    "github.com/pgavlin/jitdash/pkg/jenkins"
+)
+
+// An artifactLink links to an artifact of a job's last successful build.
+type artifactLink struct {
+    Name string // the artifact's file name
+    Url string
+    Title string
+}
+
+// artifactLinks returns links to the shown artifacts of the job's last successful build, in the order in which Jenkins
+// lists them.
+func artifactLinks(i *jenkins.Instance, job *jenkins.Job, options Options) []artifactLink {
+    b := job.LastSuccess()
+    if b == nil {
+        return nil
+    }
+
+    var links []artifactLink
+    for _, a := range b.Artifacts {
+        links = append(links, artifactLink{
+            Name: path.Base(a),
+            Url: i.DisplayUrl(b.ArtifactUrl(a)),
+            Title: options.text("downloadArtifact", a, b.Id),
+        })
+    }
+    return links
+}
@@ -0,0 +1,199 @@
+package render
+
+// german is the catalog of German messages.
+var german = Messages{
+    "unknownBuild": "Unbekannt: Die Details des Builds konnten nicht abgerufen werden: %s",
+    "aborted": "Abgebrochen",
+    "notBuilt": "Nicht gebaut",
+    "passed": "Erfolgreich",
+    "passedTests": "Erfolgreich: %d Tests",
+    "failed": "Fehlgeschlagen",
+    "failures": "%d Fehler",
+    "testsFailed": "%d von %d Tests fehlgeschlagen",
+    "newFailures": "%s (%d neu)",
+    "inStage": "%s in %s",
+    "unstable": "Instabil: %s",
+    "unstableBuild": "Instabil",
+    "building": "läuft",
+    "buildingProgress": "Läuft: %d%%",
+    "remaining": "%s, noch etwa %s",
+    "overdue": "%s, dauert länger als erwartet",
+    "skipped": "%s, %d übersprungen",
+    "started": "Gestartet %s (vor %s)",
+    "knownFailure": "Bekannter Fehler: %s",
+    "buildLabel": "Build %d: %s",
+    "buildHistory": "Build-Verlauf",
+    "queued": "In der Warteschlange",
+    "queuedWhy": "In der Warteschlange: %s",
+    "downloadArtifact": "%s aus Build %d herunterladen",
+
+    "ago": "vor %s",
+    "never": "nie",
+    "staleSince": "veraltet seit %s (vor %s)",
+
+    "authFailures": "Authentifizierungsfehler",
+    "warnings": "Warnungen",
+    "error": "Fehler",
+    "warning": "Warnung",
+    "brokenBuilds": "Fehlgeschlagene Builds",
+    "failureCategories": "Fehlerkategorien",
+    "buildHealth": "Build-Zustand",
+    "agents": "Agenten",
+    "staleJobs": "Veraltete Jobs",
+    "other": "Sonstige",
+    "instance": "Instanz",
+    "job": "Job",
+    "jobs": "Jobs",
+    "history": "Verlauf",
+    "lastSuccess": "Letzter Erfolg",
+    "lastFailure": "Letzter Fehlschlag",
+    "firstFailure": "Erster Fehlschlag",
+    "culprits": "Verursacher",
+    "unknownCulprits": "unbekannt",
+    "category": "Kategorie",
+    "failingJobs": "Fehlschlagende Jobs",
+    "errors": "Fehler",
+    "agent": "Agent",
+    "status": "Status",
+    "executors": "Executors",
+    "coverage": "Abdeckung",
+    "analysisWarnings": "Warnungen",
+    "artifacts": "Artefakte",
+
+    "lineCoverage": "Zeilenabdeckung: %.1f%%",
+    "branchCoverage": "%s; Zweigabdeckung: %.1f%%",
+    "coverageTrend": "%s; von %.1f%% auf %.1f%% über %d Builds",
+
+    "warningCount": "%d Warnungen",
+    "warningsTrend": "%s; von %d auf %d über %d Builds",
+
+    "agentSummary": "%d von %d Agenten online, %d von %d Executors belegt",
+    "idle": "frei",
+    "busy": "belegt",
+    "offline": "offline",
+    "offlineReason": "offline: %s",
+
+    "duplicate": "Duplikat",
+    "duplicateTitle": "Dieser Job wird in dieser Instanz auch an anderer Stelle aufgeführt",
+    "stale": "veraltet",
+    "staleTitle": "Keine Builds in den letzten %s",
+    "upstreamFailing": "Upstream %s schlägt fehl",
+
+    "filterJobs": "Jobs filtern",
+    "filterStatus": "Nach Status filtern",
+    "allJobs": "Alle Jobs",
+    "failing": "Fehlschlagend",
+    "passing": "Erfolgreich",
+    "staleStatus": "Veraltet",
+    "pages": "Seiten",
+    "pageOf": "Seite %d von %d:",
+    "previousPage": "« Zurück",
+    "nextPage": "Weiter »",
+
+    "unreachableBanner": "Diese Instanz war nicht erreichbar, daher werden ihre zuletzt bekannten Jobs angezeigt: %s.",
+    "unreachable": "Nicht erreichbar; %s",
+    "refreshed": "Aktualisiert vor %s",
+    "jobsTracked": "%d Jobs überwacht",
+    "failingCount": "%d fehlschlagend",
+    "authFailureCount": "%d Authentifizierungsfehler",
+    "fetchErrorCount": "%d Abruffehler",
+}
+
+// french is the catalog of French messages.
+var french = Messages{
+    "unknownBuild": "Inconnu : impossible de récupérer les détails du build : %s",
+    "aborted": "Interrompu",
+    "notBuilt": "Non construit",
+    "passed": "Réussi",
+    "passedTests": "Réussi : %d tests",
+    "failed": "Échoué",
+    "failures": "%d échecs",
+    "testsFailed": "%d tests sur %d en échec",
+    "newFailures": "%s (%d nouveaux)",
+    "inStage": "%s dans %s",
+    "unstable": "Instable : %s",
+    "unstableBuild": "Instable",
+    "building": "en cours",
+    "buildingProgress": "En cours : %d %%",
+    "remaining": "%s, environ %s restantes",
+    "overdue": "%s, plus long que prévu",
+    "skipped": "%s, %d ignorés",
+    "started": "Démarré le %s (il y a %s)",
+    "knownFailure": "Échec connu : %s",
+    "buildLabel": "Build %d : %s",
+    "buildHistory": "Historique des builds",
+    "queued": "En file d'attente",
+    "queuedWhy": "En file d'attente : %s",
+    "downloadArtifact": "Télécharger %s du build %d",
+
+    "ago": "il y a %s",
+    "never": "jamais",
+    "staleSince": "obsolète depuis le %s (il y a %s)",
+
+    "authFailures": "Échecs d'authentification",
+    "warnings": "Avertissements",
+    "error": "erreur",
+    "warning": "avertissement",
+    "brokenBuilds": "Builds cassés",
+    "failureCategories": "Catégories d'échec",
+    "buildHealth": "État des builds",
+    "agents": "Agents",
+    "staleJobs": "Jobs obsolètes",
+    "other": "Autres",
+    "instance": "Instance",
+    "job": "Job",
+    "jobs": "Jobs",
+    "history": "Historique",
+    "lastSuccess": "Dernier succès",
+    "lastFailure": "Dernier échec",
+    "firstFailure": "Premier échec",
+    "culprits": "Responsables",
+    "unknownCulprits": "inconnus",
+    "category": "Catégorie",
+    "failingJobs": "Jobs en échec",
+    "errors": "Erreurs",
+    "agent": "Agent",
+    "status": "État",
+    "executors": "Exécuteurs",
+    "coverage": "Couverture",
+    "analysisWarnings": "Avertissements",
+    "artifacts": "Artefacts",
+
+    "lineCoverage": "Couverture des lignes : %.1f %%",
+    "branchCoverage": "%s ; couverture des branches : %.1f %%",
+    "coverageTrend": "%s ; de %.1f %% à %.1f %% sur %d builds",
+
+    "warningCount": "%d avertissements",
+    "warningsTrend": "%s ; de %d à %d sur %d builds",
+
+    "agentSummary": "%d agents sur %d en ligne, %d exécuteurs sur %d occupés",
+    "idle": "libre",
+    "busy": "occupé",
+    "offline": "hors ligne",
+    "offlineReason": "hors ligne : %s",
+
+    "duplicate": "doublon",
+    "duplicateTitle": "Ce job est aussi listé ailleurs dans cette instance",
+    "stale": "obsolète",
+    "staleTitle": "Aucun build depuis %s",
+    "upstreamFailing": "%s en amont en échec",
+
+    "filterJobs": "Filtrer les jobs",
+    "filterStatus": "Filtrer par état",
+    "allJobs": "Tous les jobs",
+    "failing": "En échec",
+    "passing": "Réussis",
+    "staleStatus": "Obsolètes",
+    "pages": "Pages",
+    "pageOf": "Page %d sur %d :",
+    "previousPage": "« Précédente",
+    "nextPage": "Suivante »",
+
+    "unreachableBanner": "Cette instance est injoignable ; ses derniers jobs connus sont affichés : %s.",
+    "unreachable": "Injoignable ; %s",
+    "refreshed": "Actualisé il y a %s",
+    "jobsTracked": "%d jobs suivis",
+    "failingCount": "%d en échec",
+    "authFailureCount": "%d échecs d'authentification",
+    "fetchErrorCount": "%d erreurs de récupération",
+}
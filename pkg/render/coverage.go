@@ -0,0 +1,39 @@
+package render
+
+import (
+    "fmt"
+
+    "github.com/pgavlin/jitdash/pkg/jenkins"
+)
+
+// coverage returns the job's coverage as shown in its coverage column and a description of it: the sparkline of the
+// coverage of the job's history, if options.Coverage asks for one, and the latest line coverage with its change since
+// the previous build that reported coverage. All are empty if no build in the job's history reports coverage.
+func coverage(i *jenkins.Instance, job *jenkins.Job, options Options) (spark, text, title string) {
+    builds := trendBuilds(i, job, options, func(b *jenkins.Build) bool { return b.Coverage != nil })
+    if len(builds) == 0 {
+        return "", "", ""
+    }
+
+    latest := builds[len(builds) - 1].Coverage
+    text = fmt.Sprintf("%.1f%%", latest.Lines)
+    if len(builds) > 1 {
+        if change := latest.Lines - builds[len(builds) - 2].Coverage.Lines; change <= -0.05 || change >= 0.05 {
+            text += fmt.Sprintf(" (%+.1f)", change)
+        }
+    }
+
+    title = options.text("lineCoverage", latest.Lines)
+    if latest.Branches >= 0 {
+        title = options.text("branchCoverage", title, latest.Branches)
+    }
+    if options.Coverage == TrendSparkline {
+        values := make([]float64, len(builds))
+        for n, b := range builds {
+            values[n] = b.Coverage.Lines
+        }
+        spark = trendSparkline(values)
+        title = options.text("coverageTrend", title, builds[0].Coverage.Lines, latest.Lines, len(builds))
+    }
+    return spark, text, title
+}
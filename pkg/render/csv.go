@@ -0,0 +1,50 @@
+package render
+
+import (
+    "encoding/csv"
+    "io"
+    "strconv"
+    "time"
+
+    "github.com/pgavlin/jitdash/pkg/jenkins"
+)
+
+// CSV renders one row per fetched build, preceded by a header row. Builds that are still running have the result
+// BUILDING and an empty duration. Durations are in seconds.
+func CSV(w io.Writer, instances []*jenkins.InstanceJobs, options Options) error {
+    c := csv.NewWriter(w)
+    c.Write([]string{"instance", "job", "build", "timestamp", "duration", "result", "failures", "url"})
+
+    for _, ij := range instances {
+        jobs := make([]*jenkins.Job, 0, len(ij.Jobs))
+        for _, job := range ij.Jobs {
+            if (!options.OnlyFailing || job.Failing()) && !job.Duplicate {
+                jobs = append(jobs, job)
+            }
+        }
+        jenkins.SortJobs(jobs, options.Sort)
+
+        for _, job := range jobs {
+            for _, b := range job.Builds {
+                timestamp, duration, result := "", "", "BUILDING"
+                if !b.Timestamp.IsZero() {
+                    timestamp = b.Timestamp.Format(time.RFC3339)
+                }
+                if b.Err != nil {
+                    result = "UNKNOWN"
+                } else if b.Complete {
+                    result = b.Result.String()
+                    if b.Duration != 0 {
+                        duration = strconv.FormatFloat(b.Duration.Seconds(), 'f', -1, 64)
+                    }
+                }
+
+                c.Write([]string{ij.Instance.Name, job.Name, strconv.FormatInt(b.Id, 10), timestamp, duration, result,
+                    strconv.FormatInt(b.Failures, 10), ij.Instance.DisplayUrl(b.Url)})
+            }
+        }
+    }
+
+    c.Flush()
+    return c.Error()
+}
@@ -0,0 +1,77 @@
+package render
+
+import (
+    "bytes"
+    "fmt"
+    "io"
+    "strconv"
+
+    "github.com/pgavlin/jitdash/pkg/jenkins"
+)
+
+// jobClass returns the cell class that summarizes the state of the given job: failure or success according to its most
+// recent completed build, or not-built if it has none.
+func jobClass(job *jenkins.Job) string {
+    switch last := job.LastCompletedBuild(); {
+    case last == nil:
+        return "not-built"
+    case last.Failed():
+        return "failure"
+    default:
+        return "success"
+    }
+}
+
+// DOT renders the upstream/downstream relationships between jobs as a Graphviz graph with a cluster per instance. Jobs
+// are filled with the color of their state and edges point from upstream to downstream jobs. Relationships with jobs
+// that are not on the dashboard are omitted.
+func DOT(w io.Writer, instances []*jenkins.InstanceJobs, options Options) error {
+    b := new(bytes.Buffer)
+    printf := func(format string, a ...interface{}) {
+        fmt.Fprintf(b, format, a...)
+    }
+
+    printf("digraph jobs {\n")
+    printf("    rankdir=LR;\n    node [shape=box, style=filled, fontcolor=white, fontname=\"Helvetica\"];\n")
+    for n, ij := range instances {
+        printf("    subgraph cluster_%d {\n        label=%s;\n", n, strconv.Quote(ij.Instance.Name))
+
+        ids := make(map[string]string)
+        var jobs []*jenkins.Job
+        for _, job := range ij.Jobs {
+            if job.Duplicate || (options.OnlyFailing && !job.Failing()) {
+                continue
+            }
+            ids[job.Url] = fmt.Sprintf("j%d_%d", n, len(jobs))
+            jobs = append(jobs, job)
+        }
+        jenkins.SortJobs(jobs, options.Sort)
+
+        for _, job := range jobs {
+            printf("        %s [label=%s, URL=%s, fillcolor=%s];\n", ids[job.Url], strconv.Quote(job.Label()),
+                strconv.Quote(ij.Instance.DisplayUrl(job.Url)), strconv.Quote(CellColors[jobClass(job)]))
+        }
+
+        edges := make(map[[2]string]bool)
+        edge := func(from, to string) {
+            if from == "" || to == "" || edges[[2]string{from, to}] {
+                return
+            }
+            edges[[2]string{from, to}] = true
+            printf("        %s -> %s;\n", from, to)
+        }
+        for _, job := range jobs {
+            for _, url := range job.Upstream {
+                edge(ids[url], ids[job.Url])
+            }
+            for _, url := range job.Downstream {
+                edge(ids[job.Url], ids[url])
+            }
+        }
+        printf("    }\n")
+    }
+    printf("}\n")
+
+    _, err := w.Write(b.Bytes())
+    return err
+}
@@ -0,0 +1,109 @@
+package render
+
+import (
+    "bytes"
+    "fmt"
+    "html"
+    "io"
+    "strings"
+
+    "github.com/pgavlin/jitdash/pkg/jenkins"
+)
+
+// Email renders a build health digest as HTML that is safe to send by email: all styling is inline and the digest
+// contains no scripts. The digest summarizes each instance, lists the currently broken jobs, and then shows a table of
+// jobs per instance.
+func Email(w io.Writer, instances []*jenkins.InstanceJobs, options Options) error {
+    b := new(bytes.Buffer)
+    printf := func(format string, a ...interface{}) {
+        fmt.Fprintf(b, format, a...)
+    }
+
+    const table = "border-collapse: collapse; font-family: Helvetica, Arial, sans-serif; font-size: 13px"
+    const cell = "padding: 4px 8px; border-bottom: 1px solid #e0e0e0; text-align: left"
+
+    printf("<html><body style=\"font-family: Helvetica, Arial, sans-serif\">\n")
+    printf("<h2>%s</h2>\n", html.EscapeString(options.text("buildHealth")))
+
+    summary := Summarize(instances)
+    printf("<table style=\"%s\"><tr><th style=\"%s\">%s</th><th style=\"%s\">%s</th><th style=\"%s\">%s</th><th style=\"%s\">%s</th></tr>\n",
+        table, cell, html.EscapeString(options.text("instance")), cell, html.EscapeString(options.text("jobs")), cell, html.EscapeString(options.text("failing")),
+        cell, html.EscapeString(options.text("errors")))
+    for _, s := range summary.Instances {
+        color := CellColors["success"]
+        if s.Failing != 0 {
+            color = CellColors["failure"]
+        }
+        printf("<tr><td style=\"%s\">%s</td><td style=\"%s\">%d</td><td style=\"%s; color: %s\">%d</td><td style=\"%s\">%d</td></tr>\n",
+            cell, html.EscapeString(s.Name), cell, s.Jobs, cell, color, s.Failing, cell, s.Errors)
+    }
+    printf("</table>\n")
+
+    header := false
+    for _, ij := range instances {
+        var failing []*jenkins.Job
+        for _, j := range ij.Jobs {
+            if j.Failing() && !j.Duplicate {
+                failing = append(failing, j)
+            }
+        }
+        jenkins.SortJobs(failing, "recentlyBroken")
+
+        for _, j := range failing {
+            if !header {
+                printf("<h3>%s</h3>\n", html.EscapeString(options.text("brokenBuilds")))
+                printf("<table style=\"%s\"><tr><th style=\"%s\">%s</th><th style=\"%s\">%s</th><th style=\"%s\">%s</th><th style=\"%s\">%s</th></tr>\n",
+                    table, cell, html.EscapeString(options.text("instance")), cell, html.EscapeString(options.text("job")), cell,
+                    html.EscapeString(options.text("firstFailure")), cell, html.EscapeString(options.text("culprits")))
+                header = true
+            }
+
+            first := j.FirstFailingBuild()
+            culprits := strings.Join(first.Culprits, ", ")
+            if culprits == "" {
+                culprits = options.text("unknownCulprits")
+            }
+            printf("<tr><td style=\"%s\">%s</td><td style=\"%s\"><a href=\"%s\">%s</a></td><td style=\"%s\"><a href=\"%s\">#%d</a> %s</td><td style=\"%s\">%s</td></tr>\n",
+                cell, html.EscapeString(ij.Instance.Name), cell, html.EscapeString(ij.Instance.DisplayUrl(j.Url)), html.EscapeString(j.Label()),
+                cell, html.EscapeString(ij.Instance.DisplayUrl(first.Url)), first.Id, html.EscapeString(first.Change), cell, html.EscapeString(culprits))
+        }
+    }
+    if header {
+        printf("</table>\n")
+    }
+
+    for _, ij := range instances {
+        var visible []*jenkins.Job
+        for _, job := range ij.Jobs {
+            if (!options.OnlyFailing || job.Failing()) && !job.Duplicate {
+                visible = append(visible, job)
+            }
+        }
+        if len(visible) == 0 {
+            continue
+        }
+        jenkins.SortJobs(visible, options.Sort)
+
+        printf("<h3>%s</h3>\n", html.EscapeString(ij.Instance.Name))
+        printf("<table style=\"%s\"><tr><th style=\"%s\">%s</th><th style=\"%s\">%s</th></tr>\n", table, cell, html.EscapeString(options.text("job")),
+            cell, html.EscapeString(options.text("history")))
+        for _, job := range visible {
+            printf("<tr><td style=\"%s\"><a href=\"%s\">%s</a></td><td style=\"%s; font-family: Consolas, Menlo, Courier, monospace\">",
+                cell, html.EscapeString(ij.Instance.DisplayUrl(job.Url)), html.EscapeString(job.Label()), cell)
+            for _, c := range jobCells(ij.Instance, job, options) {
+                if c.Url == "" {
+                    printf("<span style=\"color: %s\">%c</span>", CellColors["not-built"], c.Spark)
+                    continue
+                }
+                printf("<a href=\"%s\" title=\"%s\" style=\"color: %s; text-decoration: none\">%c</a>",
+                    html.EscapeString(c.Url), html.EscapeString(c.Title), CellColors[c.Class], c.Spark)
+            }
+            printf("</td></tr>\n")
+        }
+        printf("</table>\n")
+    }
+    printf("</body></html>\n")
+
+    _, err := w.Write(b.Bytes())
+    return err
+}
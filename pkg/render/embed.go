@@ -0,0 +1,43 @@
+package render
+
+import (
+    "bytes"
+    "fmt"
+    "io"
+
+    "github.com/pgavlin/jitdash/pkg/jenkins"
+)
+
+// embedStyle makes embedded pages blend into the page that embeds them.
+const embedStyle = "html, body { background: transparent; margin: 0 }\ntable.jobs { font-size: 12px }\n"
+
+// Embed renders a minimal HTML page that is suitable for embedding in an iframe, e.g. in a wiki page or a Grafana text
+// panel. The page has no headings or warnings and a transparent background, and its links open outside of the frame.
+// If job is nil, the page contains a table of the instance's jobs; otherwise it contains the job's sparkline alone.
+func Embed(w io.Writer, ij *jenkins.InstanceJobs, job *jenkins.Job, options Options) error {
+    b := new(bytes.Buffer)
+    printf := func(format string, a ...interface{}) {
+        fmt.Fprintf(b, format, a...)
+    }
+
+    printf("<html><head>%s<base target=\"_blank\"><style>%s%s</style></head><body>\n", head(options), stylesheet(options.EventsUrl != ""), embedStyle)
+    if job != nil {
+        printf("<table><tr><td class=\"sparkline\">%s</td></tr></table>\n", History(jobCells(ij.Instance, job, options), options))
+    } else {
+        var visible []*jenkins.Job
+        for _, j := range ij.Jobs {
+            if (!options.OnlyFailing || j.Failing()) && !j.Duplicate {
+                visible = append(visible, j)
+            }
+        }
+        jenkins.SortJobs(visible, options.Sort)
+        jobTable(printf, ij.Instance, visible, options)
+    }
+    if options.EventsUrl != "" {
+        printf(liveUpdateScript, options.EventsUrl)
+    }
+    printf("</body></html>\n")
+
+    _, err := w.Write(b.Bytes())
+    return err
+}
@@ -0,0 +1,19 @@
+package render
+
+import (
+    "io"
+
+    "github.com/pgavlin/jitdash/pkg/jenkins"
+)
+
+// A Renderer renders the jobs of the given instances to a writer.
+type Renderer func(w io.Writer, instances []*jenkins.InstanceJobs, options Options) error
+
+// Formats maps the names of the supported output formats to their renderers.
+var Formats = map[string]Renderer{
+    "html": HTML,
+    "csv": CSV,
+    "markdown": Markdown,
+    "term": Term,
+    "dot": DOT,
+}
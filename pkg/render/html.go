@@ -0,0 +1,611 @@
+// Package render renders the jobs fetched by package jenkins.
+package render
+
+import (
+    "bytes"
+    "fmt"
+    "html"
+    "io"
+    "log/slog"
+    "sort"
+    "strings"
+    "time"
+
+    "github.com/pgavlin/jitdash/pkg/jenkins"
+)
+
+// Options controls how jobs are rendered.
+type Options struct {
+    MaxBuilds int // the number of most recent builds fetched per job; see jenkins.Instance.JobLimits
+    MaxHistory int // the number of most recent builds to render per job
+    Scale jenkins.Scale // the default mapping of failure counts to sparkline heights; see jenkins.Instance.JobScale
+    Sort string // the name of the job ordering; see jenkins.JobOrders
+    Location *time.Location // the timezone in which to display timestamps, or nil for UTC
+    OnlyFailing bool // true to omit jobs whose most recent completed build passed
+    StaleAfter time.Duration // if non-zero, jobs whose most recent build started longer ago than this are marked stale
+    StaleSection bool // true to list stale jobs in their own section rather than in their groups
+    Overview bool // in serve mode, true to serve an overview of the instances rather than the full dashboard at "/"
+    PageSize int // the number of jobs per page, or zero to render every job on a single page
+    Page int // the 1-based number of the page to render when PageSize is non-zero
+    PageUrl func(page int) string // returns the URL of the given page; required when PageSize is non-zero
+    EventsUrl string // if non-empty, the URL of a Server-Sent Events stream that signals that the page should update
+    Sections []*jenkins.Section // if non-empty, the HTML dashboard lists these sections rather than a section per instance
+    Offline bool // true to render HTML pages that load nothing from the network, e.g. for exports viewed from a file share
+    Messages Messages // the catalog of rendered strings, or nil to render them in English; see ParseMessages
+    Shapes bool // true to distinguish the outcomes of builds by the shapes of their cells as well as by color
+    Coverage string // how to show the code coverage of jobs: TrendColumn, TrendSparkline, or empty not to show it
+    AnalysisWarnings string // how to show the static-analysis warning counts of jobs, like Coverage
+}
+
+// liveUpdateScript re-fetches the page and replaces its body each time the event stream at the URL given by its
+// format argument signals an update. The job filter is re-applied to the new body.
+const liveUpdateScript = `<script>
+(function() {
+    var events = new EventSource(%q);
+    events.addEventListener("update", function() {
+        fetch(window.location.href).then(function(r) { return r.text(); }).then(function(text) {
+            var doc = new DOMParser().parseFromString(text, "text/html");
+            document.body.innerHTML = doc.body.innerHTML;
+            if (window.applyFilter) {
+                window.applyFilter();
+            }
+        });
+    });
+})();
+</script>
+`
+
+// filterForm returns a form that lets users filter the job tables by name and status.
+func filterForm(options Options) string {
+    return fmt.Sprintf(`<div class="filter"><input id="filter-text" type="search" placeholder="%[1]s" aria-label="%[1]s">
+<select id="filter-status" aria-label="%[6]s"><option value="">%[2]s</option><option value="failing">%[3]s</option><option value="passing">%[4]s</option><option value="stale">%[5]s</option></select></div>
+`, html.EscapeString(options.text("filterJobs")), html.EscapeString(options.text("allJobs")), html.EscapeString(options.text("failing")),
+        html.EscapeString(options.text("passing")), html.EscapeString(options.text("staleStatus")), html.EscapeString(options.text("filterStatus")))
+}
+
+// filterScript hides the job rows that do not match the filter form. Listeners are attached to the document so that
+// they survive live updates, which replace the form along with the rest of the body.
+const filterScript = `<script>
+(function() {
+    var state = {text: "", status: ""};
+    window.applyFilter = function() {
+        var text = document.getElementById("filter-text"), status = document.getElementById("filter-status");
+        text.value = state.text;
+        status.value = state.status;
+        document.querySelectorAll("tr[data-name]").forEach(function(row) {
+            var show = row.dataset.name.indexOf(state.text.toLowerCase()) != -1 &&
+                (state.status == "" || row.dataset.status == state.status);
+            row.style.display = show ? "" : "none";
+        });
+    };
+    document.addEventListener("input", function(e) {
+        if (e.target.id == "filter-text") {
+            state.text = e.target.value;
+        } else if (e.target.id == "filter-status") {
+            state.status = e.target.value;
+        } else {
+            return;
+        }
+        window.applyFilter();
+    });
+})();
+</script>
+`
+
+// jobStatus returns the status by which the given job can be filtered: stale, failing, or passing.
+func jobStatus(job *jenkins.Job, options Options) string {
+    switch {
+    case job.Stale(options.StaleAfter):
+        return "stale"
+    case job.Failing():
+        return "failing"
+    default:
+        return "passing"
+    }
+}
+
+// History renders the given sparkline cells as HTML. Each build links to its Jenkins page. The sparkline is marked up
+// as a list whose items are labeled with their builds' descriptions, and the sparks themselves are hidden from screen
+// readers.
+func History(cells []Cell, options Options) string {
+    w := new(bytes.Buffer)
+    fmt.Fprintf(w, "<span role=\"list\" aria-label=\"%s\">", html.EscapeString(options.text("buildHistory")))
+    for _, c := range cells {
+        if c.Url == "" {
+            fmt.Fprintf(w, "<span aria-hidden=\"true\">%c</span>", c.Spark)
+            continue
+        }
+
+        label := strings.ReplaceAll(c.Title, "\n", "; ")
+        if c.Build != nil {
+            label = options.text("buildLabel", c.Build.Id, label)
+        }
+        url := html.EscapeString(c.Url)
+        fmt.Fprintf(w, "<a role=\"listitem\" class=\"%s\" href=\"%s\" title=\"%s&#10;%s\" aria-label=\"%s\"><span aria-hidden=\"true\">%c</span></a>",
+            c.Class, url, html.EscapeString(c.Title), url, html.EscapeString(label), c.Spark)
+    }
+    w.WriteString("</span>")
+    return w.String()
+}
+
+// brokenBuilds renders a table of the currently failing jobs of every instance along with the first failing build in
+// each job's current run of failures and that build's culprits. Jobs are tagged with their failure category and with
+// the failing upstream jobs that likely broke them. Nothing is rendered if no jobs are failing.
+func brokenBuilds(printf func(format string, a ...interface{}), instances []*jenkins.InstanceJobs, options Options) {
+    header := false
+    for _, ij := range instances {
+        var failing []*jenkins.Job
+        for _, j := range ij.Jobs {
+            if j.Failing() && !j.Duplicate {
+                failing = append(failing, j)
+            }
+        }
+        jenkins.SortJobs(failing, "recentlyBroken")
+
+        for _, j := range failing {
+            if !header {
+                printf("<h2>%s</h2>\n", html.EscapeString(options.text("brokenBuilds")))
+                printf("<table class=\"broken\"><tr><th scope=\"col\" class=\"secondary\">%s</th><th scope=\"col\">%s</th><th scope=\"col\">%s</th><th scope=\"col\">%s</th></tr>\n",
+                    html.EscapeString(options.text("instance")), html.EscapeString(options.text("job")), html.EscapeString(options.text("firstFailure")),
+                    html.EscapeString(options.text("culprits")))
+                header = true
+            }
+
+            first := j.FirstFailingBuild()
+            culprits := strings.Join(first.Culprits, ", ")
+            if culprits == "" {
+                culprits = options.text("unknownCulprits")
+            }
+            var tag string
+            if category := ij.Instance.Classify(j.LastCompletedBuild()); category != "" {
+                tag = " <span class=\"category\">" + html.EscapeString(category) + "</span>"
+            }
+            if upstream := failingUpstream(ij, j); upstream != "" {
+                tag += " <span class=\"upstream\">" + html.EscapeString(options.text("upstreamFailing", upstream)) + "</span>"
+            }
+            printf("<tr><td class=\"secondary\">%s</td><td><a href=\"%s\">%s</a>%s</td><td><a href=\"%s\">#%d</a> %s</td><td>%s</td></tr>\n",
+                html.EscapeString(ij.Instance.Name), html.EscapeString(ij.Instance.DisplayUrl(j.Url)), html.EscapeString(j.Label()), tag,
+                html.EscapeString(ij.Instance.DisplayUrl(first.Url)), first.Id, html.EscapeString(first.Change), html.EscapeString(culprits))
+        }
+    }
+    if header {
+        printf("</table><br />\n")
+    }
+}
+
+// failureCategories renders a table of the number of failing jobs per failure category, most common first. Nothing is
+// rendered if no failing job has been classified.
+func failureCategories(printf func(format string, a ...interface{}), instances []*jenkins.InstanceJobs, options Options) {
+    categories := Summarize(instances).Categories
+    if len(categories) == 0 {
+        return
+    }
+
+    names := make([]string, 0, len(categories))
+    for c := range categories {
+        names = append(names, c)
+    }
+    sort.Slice(names, func(i, j int) bool {
+        if categories[names[i]] != categories[names[j]] {
+            return categories[names[i]] > categories[names[j]]
+        }
+        return names[i] < names[j]
+    })
+
+    printf("<h2>%s</h2>\n", html.EscapeString(options.text("failureCategories")))
+    printf("<table class=\"categories\"><tr><th scope=\"col\">%s</th><th scope=\"col\">%s</th></tr>\n", html.EscapeString(options.text("category")),
+        html.EscapeString(options.text("failingJobs")))
+    for _, c := range names {
+        printf("<tr><td><span class=\"category\">%s</span></td><td>%d</td></tr>\n", html.EscapeString(c), categories[c])
+    }
+    printf("</table><br />\n")
+}
+
+// agentTable renders a summary of an instance's agents followed by a table of the agents, offline agents first.
+func agentTable(printf func(format string, a ...interface{}), status *jenkins.AgentStatus, options Options) {
+    printf("<h3>%s</h3>\n", html.EscapeString(options.text("agents")))
+    printf("<p>%s</p>\n", html.EscapeString(options.text("agentSummary", status.OnlineAgents(), len(status.Agents),
+        status.BusyExecutors, status.TotalExecutors)))
+
+    printf("<table class=\"agents\"><tr><th scope=\"col\">%s</th><th scope=\"col\">%s</th><th scope=\"col\" class=\"secondary\">%s</th></tr>\n", html.EscapeString(options.text("agent")),
+        html.EscapeString(options.text("status")), html.EscapeString(options.text("executors")))
+    for _, offline := range []bool{true, false} {
+        for _, a := range status.Agents {
+            if a.Offline != offline {
+                continue
+            }
+
+            state, class := options.text("idle"), "success"
+            switch {
+            case a.Offline:
+                state, class = options.text("offline"), "failure"
+                if a.OfflineReason != "" {
+                    state = options.text("offlineReason", a.OfflineReason)
+                }
+            case !a.Idle:
+                state, class = options.text("busy"), "building"
+            }
+            printf("<tr><td>%s</td><td style=\"color: %s\">%s</td><td class=\"secondary\">%d</td></tr>\n", html.EscapeString(a.Name),
+                CellColors[class], html.EscapeString(state), a.Executors)
+        }
+    }
+    printf("</table><br />\n")
+}
+
+// jobTable renders a table of the given jobs of the given instance, one row per job. Stale jobs are styled distinctly.
+// The instance's extra columns, the job's coverage and warnings if options ask for them, and links to the artifacts of
+// the job's last successful build if the instance shows artifacts follow each job's history.
+func jobTable(printf func(format string, a ...interface{}), i *jenkins.Instance, jobs []*jenkins.Job, options Options) {
+    columnHeadings := ""
+    for _, c := range i.Columns {
+        columnHeadings += fmt.Sprintf("<th scope=\"col\" class=\"secondary\">%s</th>", html.EscapeString(c.Name))
+    }
+    if options.Coverage != "" {
+        columnHeadings += fmt.Sprintf("<th scope=\"col\" class=\"secondary\">%s</th>", html.EscapeString(options.text("coverage")))
+    }
+    if options.AnalysisWarnings != "" {
+        columnHeadings += fmt.Sprintf("<th scope=\"col\" class=\"secondary\">%s</th>", html.EscapeString(options.text("analysisWarnings")))
+    }
+    if len(i.Artifacts) != 0 {
+        columnHeadings += fmt.Sprintf("<th scope=\"col\" class=\"secondary\">%s</th>", html.EscapeString(options.text("artifacts")))
+    }
+    printf("<table class=\"jobs\"><tr><th scope=\"col\">%s</th><th scope=\"col\">%s</th>%s<th scope=\"col\" class=\"secondary\">%s</th><th scope=\"col\" class=\"secondary\">%s</th></tr>\n",
+        html.EscapeString(options.text("job")), html.EscapeString(options.text("history")), columnHeadings,
+        html.EscapeString(options.text("lastSuccess")), html.EscapeString(options.text("lastFailure")))
+    for _, job := range jobs {
+        slog.Debug("rendering job", "job", job.Name)
+
+        class, note := "", ""
+        if job.Duplicate {
+            note = fmt.Sprintf(" <span class=\"duplicate\" title=\"%s\">(%s)</span>", html.EscapeString(options.text("duplicateTitle")),
+                html.EscapeString(options.text("duplicate")))
+        }
+        if job.Stale(options.StaleAfter) {
+            class = " class=\"stale\""
+            note += fmt.Sprintf(" <span title=\"%s\">(%s)</span>", html.EscapeString(options.text("staleTitle", Humanize(options.StaleAfter))),
+                html.EscapeString(options.text("stale")))
+        }
+        filter := fmt.Sprintf(" data-name=\"%s\" data-status=\"%s\"", html.EscapeString(strings.ToLower(strings.TrimSpace(job.DisplayName + " " + job.Name))),
+            jobStatus(job, options))
+        columns := ""
+        for _, c := range i.Columns {
+            columns += fmt.Sprintf("<td class=\"secondary\">%s</td>", html.EscapeString(job.Column(c.Name)))
+        }
+        if options.Coverage != "" {
+            columns += trendCell(coverage(i, job, options))
+        }
+        if options.AnalysisWarnings != "" {
+            columns += trendCell(analysisWarnings(i, job, options))
+        }
+        if len(i.Artifacts) != 0 {
+            var links []string
+            for _, l := range artifactLinks(i, job, options) {
+                links = append(links, fmt.Sprintf("<a href=\"%s\" title=\"%s\" download>%s</a>", html.EscapeString(l.Url), html.EscapeString(l.Title),
+                    html.EscapeString(l.Name)))
+            }
+            columns += fmt.Sprintf("<td class=\"secondary\">%s</td>", strings.Join(links, " "))
+        }
+        printf("<tr%s%s><td><a href=\"%s\">%s</a>%s</td><td class=\"sparkline\">%s</td>%s<td class=\"secondary\" title=\"%s\">%s</td><td class=\"secondary\" title=\"%s\">%s</td></tr>\n", class, filter, html.EscapeString(i.DisplayUrl(job.Url)), html.EscapeString(job.Label()), note,
+            History(jobCells(i, job, options), options), columns,
+            html.EscapeString(localTime(job.LastSuccess(), options.location())), html.EscapeString(since(job.LastSuccess(), options)),
+            html.EscapeString(localTime(job.LastFailure(), options.location())), html.EscapeString(since(job.LastFailure(), options)))
+    }
+    printf("</table><br />\n")
+}
+
+// trendCell renders a cell of a trend column. The sparkline is hidden from screen readers, which read the title.
+func trendCell(spark, text, title string) string {
+    if spark != "" {
+        spark = "<span class=\"trend\" aria-hidden=\"true\">" + spark + "</span> "
+    }
+    return fmt.Sprintf("<td class=\"secondary\" title=\"%s\">%s%s</td>", html.EscapeString(title), spark, html.EscapeString(text))
+}
+
+// Pages returns the number of pages over which the given instances' jobs are rendered.
+func Pages(instances []*jenkins.InstanceJobs, options Options) int {
+    if options.PageSize <= 0 {
+        return 1
+    }
+
+    count := 0
+    if len(options.Sections) != 0 {
+        for _, s := range options.Sections {
+            for _, t := range sectionTables(s, instances, options) {
+                count += len(t.jobs)
+            }
+        }
+    } else {
+        for _, ij := range instances {
+            for _, job := range ij.Jobs {
+                if !options.OnlyFailing || job.Failing() {
+                    count++
+                }
+            }
+        }
+    }
+    if count == 0 {
+        return 1
+    }
+    return (count + options.PageSize - 1) / options.PageSize
+}
+
+// location returns the timezone in which to display timestamps.
+func (options Options) location() *time.Location {
+    if options.Location == nil {
+        return time.UTC
+    }
+    return options.Location
+}
+
+// currentPage returns the 1-based number of the page to render.
+func (options Options) currentPage() int {
+    if options.Page < 1 {
+        return 1
+    }
+    return options.Page
+}
+
+// onPage returns the part of the given jobs that falls on the current page. offset is the number of jobs rendered
+// before these on all pages.
+func onPage(jobs []*jenkins.Job, offset int, options Options) []*jenkins.Job {
+    if options.PageSize <= 0 {
+        return jobs
+    }
+
+    clamp := func(n int) int {
+        switch {
+        case n < 0:
+            return 0
+        case n > len(jobs):
+            return len(jobs)
+        default:
+            return n
+        }
+    }
+    first := (options.currentPage() - 1) * options.PageSize - offset
+    return jobs[clamp(first):clamp(first + options.PageSize)]
+}
+
+// pageLinks renders links to the previous page, to each page, and to the next page. Nothing is rendered if there is
+// only one page.
+func pageLinks(printf func(format string, a ...interface{}), pages int, options Options) {
+    if pages <= 1 {
+        return
+    }
+
+    page := options.currentPage()
+    link := func(n int, text string) {
+        switch {
+        case n == page:
+            printf(" <span aria-current=\"page\">%s</span>", text)
+        case n < 1 || n > pages:
+            printf(" <span>%s</span>", text)
+        default:
+            printf(" <a href=\"%s\">%s</a>", html.EscapeString(options.PageUrl(n)), text)
+        }
+    }
+
+    printf("<nav class=\"pages\" aria-label=\"%s\">%s", html.EscapeString(options.text("pages")), html.EscapeString(options.text("pageOf", page, pages)))
+    link(page - 1, html.EscapeString(options.text("previousPage")))
+    for n := 1; n <= pages; n++ {
+        link(n, fmt.Sprintf("%d", n))
+    }
+    link(page + 1, html.EscapeString(options.text("nextPage")))
+    printf("</nav>\n")
+}
+
+// jobSection is a titled table of jobs.
+type jobSection struct {
+    title string
+    jobs []*jenkins.Job
+}
+
+// instanceSections renders a section per instance with a table per group of jobs. Jobs that fall on other pages are
+// skipped.
+func instanceSections(printf func(format string, a ...interface{}), instances []*jenkins.InstanceJobs, options Options) {
+    offset := 0
+    for _, ij := range instances {
+        i := ij.Instance
+
+        var visible, stale []*jenkins.Job
+        for _, job := range ij.Jobs {
+            switch {
+            case options.OnlyFailing && !job.Failing():
+            case options.StaleSection && job.Stale(options.StaleAfter):
+                stale = append(stale, job)
+            default:
+                visible = append(visible, job)
+            }
+        }
+
+        var tables []jobSection
+        groups := i.GroupNames(visible)
+        for _, g := range groups {
+            var groupJobs []*jenkins.Job
+            for _, job := range visible {
+                if job.Group == g {
+                    groupJobs = append(groupJobs, job)
+                }
+            }
+            jenkins.SortJobs(groupJobs, options.Sort)
+
+            var title string
+            if len(groups) > 1 {
+                title = g
+                if title == "" {
+                    title = options.text("other")
+                }
+            }
+            tables = append(tables, jobSection{title, groupJobs})
+        }
+        if len(stale) != 0 {
+            jenkins.SortJobs(stale, "lastBuild")
+            tables = append(tables, jobSection{options.text("staleJobs"), stale})
+        }
+
+        // Only render the instance if some of its jobs fall on this page. Instances without jobs are rendered on the
+        // first page.
+        skip := len(tables) != 0 || options.currentPage() != 1
+        for n := range tables {
+            jobs := onPage(tables[n].jobs, offset, options)
+            offset += len(tables[n].jobs)
+            tables[n].jobs = jobs
+            skip = skip && len(jobs) == 0
+        }
+        if skip {
+            continue
+        }
+
+        printf("<h2>%s</h2>\n", html.EscapeString(i.Name))
+        if note := staleNote(ij, options); note != "" {
+            printf("<p class=\"stale-instance\">%s</p>\n", html.EscapeString(options.text("unreachableBanner", note)))
+        }
+
+        if ij.Agents != nil {
+            agentTable(printf, ij.Agents, options)
+        }
+
+        for _, t := range tables {
+            if len(t.jobs) == 0 {
+                continue
+            }
+            if t.title != "" {
+                printf("<h3>%s</h3>\n", html.EscapeString(t.title))
+            }
+            jobTable(printf, i, t.jobs, options)
+        }
+    }
+}
+
+// sectionTable is the part of a curated section that lists the jobs of one instance.
+type sectionTable struct {
+    instance *jenkins.Instance
+    jobs []*jenkins.Job
+}
+
+// sectionOptions returns the options with which the jobs of the given section are rendered.
+func sectionOptions(s *jenkins.Section, options Options) Options {
+    options.OnlyFailing = options.OnlyFailing || s.OnlyFailing
+    if s.Sort != "" {
+        options.Sort = s.Sort
+    }
+    if s.MaxHistory != 0 {
+        options.MaxHistory = s.MaxHistory
+    }
+    return options
+}
+
+// ForDashboard returns the options with which the given named dashboard is rendered.
+func (options Options) ForDashboard(d *jenkins.Dashboard) Options {
+    options = sectionOptions(&d.Section, options)
+    options.Sections = d.Sections
+    return options
+}
+
+// sectionTables returns the jobs the given section lists, in a table per instance. Duplicate listings of a job are
+// omitted.
+func sectionTables(s *jenkins.Section, instances []*jenkins.InstanceJobs, options Options) []sectionTable {
+    options = sectionOptions(s, options)
+
+    var tables []sectionTable
+    for _, ij := range instances {
+        var jobs []*jenkins.Job
+        for _, job := range ij.Jobs {
+            if !job.Duplicate && s.Selects(ij.Instance, job) && (!options.OnlyFailing || job.Failing()) {
+                jobs = append(jobs, job)
+            }
+        }
+        if len(jobs) != 0 {
+            jenkins.SortJobs(jobs, options.Sort)
+            tables = append(tables, sectionTable{ij.Instance, jobs})
+        }
+    }
+    return tables
+}
+
+// curatedSections renders the configured sections in order, each with a table of its jobs per instance. Instances are
+// only named if a section lists the jobs of more than one. Sections without jobs on the current page are skipped.
+func curatedSections(printf func(format string, a ...interface{}), instances []*jenkins.InstanceJobs, options Options) {
+    offset := 0
+    for _, s := range options.Sections {
+        tables := sectionTables(s, instances, options)
+
+        skip := true
+        for n := range tables {
+            jobs := onPage(tables[n].jobs, offset, options)
+            offset += len(tables[n].jobs)
+            tables[n].jobs = jobs
+            skip = skip && len(jobs) == 0
+        }
+        if skip {
+            continue
+        }
+
+        printf("<h2>%s</h2>\n", html.EscapeString(s.Name))
+        for _, t := range tables {
+            if len(t.jobs) == 0 {
+                continue
+            }
+            if len(tables) > 1 {
+                printf("<h3>%s</h3>\n", html.EscapeString(t.instance.Name))
+            }
+            jobTable(printf, t.instance, t.jobs, sectionOptions(s, options))
+        }
+    }
+}
+
+// HTML renders an HTML page with a section per instance to the given writer. Each section contains a table per group
+// of jobs; if options.Sections is non-empty, the page instead contains those sections in order. Any fetch errors are
+// listed in a warnings section at the top of the page, followed by a summary of the currently broken jobs and the
+// number of them per failure category.
+//
+// If options.PageSize is non-zero, only the jobs on the page given by options.Page are rendered, and the page links to
+// the others. The warnings and broken builds are only rendered on the first page.
+func HTML(w io.Writer, instances []*jenkins.InstanceJobs, options Options) error {
+    b := new(bytes.Buffer)
+    printf := func(format string, a ...interface{}) {
+        fmt.Fprintf(b, format, a...)
+    }
+
+    printf("<html><head>%s<style>%s</style></head><body>\n", head(options), stylesheet(options.EventsUrl != ""))
+    printf("%s", filterForm(options))
+
+    pages := Pages(instances, options)
+    pageLinks(printf, pages, options)
+
+    if options.currentPage() == 1 {
+        authErrs, errs := splitAuthFailures(instances)
+        if len(authErrs) != 0 {
+            printf("<h2>%s</h2>\n<ul class=\"warnings auth\">\n", html.EscapeString(options.text("authFailures")))
+            for _, e := range authErrs {
+                printf("<li>%s</li>\n", html.EscapeString(e.Error()))
+            }
+            printf("</ul>\n")
+        }
+        if len(errs) != 0 {
+            printf("<h2>%s</h2>\n<ul class=\"warnings\">\n", html.EscapeString(options.text("warnings")))
+            for _, e := range errs {
+                printf("<li>%s</li>\n", html.EscapeString(e.Error()))
+            }
+            printf("</ul>\n")
+        }
+
+        brokenBuilds(printf, instances, options)
+        failureCategories(printf, instances, options)
+    }
+
+    if len(options.Sections) != 0 {
+        curatedSections(printf, instances, options)
+    } else {
+        instanceSections(printf, instances, options)
+    }
+    pageLinks(printf, pages, options)
+    printf("%s", filterScript)
+    if options.EventsUrl != "" {
+        printf(liveUpdateScript, options.EventsUrl)
+    }
+    printf("</body></html>\n")
+
+    _, err := w.Write(b.Bytes())
+    return err
+}
@@ -0,0 +1,150 @@
+package render
+
+import (
+    "bytes"
+    "fmt"
+    "io"
+    "strings"
+
+    "github.com/pgavlin/jitdash/pkg/jenkins"
+)
+
+// emoji maps cell classes to the emoji that represent them in Markdown.
+var emoji = map[string]string{
+    "success": "✅",
+    "unstable": "⚠️",
+    "failure": "❌",
+    "known": "🔶",
+    "aborted": "⏹️",
+    "not-built": "⚪",
+    "building": "🔄",
+    "queued": "⏳",
+    "unknown": "❔",
+}
+
+// markdownEscaper escapes the characters that would otherwise break a table cell or a link.
+var markdownEscaper = strings.NewReplacer("|", "\\|", "[", "\\[", "]", "\\]", "\n", " ")
+
+// Markdown renders the dashboard as GitHub-flavored Markdown with a section per instance and a table per group of
+// jobs. Each build in a job's history is shown as an emoji that links to the build.
+func Markdown(w io.Writer, instances []*jenkins.InstanceJobs, options Options) error {
+    b := new(bytes.Buffer)
+    printf := func(format string, a ...interface{}) {
+        fmt.Fprintf(b, format, a...)
+    }
+
+    authErrs, errs := splitAuthFailures(instances)
+    if len(authErrs) != 0 {
+        printf("## %s\n\n", options.text("authFailures"))
+        for _, e := range authErrs {
+            printf("- **%s**\n", markdownEscaper.Replace(e.Error()))
+        }
+        printf("\n")
+    }
+    if len(errs) != 0 {
+        printf("## %s\n\n", options.text("warnings"))
+        for _, e := range errs {
+            printf("- %s\n", markdownEscaper.Replace(e.Error()))
+        }
+        printf("\n")
+    }
+
+    for _, ij := range instances {
+        i := ij.Instance
+        printf("## %s\n\n", markdownEscaper.Replace(i.Name))
+        if note := staleNote(ij, options); note != "" {
+            printf("> %s\n\n", markdownEscaper.Replace(options.text("unreachableBanner", note)))
+        }
+
+        var visible []*jenkins.Job
+        for _, job := range ij.Jobs {
+            if !options.OnlyFailing || job.Failing() {
+                visible = append(visible, job)
+            }
+        }
+
+        groups := i.GroupNames(visible)
+        for _, g := range groups {
+            var groupJobs []*jenkins.Job
+            for _, job := range visible {
+                if job.Group == g {
+                    groupJobs = append(groupJobs, job)
+                }
+            }
+            jenkins.SortJobs(groupJobs, options.Sort)
+
+            if len(groups) > 1 {
+                groupName := g
+                if groupName == "" {
+                    groupName = options.text("other")
+                }
+                printf("### %s\n\n", markdownEscaper.Replace(groupName))
+            }
+
+            var columnHeadings, columnRules string
+            for _, c := range i.Columns {
+                columnHeadings += " " + markdownEscaper.Replace(c.Name) + " |"
+                columnRules += " --- |"
+            }
+            if options.Coverage != "" {
+                columnHeadings += " " + options.text("coverage") + " |"
+                columnRules += " --- |"
+            }
+            if options.AnalysisWarnings != "" {
+                columnHeadings += " " + options.text("analysisWarnings") + " |"
+                columnRules += " --- |"
+            }
+            if len(i.Artifacts) != 0 {
+                columnHeadings += " " + options.text("artifacts") + " |"
+                columnRules += " --- |"
+            }
+            printf("| %s | %s |%s %s | %s |\n| --- | --- |%s --- | --- |\n", options.text("job"), options.text("history"), columnHeadings,
+                options.text("lastSuccess"), options.text("lastFailure"), columnRules)
+            for _, job := range groupJobs {
+                duplicate, stale, upstream := "", "", ""
+                if job.Stale(options.StaleAfter) {
+                    stale = " (" + options.text("stale") + ")"
+                }
+                if job.Duplicate {
+                    duplicate = " (" + options.text("duplicate") + ")"
+                }
+                if u := failingUpstream(ij, job); u != "" {
+                    upstream = " (" + markdownEscaper.Replace(options.text("upstreamFailing", u)) + ")"
+                }
+
+                var history []string
+                for _, c := range jobCells(i, job, options) {
+                    if c.Url != "" {
+                        title := strings.NewReplacer("\"", "'").Replace(markdownEscaper.Replace(c.Title))
+                        history = append(history, fmt.Sprintf("[%s](%s \"%s\")", emoji[c.Class], c.Url, title))
+                    }
+                }
+                columns := ""
+                for _, c := range i.Columns {
+                    columns += " " + markdownEscaper.Replace(job.Column(c.Name)) + " |"
+                }
+                if options.Coverage != "" {
+                    spark, text, _ := coverage(i, job, options)
+                    columns += " " + strings.TrimSpace(spark + " " + text) + " |"
+                }
+                if options.AnalysisWarnings != "" {
+                    spark, text, _ := analysisWarnings(i, job, options)
+                    columns += " " + strings.TrimSpace(spark + " " + text) + " |"
+                }
+                if len(i.Artifacts) != 0 {
+                    var links []string
+                    for _, l := range artifactLinks(i, job, options) {
+                        links = append(links, fmt.Sprintf("[%s](%s \"%s\")", markdownEscaper.Replace(l.Name), l.Url, strings.ReplaceAll(l.Title, "\"", "'")))
+                    }
+                    columns += " " + strings.Join(links, " ") + " |"
+                }
+                printf("| [%s](%s)%s%s%s | %s |%s %s | %s |\n", markdownEscaper.Replace(job.Label()), i.DisplayUrl(job.Url), duplicate, stale, upstream, strings.Join(history, " "),
+                    columns, since(job.LastSuccess(), options), since(job.LastFailure(), options))
+            }
+            printf("\n")
+        }
+    }
+
+    _, err := w.Write(b.Bytes())
+    return err
+}
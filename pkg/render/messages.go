@@ -0,0 +1,204 @@
+package render
+
+import (
+    "errors"
+    "fmt"
+    "regexp"
+    "strings"
+
+    "github.com/pgavlin/jitdash/pkg/jenkins"
+)
+
+// Messages is a catalog of the strings that the renderers show, keyed by message ID. Messages that take arguments are
+// format strings for fmt.Sprintf.
+type Messages map[string]string
+
+// english is the catalog of English messages. Every message ID has an English message, which is used when the selected
+// catalog does not translate it.
+var english = Messages{
+    // Build titles
+    "unknownBuild": "Unknown: could not fetch the build's details: %s",
+    "aborted": "Aborted",
+    "notBuilt": "Not built",
+    "passed": "Passed",
+    "passedTests": "Passed: %d tests",
+    "failed": "Failed",
+    "failures": "%d failures",
+    "testsFailed": "%d of %d tests failed",
+    "newFailures": "%s (%d new)",
+    "inStage": "%s in %s",
+    "unstable": "Unstable: %s",
+    "unstableBuild": "Unstable",
+    "building": "building",
+    "buildingProgress": "Building: %d%%",
+    "remaining": "%s, about %s remaining",
+    "overdue": "%s, taking longer than estimated",
+    "skipped": "%s, %d skipped",
+    "started": "Started %s (%s ago)",
+    "knownFailure": "Known failure: %s",
+    "buildLabel": "Build %d: %s",
+    "buildHistory": "Build history",
+    "queued": "Queued",
+    "queuedWhy": "Queued: %s",
+    "downloadArtifact": "Download %s from build %d",
+
+    // Ages
+    "ago": "%s ago",
+    "never": "never",
+    "staleSince": "stale since %s (%s ago)",
+
+    // Headings and labels
+    "authFailures": "Authentication failures",
+    "warnings": "Warnings",
+    "error": "error",
+    "warning": "warning",
+    "brokenBuilds": "Broken builds",
+    "failureCategories": "Failure categories",
+    "buildHealth": "Build health",
+    "agents": "Agents",
+    "staleJobs": "Stale jobs",
+    "other": "Other",
+    "instance": "Instance",
+    "job": "Job",
+    "jobs": "Jobs",
+    "history": "History",
+    "lastSuccess": "Last success",
+    "lastFailure": "Last failure",
+    "firstFailure": "First failure",
+    "culprits": "Culprits",
+    "unknownCulprits": "unknown",
+    "category": "Category",
+    "failingJobs": "Failing jobs",
+    "errors": "Errors",
+    "agent": "Agent",
+    "status": "Status",
+    "executors": "Executors",
+    "coverage": "Coverage",
+    "analysisWarnings": "Warnings",
+    "artifacts": "Artifacts",
+
+    // Coverage
+    "lineCoverage": "Line coverage: %.1f%%",
+    "branchCoverage": "%s; branch coverage: %.1f%%",
+    "coverageTrend": "%s; from %.1f%% to %.1f%% over %d builds",
+
+    // Static analysis
+    "warningCount": "%d warnings",
+    "warningsTrend": "%s; from %d to %d over %d builds",
+
+    // Agents
+    "agentSummary": "%d of %d agents online, %d of %d executors busy",
+    "idle": "idle",
+    "busy": "busy",
+    "offline": "offline",
+    "offlineReason": "offline: %s",
+
+    // Job annotations
+    "duplicate": "duplicate",
+    "duplicateTitle": "This job is also listed elsewhere in this instance",
+    "stale": "stale",
+    "staleTitle": "No builds in the last %s",
+    "upstreamFailing": "upstream %s failing",
+
+    // Filters and pages
+    "filterJobs": "Filter jobs",
+    "filterStatus": "Filter by status",
+    "allJobs": "All jobs",
+    "failing": "Failing",
+    "passing": "Passing",
+    "staleStatus": "Stale",
+    "pages": "Pages",
+    "pageOf": "Page %d of %d:",
+    "previousPage": "« Previous",
+    "nextPage": "Next »",
+
+    // Instances
+    "unreachableBanner": "This instance could not be reached, so its last-known jobs are shown: %s.",
+    "unreachable": "Unreachable; %s",
+    "refreshed": "Refreshed %s ago",
+    "jobsTracked": "%d jobs tracked",
+    "failingCount": "%d failing",
+    "authFailureCount": "%d authentication failures",
+    "fetchErrorCount": "%d fetch errors",
+}
+
+// catalogs maps locales to their built-in catalogs.
+var catalogs = map[string]Messages{
+    "en": english,
+    "de": german,
+    "fr": french,
+}
+
+// Text returns the message with the given ID formatted with the given arguments. Messages that the catalog does not
+// translate are shown in English.
+func (m Messages) Text(id string, a ...interface{}) string {
+    format, ok := m[id]
+    if !ok {
+        format = english[id]
+    }
+    if len(a) == 0 {
+        return format
+    }
+    return fmt.Sprintf(format, a...)
+}
+
+// text returns the message with the given ID in the options' catalog.
+func (options Options) text(id string, a ...interface{}) string {
+    return options.Messages.Text(id, a...)
+}
+
+// verbPattern matches the formatting verbs of a message, including escaped percent signs.
+var verbPattern = regexp.MustCompile(`%[-+# 0]*[0-9]*(\.[0-9]+)?[a-zA-Z%]`)
+
+// verbs returns the formatting verbs of the given message in order.
+func verbs(message string) string {
+    var vs []string
+    for _, v := range verbPattern.FindAllString(message, -1) {
+        if v != "%%" {
+            vs = append(vs, v)
+        }
+    }
+    return strings.Join(vs, " ")
+}
+
+// ParseMessages parses the locale and message overrides of a configuration:
+//
+//     "locale": "de",
+//     "messages": {"brokenBuilds": "Kaputte Builds", "failures": "%d Fehler"}
+//
+// The locale selects a built-in catalog (en, de, or fr); a region suffix such as "de-CH" falls back to its language.
+// Overrides replace individual messages of the selected catalog and must take the same arguments as the English
+// messages they replace.
+func ParseMessages(config jenkins.JsonObject) (Messages, error) {
+    m := Messages{}
+    if locale, ok := config.GetString("locale"); ok {
+        catalog, ok := catalogs[locale]
+        if !ok {
+            language, _, _ := strings.Cut(locale, "-")
+            catalog, ok = catalogs[language]
+        }
+        if !ok {
+            return nil, errors.New(fmt.Sprintf("unknown locale %s", locale))
+        }
+        for id, message := range catalog {
+            m[id] = message
+        }
+    }
+
+    overrides, _ := config.GetObject("messages")
+    for id := range overrides {
+        message, ok := overrides.GetString(id)
+        if !ok {
+            return nil, errors.New(fmt.Sprintf("message %s is not a string", id))
+        }
+        englishMessage, ok := english[id]
+        if !ok {
+            return nil, errors.New(fmt.Sprintf("unknown message %s", id))
+        }
+        if want := verbs(englishMessage); verbs(message) != want {
+            return nil, errors.New(fmt.Sprintf("message %s must contain the formatting verbs \"%s\"", id, want))
+        }
+        m[id] = message
+    }
+    return m, nil
+}
@@ -0,0 +1,67 @@
+package render
+
+import (
+    "bytes"
+    "fmt"
+    "html"
+    "io"
+    "time"
+
+    "github.com/pgavlin/jitdash/pkg/jenkins"
+)
+
+// overviewStyle styles the instance cards of the overview page.
+const overviewStyle = `div.card { display: inline-block; vertical-align: top; width: 14em; margin: 0.5em; padding: 0.5em 1em; border: 1px solid #e0e0e0; border-radius: 4px }
+div.card h3 { margin: 0.25em 0 }
+div.card p { margin: 0.25em 0 }
+@media (max-width: 600px) {
+    div.card { display: block; width: auto }
+}
+`
+
+// Overview renders a landing page with a summary card per instance. Each card links to the instance's own page, whose
+// URL is given by pageUrl.
+func Overview(w io.Writer, instances []*jenkins.InstanceJobs, options Options, pageUrl func(instance string) string) error {
+    b := new(bytes.Buffer)
+    printf := func(format string, a ...interface{}) {
+        fmt.Fprintf(b, format, a...)
+    }
+
+    printf("<html><head>%s<style>%s%s</style></head><body>\n", head(options), stylesheet(options.EventsUrl != ""), overviewStyle)
+
+    summary := Summarize(instances)
+    for n, s := range summary.Instances {
+        ij := instances[n]
+
+        color := CellColors["success"]
+        if s.Failing != 0 {
+            color = CellColors["failure"]
+        }
+
+        printf("<div class=\"card\" style=\"border-top: 4px solid %s\">\n", color)
+        printf("<h3><a href=\"%s\">%s</a></h3>\n", html.EscapeString(pageUrl(s.Name)), html.EscapeString(s.Name))
+        printf("<p>%s</p>\n", html.EscapeString(options.text("jobsTracked", s.Jobs)))
+        printf("<p style=\"color: %s\">%s</p>\n", color, html.EscapeString(options.text("failingCount", s.Failing)))
+        if s.AuthErrors != 0 {
+            printf("<p style=\"color: %s; font-weight: bold\">%s</p>\n", CellColors["failure"], html.EscapeString(options.text("authFailureCount", s.AuthErrors)))
+        }
+        if s.Errors != 0 {
+            printf("<p>%s</p>\n", html.EscapeString(options.text("fetchErrorCount", s.Errors)))
+        }
+        if note := staleNote(ij, options); note != "" {
+            printf("<p style=\"color: %s\">%s</p>\n", CellColors["unstable"], html.EscapeString(options.text("unreachable", note)))
+        } else if !ij.FetchedAt.IsZero() {
+            printf("<p title=\"%s\">%s</p>\n", ij.FetchedAt.In(options.location()).Format(timeLayout),
+                html.EscapeString(options.text("refreshed", Humanize(time.Since(ij.FetchedAt)))))
+        }
+        printf("</div>\n")
+    }
+
+    if options.EventsUrl != "" {
+        printf(liveUpdateScript, options.EventsUrl)
+    }
+    printf("</body></html>\n")
+
+    _, err := w.Write(b.Bytes())
+    return err
+}
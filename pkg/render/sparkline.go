@@ -0,0 +1,296 @@
+package render
+
+import (
+    "fmt"
+    "strings"
+    "time"
+
+    "github.com/pgavlin/jitdash/pkg/jenkins"
+)
+
+var sparks = []rune{'▁', '▂', '▃', '▄', '▅', '▆', '▇', '█'}
+
+// progressSparks show the estimated progress of running builds, from just started to overdue.
+var progressSparks = []rune{'○', '◔', '◑', '◕', '●'}
+
+// A Cell describes how a single build is drawn in a sparkline. Sparklines may end with a cell for a queued build, which
+// links to its job.
+type Cell struct {
+    Build *jenkins.Build // the build, or nil for padding and queued builds
+    Url string // the URL the cell links to, or empty for padding
+    Spark rune
+    Title string // a description of the build's outcome
+    Class string // the build's outcome: success, unstable, failure, known, aborted, not-built, building, queued, or unknown
+}
+
+// shapes maps the classes of completed builds to the runes that represent them when outcomes are distinguished by
+// shape. The cells of running and queued builds already have distinct shapes.
+var shapes = map[string]rune{
+    "success": '✓',
+    "unstable": '▲',
+    "failure": '✗',
+    "known": '◆',
+    "aborted": '■',
+    "not-built": '□',
+}
+
+// cellClasses lists the cell classes in the order in which their styles are emitted.
+var cellClasses = []string{"success", "unstable", "failure", "known", "aborted", "not-built", "building", "queued", "unknown"}
+
+// CellColors maps cell classes to their colors.
+var CellColors = map[string]string{
+    "success": "#2e7d32",
+    "unstable": "#f9a825",
+    "failure": "#c62828",
+    "known": "#ef6c00",
+    "aborted": "#757575",
+    "not-built": "#bdbdbd",
+    "building": "#1565c0",
+    "queued": "#6a1b9a",
+    "unknown": "#6d4c41",
+}
+
+// viewport asks mobile browsers to lay pages out at the device's width rather than zooming out on a desktop-sized page.
+const viewport = `<meta name="viewport" content="width=device-width, initial-scale=1">`
+
+// offlineHead declares the page's encoding, which a page read from a file cannot take from a Content-Type header, and
+// forbids the page from loading anything from the network. Links to other pages still work. The empty favicon keeps
+// browsers from requesting one.
+const offlineHead = `<meta charset="utf-8">` +
+    `<meta http-equiv="Content-Security-Policy" content="default-src 'none'; style-src 'unsafe-inline'; script-src 'unsafe-inline'; img-src data:">` +
+    `<link rel="icon" href="data:,">`
+
+// head returns the elements that precede the stylesheet in the head of a page.
+func head(options Options) string {
+    if options.Offline {
+        return offlineHead + viewport
+    }
+    return viewport
+}
+
+// smallScreenStyle collapses the secondary columns of tables on narrow screens, stacks each job's name above its
+// history, and enlarges the sparklines so that individual builds can be tapped.
+const smallScreenStyle = `@media (max-width: 600px) {
+    body { margin: 4px }
+    table { width: 100% }
+    .secondary { display: none }
+    table.jobs tr, table.jobs td { display: block }
+    table.jobs tr:first-child { display: none }
+    table.jobs tr { padding: 4px 0; border-bottom: 1px solid #e0e0e0 }
+    td.sparkline { font-size: 18px; word-break: break-all }
+    div.filter input, div.filter select { width: 100%; font-size: 16px; margin: 2px 0 }
+}
+`
+
+// stylesheet returns the stylesheet for HTML pages. Sparkline cells are colored by class and stale jobs are greyed out.
+// On small screens, tables collapse to their essential columns. If animate is true, the cells of running builds pulse.
+func stylesheet(animate bool) string {
+    b := new(strings.Builder)
+    b.WriteString("td.sparkline { font-family: \"Consolas, \\\"Liberation Mono\\\", Menlo, Courier, monospace\"; font-size: 12px }\n")
+    b.WriteString("td.sparkline a { text-decoration: none }\n")
+    for _, c := range cellClasses {
+        fmt.Fprintf(b, "td.sparkline a.%s { color: %s }\n", c, CellColors[c])
+    }
+    b.WriteString("tr.stale td { color: #9e9e9e; font-style: italic }\n")
+    fmt.Fprintf(b, "ul.auth { color: %s; font-weight: bold }\n", CellColors["failure"])
+    b.WriteString("p.stale-instance { padding: 4px 8px; background: #fff8e1; border-left: 4px solid #f9a825 }\n")
+    b.WriteString("span.upstream { font-size: 11px; color: #757575 }\n")
+    b.WriteString("span.category { font-size: 11px; padding: 0 4px; border-radius: 3px; background: #eeeeee }\n")
+    b.WriteString("nav.pages { margin: 0.5em 0 }\nnav.pages a, nav.pages span { margin: 0 0.25em }\n")
+    b.WriteString(smallScreenStyle)
+    if animate {
+        b.WriteString("@keyframes pulse { 50% { opacity: 0.3 } }\n")
+        b.WriteString("td.sparkline a.building { animation: pulse 2s ease-in-out infinite }\n")
+    }
+    return b.String()
+}
+
+// ShortCommit abbreviates a commit hash for display.
+func ShortCommit(commit string) string {
+    if len(commit) > 7 {
+        return commit[:7]
+    }
+    return commit
+}
+
+// historyLength returns the number of builds to render for the given job of the given instance. Jobs of instances with
+// a window render every build in the window.
+func historyLength(i *jenkins.Instance, job *jenkins.Job, options Options) int {
+    if i.Window != 0 {
+        return len(job.Builds)
+    }
+    _, count := i.JobLimits(job.Name, options.MaxBuilds, options.MaxHistory)
+    return count
+}
+
+// jobCells computes the sparkline cells for the given job of the given instance. Builds whose failures are all known
+// failures are marked as such, the titles of classified failed builds are tagged with their categories, and the titles
+// of builds list the instance's shown parameters. Cells link to the instance's display URLs. If options.Shapes is set,
+// the cells of completed builds are drawn as the shapes of their outcomes rather than as bars.
+func jobCells(i *jenkins.Instance, job *jenkins.Job, options Options) []Cell {
+    cells := Cells(job, historyLength(i, job, options), i.JobScale(job.Name, options.Scale), options.location(), options.Messages)
+    for n, c := range cells {
+        if c.Build != nil {
+            cells[n].Url = i.BuildLink(job.Name, c.Build)
+        }
+        cells[n].Url = i.DisplayUrl(cells[n].Url)
+        if c.Build == nil {
+            continue
+        }
+
+        if parameters := i.ShownParameters(c.Build); len(parameters) != 0 {
+            var lines []string
+            for _, p := range parameters {
+                lines = append(lines, p.Name + "=" + p.Value)
+            }
+            title, started, _ := strings.Cut(c.Title, "\n")
+            cells[n].Title = title + "\n" + strings.Join(lines, "\n")
+            if started != "" {
+                cells[n].Title += "\n" + started
+            }
+            c = cells[n]
+        }
+
+        if category := i.Classify(c.Build); category != "" {
+            cells[n].Title = "[" + category + "] " + c.Title
+            c = cells[n]
+        }
+
+        known := i.KnownFailures(job.Name, c.Build)
+        if len(known) == 0 {
+            continue
+        }
+
+        // The annotations precede the line that gives the build's start time.
+        title, started, _ := strings.Cut(c.Title, "\n")
+        title = options.text("knownFailure", title)
+        for _, k := range known {
+            title += "; " + k.Reason
+            if k.Ticket != "" {
+                title += " (" + k.Ticket + ")"
+            }
+        }
+        if started != "" {
+            title += "\n" + started
+        }
+        cells[n].Title, cells[n].Class = title, "known"
+    }
+
+    if options.Shapes {
+        for n, c := range cells {
+            switch shape, ok := shapes[c.Class]; {
+            case ok:
+                cells[n].Spark = shape
+            case c.Class == "":
+                cells[n].Spark = '·'
+            }
+        }
+    }
+    return cells
+}
+
+// Cells computes the sparkline cells for the most recent count builds of the given job. If the job has fewer than count
+// builds, the sparkline is padded on the left. If the job is queued, a cell for the queued build is appended. Failure
+// counts are mapped to cell heights according to the given scale. Each build's title ends with the time at which it
+// started in the given timezone, and titles are taken from the given message catalog.
+func Cells(job *jenkins.Job, count int, scale jenkins.Scale, location *time.Location, messages Messages) []Cell {
+    var cells []Cell
+    for ; count > len(job.Builds); count-- {
+        cells = append(cells, Cell{Spark: sparks[0]})
+    }
+
+    start := len(job.Builds) - count
+
+    max := scale.Max
+    if max == 0 {
+        for i := start; i < len(job.Builds); i++ {
+            if f := job.Builds[i].Failures; f > max {
+                max = f
+            }
+        }
+    }
+
+    for i := start; i < len(job.Builds); i++ {
+        build := job.Builds[i]
+
+        var spark rune
+        var title, class string
+        if build.Err != nil {
+            spark, title, class = '?', messages.Text("unknownBuild", build.Err.Error()), "unknown"
+        } else if build.Complete {
+            switch f := build.Failures; {
+            case build.Result == jenkins.ResultAborted:
+                spark, title, class = '×', messages.Text("aborted"), "aborted"
+
+            case build.Result == jenkins.ResultNotBuilt:
+                spark, title, class = '·', messages.Text("notBuilt"), "not-built"
+
+            case f <= 0 && build.Result == jenkins.ResultUnstable:
+                spark, title, class = sparks[1], messages.Text("unstableBuild"), "unstable"
+                if build.FailedStage != "" {
+                    title = messages.Text("inStage", title, build.FailedStage)
+                }
+
+            case f == 0:
+                spark, title, class = sparks[0], messages.Text("passed"), "success"
+                if build.Tests != 0 {
+                    title = messages.Text("passedTests", build.Tests)
+                }
+
+            case f == -1:
+                spark, title, class = sparks[len(sparks) - 1], messages.Text("failed"), "failure"
+                if build.FailedStage != "" {
+                    title = messages.Text("inStage", title, build.FailedStage)
+                }
+
+            default:
+                spark = sparks[1 + int(scale.Fraction(f, max) * float64(len(sparks) - 2))]
+                title, class = messages.Text("failures", f), "failure"
+                if build.Tests != 0 {
+                    title = messages.Text("testsFailed", f, build.Tests)
+                }
+                if newFailures, ok := job.NewFailures(i); ok {
+                    title = messages.Text("newFailures", title, newFailures)
+                }
+                if build.FailedStage != "" {
+                    title = messages.Text("inStage", title, build.FailedStage)
+                }
+                if build.Result == jenkins.ResultUnstable {
+                    title, class = messages.Text("unstable", title), "unstable"
+                }
+            }
+        } else if progress, ok := build.Progress(); ok {
+            spark, class = progressSparks[int(progress * float64(len(progressSparks) - 1))], "building"
+            title = messages.Text("buildingProgress", int(progress * 100))
+            if remaining := build.EstimatedDuration - time.Since(build.Timestamp); remaining > 0 {
+                title = messages.Text("remaining", title, Humanize(remaining))
+            } else {
+                title = messages.Text("overdue", title)
+            }
+        } else {
+            spark, title, class = 'B', messages.Text("building"), "building"
+        }
+
+        if build.Complete && build.Skipped != 0 {
+            title = messages.Text("skipped", title, build.Skipped)
+        }
+        if build.Commit != "" || build.Change != "" {
+            title += ": " + strings.TrimSpace(ShortCommit(build.Commit) + " " + build.Change)
+        }
+        if !build.Timestamp.IsZero() {
+            title += "\n" + messages.Text("started", localTime(build, location), Humanize(time.Since(build.Timestamp)))
+        }
+
+        cells = append(cells, Cell{build, build.Url, spark, title, class})
+    }
+
+    if job.Queued {
+        title := messages.Text("queued")
+        if job.QueuedWhy != "" {
+            title = messages.Text("queuedWhy", job.QueuedWhy)
+        }
+        cells = append(cells, Cell{Url: job.Url, Spark: 'Q', Title: title, Class: "queued"})
+    }
+
+    return cells
+}
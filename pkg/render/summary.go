@@ -0,0 +1,97 @@
+package render
+
+import (
+    "strings"
+
+    "github.com/pgavlin/jitdash/pkg/jenkins"
+)
+
+// InstanceSummary summarizes the state of an instance's jobs.
+type InstanceSummary struct {
+    Name string `json:"name"`
+    Jobs int `json:"jobs"`
+    Failing int `json:"failing"`
+    FailingJobs []string `json:"failingJobs"`
+    Errors int `json:"errors"`
+    AuthErrors int `json:"authErrors"` // the number of errors due to missing or rejected credentials
+    NotFound int `json:"notFound"` // the number of errors due to jobs, folders, or views that do not exist
+    Categories map[string]int `json:"categories,omitempty"` // the number of failing jobs per failure category
+}
+
+// DashboardSummary summarizes the state of all instances.
+type DashboardSummary struct {
+    Instances []InstanceSummary `json:"instances"`
+    Failing int `json:"failing"`
+    Errors int `json:"errors"`
+    AuthErrors int `json:"authErrors"`
+    NotFound int `json:"notFound"`
+    Categories map[string]int `json:"categories,omitempty"`
+}
+
+// Summarize computes the summary of the given instances. Failing jobs are counted per the category of their most recent
+// completed build; see jenkins.Instance.Classify.
+func Summarize(instances []*jenkins.InstanceJobs) DashboardSummary {
+    summary := DashboardSummary{Instances: []InstanceSummary{}}
+    for _, ij := range instances {
+        s := InstanceSummary{Name: ij.Instance.Name, Jobs: len(ij.Jobs), FailingJobs: []string{}, Errors: len(ij.Errors)}
+        for _, j := range ij.Jobs {
+            if j.Failing() {
+                s.Failing++
+                s.FailingJobs = append(s.FailingJobs, j.Name)
+                if category := ij.Instance.Classify(j.LastCompletedBuild()); category != "" {
+                    if s.Categories == nil {
+                        s.Categories = make(map[string]int)
+                    }
+                    s.Categories[category]++
+                }
+            }
+        }
+        for _, e := range ij.Errors {
+            switch {
+            case e.IsAuthFailure():
+                s.AuthErrors++
+            case e.IsNotFound():
+                s.NotFound++
+            }
+        }
+
+        summary.Instances = append(summary.Instances, s)
+        summary.Failing += s.Failing
+        summary.Errors += s.Errors
+        summary.AuthErrors += s.AuthErrors
+        summary.NotFound += s.NotFound
+        for category, n := range s.Categories {
+            if summary.Categories == nil {
+                summary.Categories = make(map[string]int)
+            }
+            summary.Categories[category] += n
+        }
+    }
+    return summary
+}
+
+// splitAuthFailures returns the fetch errors of the given instances that are due to missing or rejected credentials,
+// followed by the others.
+func splitAuthFailures(instances []*jenkins.InstanceJobs) ([]*jenkins.FetchError, []*jenkins.FetchError) {
+    var auth, other []*jenkins.FetchError
+    for _, ij := range instances {
+        for _, e := range ij.Errors {
+            if e.IsAuthFailure() {
+                auth = append(auth, e)
+            } else {
+                other = append(other, e)
+            }
+        }
+    }
+    return auth, other
+}
+
+// failingUpstream returns the comma-separated labels of the failing jobs upstream of the given job that likely caused
+// it to fail, or the empty string if there are none.
+func failingUpstream(ij *jenkins.InstanceJobs, job *jenkins.Job) string {
+    var labels []string
+    for _, u := range ij.FailingUpstream(job) {
+        labels = append(labels, u.Label())
+    }
+    return strings.Join(labels, ", ")
+}
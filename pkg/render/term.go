@@ -0,0 +1,134 @@
+package render
+
+import (
+    "bytes"
+    "fmt"
+    "io"
+    "strings"
+    "unicode/utf8"
+
+    "github.com/pgavlin/jitdash/pkg/jenkins"
+)
+
+// ansiColors maps cell classes to ANSI foreground color codes.
+var ansiColors = map[string]int{
+    "success": 32,
+    "unstable": 33,
+    "failure": 31,
+    "known": 91,
+    "aborted": 90,
+    "not-built": 37,
+    "building": 34,
+    "queued": 35,
+    "unknown": 95,
+}
+
+// hyperlink wraps text in an OSC 8 escape sequence that links it to the given URL. Terminals that do not support
+// hyperlinks show the text alone.
+func hyperlink(url, text string) string {
+    return "\x1b]8;;" + url + "\x1b\\" + text + "\x1b]8;;\x1b\\"
+}
+
+// Term renders the dashboard for a terminal: a section per instance, with each job's name followed by its sparkline,
+// its values in the instance's extra columns, its coverage and warnings if options ask for them, and links to the shown
+// artifacts of its last successful build. Builds are colored by outcome and both job names and builds link to their
+// pages.
+func Term(w io.Writer, instances []*jenkins.InstanceJobs, options Options) error {
+    b := new(bytes.Buffer)
+    printf := func(format string, a ...interface{}) {
+        fmt.Fprintf(b, format, a...)
+    }
+
+    authErrs, errs := splitAuthFailures(instances)
+    for _, e := range authErrs {
+        printf("\x1b[31m%s:\x1b[0m %s\n", options.text("error"), e.Error())
+    }
+    for _, e := range errs {
+        printf("\x1b[33m%s:\x1b[0m %s\n", options.text("warning"), e.Error())
+    }
+
+    for _, ij := range instances {
+        i := ij.Instance
+        printf("\x1b[1m%s\x1b[0m", i.Name)
+        if note := staleNote(ij, options); note != "" {
+            printf("  \x1b[33m(%s)\x1b[0m", options.text("unreachable", note))
+        }
+        printf("\n")
+
+        var visible []*jenkins.Job
+        width := 0
+        for _, job := range ij.Jobs {
+            if !options.OnlyFailing || job.Failing() {
+                visible = append(visible, job)
+                if n := utf8.RuneCountInString(job.Label()); n > width {
+                    width = n
+                }
+            }
+        }
+
+        groups := i.GroupNames(visible)
+        for _, g := range groups {
+            var groupJobs []*jenkins.Job
+            for _, job := range visible {
+                if job.Group == g {
+                    groupJobs = append(groupJobs, job)
+                }
+            }
+            jenkins.SortJobs(groupJobs, options.Sort)
+
+            if len(groups) > 1 {
+                groupName := g
+                if groupName == "" {
+                    groupName = options.text("other")
+                }
+                printf("  \x1b[4m%s\x1b[0m\n", groupName)
+            }
+
+            for _, job := range groupJobs {
+                printf("    %s%*s  ", hyperlink(i.DisplayUrl(job.Url), job.Label()), width - utf8.RuneCountInString(job.Label()), "")
+                for _, c := range jobCells(i, job, options) {
+                    if c.Url == "" {
+                        printf("\x1b[%dm%c\x1b[0m", ansiColors["not-built"], c.Spark)
+                        continue
+                    }
+                    printf("\x1b[%dm%s\x1b[0m", ansiColors[c.Class], hyperlink(c.Url, string(c.Spark)))
+                }
+                for _, c := range i.Columns {
+                    if value := job.Column(c.Name); value != "" {
+                        printf("  %s: %s", c.Name, value)
+                    }
+                }
+                if options.Coverage != "" {
+                    if spark, text, _ := coverage(i, job, options); text != "" {
+                        printf("  %s: %s", options.text("coverage"), strings.TrimSpace(spark + " " + text))
+                    }
+                }
+                if options.AnalysisWarnings != "" {
+                    if spark, text, _ := analysisWarnings(i, job, options); text != "" {
+                        printf("  %s: %s", options.text("analysisWarnings"), strings.TrimSpace(spark + " " + text))
+                    }
+                }
+                if links := artifactLinks(i, job, options); len(links) != 0 {
+                    printf("  %s:", options.text("artifacts"))
+                    for _, l := range links {
+                        printf(" %s", hyperlink(l.Url, l.Name))
+                    }
+                }
+                if job.Duplicate {
+                    printf("  (%s)", options.text("duplicate"))
+                }
+                if job.Stale(options.StaleAfter) {
+                    printf("  (%s)", options.text("stale"))
+                }
+                if upstream := failingUpstream(ij, job); upstream != "" {
+                    printf("  (%s)", options.text("upstreamFailing", upstream))
+                }
+                printf("\n")
+            }
+        }
+        printf("\n")
+    }
+
+    _, err := w.Write(b.Bytes())
+    return err
+}
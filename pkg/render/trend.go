@@ -0,0 +1,52 @@
+package render
+
+import (
+    "strings"
+
+    "github.com/pgavlin/jitdash/pkg/jenkins"
+)
+
+// Ways of showing a per-build metric of jobs, such as their coverage, alongside their histories.
+const (
+    TrendColumn = "column" // a column with each job's latest value and its change since the previous build that reported it
+    TrendSparkline = "sparkline" // the column, with the latest value preceded by a sparkline of the values in the job's history
+)
+
+// trendBuilds returns the completed builds in the job's history that report the metric, oldest first.
+func trendBuilds(i *jenkins.Instance, job *jenkins.Job, options Options, reports func(b *jenkins.Build) bool) []*jenkins.Build {
+    start := len(job.Builds) - historyLength(i, job, options)
+    if start < 0 {
+        start = 0
+    }
+
+    var builds []*jenkins.Build
+    for _, b := range job.Builds[start:] {
+        if b.Complete && b.Err == nil && reports(b) {
+            builds = append(builds, b)
+        }
+    }
+    return builds
+}
+
+// trendSparkline draws the given values, scaled to their range so that small changes are visible. Constant values are
+// drawn at half height.
+func trendSparkline(values []float64) string {
+    min, max := values[0], values[0]
+    for _, v := range values {
+        if v < min {
+            min = v
+        } else if v > max {
+            max = v
+        }
+    }
+
+    var spark strings.Builder
+    for _, v := range values {
+        n := len(sparks) / 2
+        if max > min {
+            n = int((v - min) / (max - min) * float64(len(sparks) - 1) + 0.5)
+        }
+        spark.WriteRune(sparks[n])
+    }
+    return spark.String()
+}
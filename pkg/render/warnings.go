@@ -0,0 +1,47 @@
+package render
+
+import (
+    "fmt"
+    "sort"
+    "strings"
+
+    "github.com/pgavlin/jitdash/pkg/jenkins"
+)
+
+// analysisWarnings returns the job's static-analysis warnings as shown in its warnings column and a description of
+// them: the sparkline of the warning counts of the job's history, if options.AnalysisWarnings asks for one, and the
+// latest count with its change since the previous build that reported warnings. All are empty if no build in the job's
+// history reports warnings.
+func analysisWarnings(i *jenkins.Instance, job *jenkins.Job, options Options) (spark, text, title string) {
+    builds := trendBuilds(i, job, options, func(b *jenkins.Build) bool { return b.Warnings != nil })
+    if len(builds) == 0 {
+        return "", "", ""
+    }
+
+    latest := builds[len(builds) - 1]
+    text = fmt.Sprint(latest.WarningCount())
+    if len(builds) > 1 {
+        if change := latest.WarningCount() - builds[len(builds) - 2].WarningCount(); change != 0 {
+            text += fmt.Sprintf(" (%+d)", change)
+        }
+    }
+
+    title = options.text("warningCount", latest.WarningCount())
+    if len(latest.Warnings) != 0 {
+        var tools []string
+        for tool, n := range latest.Warnings {
+            tools = append(tools, fmt.Sprintf("%s: %d", tool, n))
+        }
+        sort.Strings(tools)
+        title += " (" + strings.Join(tools, ", ") + ")"
+    }
+    if options.AnalysisWarnings == TrendSparkline {
+        values := make([]float64, len(builds))
+        for n, b := range builds {
+            values[n] = float64(b.WarningCount())
+        }
+        spark = trendSparkline(values)
+        title = options.text("warningsTrend", title, builds[0].WarningCount(), latest.WarningCount(), len(builds))
+    }
+    return spark, text, title
+}
@@ -0,0 +1,137 @@
+package secrets
+
+import (
+    "bytes"
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "net/url"
+    "os"
+    "sort"
+    "strings"
+    "time"
+)
+
+// AWSSecretsManager is a secret stored in AWS Secrets Manager. Requests are signed with the credentials in the
+// standard AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, and AWS_SESSION_TOKEN environment variables.
+type AWSSecretsManager struct {
+    Region string
+    SecretId string // the name or ARN of the secret
+    Key string // if non-empty, the secret is a JSON object and the value is its field with this name
+    Endpoint string // the URL of the Secrets Manager API, or empty to use the region's public endpoint
+    Client *http.Client
+}
+
+func (s *AWSSecretsManager) Secret() (string, error) {
+    endpoint := s.Endpoint
+    if endpoint == "" {
+        endpoint = fmt.Sprintf("https://secretsmanager.%s.amazonaws.com/", s.Region)
+    }
+
+    body, err := json.Marshal(map[string]string{"SecretId": s.SecretId})
+    if err != nil {
+        return "", err
+    }
+    req, err := http.NewRequest("POST", endpoint, bytes.NewReader(body))
+    if err != nil {
+        return "", err
+    }
+    req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+    req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+    if err := signV4(req, body, s.Region, "secretsmanager", time.Now()); err != nil {
+        return "", err
+    }
+
+    resp, err := s.Client.Do(req)
+    if err != nil {
+        return "", err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        return "", errors.New(fmt.Sprintf("could not read %s from AWS Secrets Manager: %s", s.SecretId, resp.Status))
+    }
+
+    var result struct {
+        SecretString string
+    }
+    if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+        return "", err
+    }
+    if s.Key == "" {
+        return result.SecretString, nil
+    }
+
+    var object map[string]interface{}
+    if err := json.Unmarshal([]byte(result.SecretString), &object); err != nil {
+        return "", errors.New(fmt.Sprintf("secret %s is not a JSON object: %s", s.SecretId, err))
+    }
+    return field(object, s.Key)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+    h := hmac.New(sha256.New, key)
+    h.Write([]byte(data))
+    return h.Sum(nil)
+}
+
+func sha256Hex(data []byte) string {
+    sum := sha256.Sum256(data)
+    return hex.EncodeToString(sum[:])
+}
+
+// signV4 signs the given request with AWS Signature Version 4 using the credentials in the environment.
+func signV4(req *http.Request, body []byte, region, service string, now time.Time) error {
+    accessKey, secretKey := os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY")
+    if accessKey == "" || secretKey == "" {
+        return errors.New("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set")
+    }
+
+    now = now.UTC()
+    amzDate, date := now.Format("20060102T150405Z"), now.Format("20060102")
+    req.Header.Set("X-Amz-Date", amzDate)
+    if token := os.Getenv("AWS_SESSION_TOKEN"); token != "" {
+        req.Header.Set("X-Amz-Security-Token", token)
+    }
+
+    headers := map[string]string{"host": req.URL.Host}
+    for k := range req.Header {
+        headers[strings.ToLower(k)] = strings.TrimSpace(req.Header.Get(k))
+    }
+    names := make([]string, 0, len(headers))
+    for k := range headers {
+        names = append(names, k)
+    }
+    sort.Strings(names)
+
+    var canonicalHeaders strings.Builder
+    for _, k := range names {
+        canonicalHeaders.WriteString(k + ":" + headers[k] + "\n")
+    }
+    signedHeaders := strings.Join(names, ";")
+
+    path := req.URL.EscapedPath()
+    if path == "" {
+        path = "/"
+    }
+    query, _ := url.ParseQuery(req.URL.RawQuery)
+    canonicalRequest := strings.Join([]string{
+        req.Method, path, strings.ReplaceAll(query.Encode(), "+", "%20"), canonicalHeaders.String(), signedHeaders, sha256Hex(body),
+    }, "\n")
+
+    scope := date + "/" + region + "/" + service + "/aws4_request"
+    stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+    key := hmacSHA256([]byte("AWS4" + secretKey), date)
+    key = hmacSHA256(key, region)
+    key = hmacSHA256(key, service)
+    key = hmacSHA256(key, "aws4_request")
+    signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+    req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+        accessKey, scope, signedHeaders, signature))
+    return nil
+}
@@ -0,0 +1,100 @@
+// Package secrets reads credentials from files and from external secret stores. Secrets are read when they are needed
+// rather than when the config is parsed, so rotated credentials are picked up without a restart.
+package secrets
+
+import (
+    "errors"
+    "fmt"
+    "os"
+    "strings"
+    "sync"
+    "time"
+)
+
+// A Source provides the current value of a secret.
+type Source interface {
+    Secret() (string, error)
+}
+
+// Literal is a secret whose value is given directly.
+type Literal string
+
+func (l Literal) Secret() (string, error) {
+    return string(l), nil
+}
+
+// File is a secret that is read from a file, e.g. one mounted from a Kubernetes secret. The file is read again
+// whenever its modification time changes. Leading and trailing whitespace is trimmed from its contents.
+type File struct {
+    Path string
+
+    m sync.Mutex
+    modTime time.Time
+    value string
+}
+
+// NewFile returns a source that reads the secret in the named file.
+func NewFile(path string) *File {
+    return &File{Path: path}
+}
+
+func (f *File) Secret() (string, error) {
+    info, err := os.Stat(f.Path)
+    if err != nil {
+        return "", err
+    }
+
+    f.m.Lock()
+    defer f.m.Unlock()
+
+    if !info.ModTime().Equal(f.modTime) {
+        contents, err := os.ReadFile(f.Path)
+        if err != nil {
+            return "", err
+        }
+        f.modTime, f.value = info.ModTime(), strings.TrimSpace(string(contents))
+    }
+    return f.value, nil
+}
+
+// cached caches the value of a secret that is expensive to read for a fixed time.
+type cached struct {
+    source Source
+    ttl time.Duration
+
+    m sync.Mutex
+    value string
+    expires time.Time
+}
+
+// Cached returns a source that reads the given source at most once per ttl.
+func Cached(source Source, ttl time.Duration) Source {
+    return &cached{source: source, ttl: ttl}
+}
+
+func (c *cached) Secret() (string, error) {
+    c.m.Lock()
+    defer c.m.Unlock()
+
+    if now := time.Now(); now.After(c.expires) {
+        value, err := c.source.Secret()
+        if err != nil {
+            return "", err
+        }
+        c.value, c.expires = value, now.Add(c.ttl)
+    }
+    return c.value, nil
+}
+
+// field returns the named string field of a secret stored as a JSON object.
+func field(object map[string]interface{}, name string) (string, error) {
+    v, ok := object[name]
+    if !ok {
+        return "", errors.New(fmt.Sprintf("secret has no field %s", name))
+    }
+    s, ok := v.(string)
+    if !ok {
+        return "", errors.New(fmt.Sprintf("secret field %s is not a string", name))
+    }
+    return s, nil
+}
@@ -0,0 +1,58 @@
+package secrets
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "strings"
+)
+
+// Vault is a secret stored in a field of a HashiCorp Vault secret. Both version 1 and version 2 of the key/value
+// secrets engine are supported.
+type Vault struct {
+    Address string // the base URL of the Vault server
+    Path string // the path of the secret, e.g. "secret/data/jenkins"
+    Field string // the field of the secret that holds the value
+    Token Source // the Vault token used to read the secret
+    Client *http.Client
+}
+
+func (v *Vault) Secret() (string, error) {
+    token, err := v.Token.Secret()
+    if err != nil {
+        return "", errors.New(fmt.Sprintf("could not read Vault token: %s", err))
+    }
+
+    url := strings.TrimSuffix(v.Address, "/") + "/v1/" + strings.TrimPrefix(v.Path, "/")
+    req, err := http.NewRequest("GET", url, nil)
+    if err != nil {
+        return "", err
+    }
+    req.Header.Set("X-Vault-Token", token)
+
+    resp, err := v.Client.Do(req)
+    if err != nil {
+        return "", err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        return "", errors.New(fmt.Sprintf("could not read %s from Vault: %s", v.Path, resp.Status))
+    }
+
+    var body struct {
+        Data map[string]interface{} `json:"data"`
+    }
+    if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+        return "", err
+    }
+
+    // Version 2 of the key/value engine nests the secret's fields within the response's data alongside its metadata.
+    data := body.Data
+    if nested, ok := data["data"].(map[string]interface{}); ok {
+        if _, ok := data["metadata"]; ok {
+            data = nested
+        }
+    }
+    return field(data, v.Field)
+}
@@ -0,0 +1,221 @@
+package server
+
+import (
+    "bytes"
+    "encoding/json"
+    "net/http"
+    "net/url"
+    "strings"
+    "time"
+
+    "github.com/pgavlin/jitdash/pkg/jenkins"
+)
+
+type apiInstance struct {
+    Name string `json:"name"`
+    Jobs int `json:"jobs"`
+    Failing int `json:"failing"`
+    Errors []string `json:"errors"`
+    StaleSince string `json:"staleSince,omitempty"` // if the instance could not be reached, when its jobs were fetched
+}
+
+type apiBuild struct {
+    Id int64 `json:"id"`
+    Url string `json:"url"`
+    Timestamp string `json:"timestamp,omitempty"` // the time at which the build started in RFC 3339 format
+    Failures int64 `json:"failures"`
+    Tests int64 `json:"tests,omitempty"`
+    Skipped int64 `json:"skipped,omitempty"`
+    Complete bool `json:"complete"`
+    Result jenkins.Result `json:"result"`
+    Commit string `json:"commit,omitempty"`
+    Change string `json:"change,omitempty"`
+    Culprits []string `json:"culprits,omitempty"`
+    Parameters map[string]string `json:"parameters,omitempty"` // the build's parameters that the instance shows
+    FailedStage string `json:"failedStage,omitempty"` // for pipeline builds, the first stage that failed
+    ConsoleTail string `json:"consoleTail,omitempty"` // the end of a failed build's console output, if captured
+    Category string `json:"category,omitempty"` // the failure category of a failed build, if it was classified
+    Error string `json:"error,omitempty"` // the error encountered while fetching the build's details, if any
+}
+
+type apiJob struct {
+    Instance string `json:"instance"`
+    Name string `json:"name"`
+    DisplayName string `json:"displayName,omitempty"`
+    Url string `json:"url"`
+    Group string `json:"group"`
+    Failing bool `json:"failing"`
+    Queued bool `json:"queued"`
+    Upstream []string `json:"upstream,omitempty"` // the URLs of the jobs that trigger the job
+    Downstream []string `json:"downstream,omitempty"` // the URLs of the jobs the job triggers
+    LastBuild *apiBuild `json:"lastBuild"`
+}
+
+type apiStats struct {
+    Instance string `json:"instance"`
+    Job string `json:"job"`
+    Builds int `json:"builds"`
+    Failures int `json:"failures"`
+    FailureRate float64 `json:"failureRate"`
+    Recoveries int `json:"recoveries"`
+    MTTRSeconds float64 `json:"mttrSeconds"`
+    LongestRedStreak int `json:"longestRedStreak"`
+}
+
+func newApiBuild(i *jenkins.Instance, b *jenkins.Build) *apiBuild {
+    if b == nil {
+        return nil
+    }
+    var parameters map[string]string
+    for _, p := range i.ShownParameters(b) {
+        if parameters == nil {
+            parameters = make(map[string]string)
+        }
+        parameters[p.Name] = p.Value
+    }
+    var timestamp string
+    if !b.Timestamp.IsZero() {
+        timestamp = b.Timestamp.Format(time.RFC3339)
+    }
+    var err string
+    if b.Err != nil {
+        err = b.Err.Error()
+    }
+    return &apiBuild{b.Id, b.Url, timestamp, b.Failures, b.Tests, b.Skipped, b.Complete, b.Result, b.Commit, b.Change, b.Culprits, parameters, b.FailedStage,
+        b.ConsoleTail, i.Classify(b), err}
+}
+
+func writeJson(w http.ResponseWriter, r *http.Request, v interface{}) {
+    b := new(bytes.Buffer)
+    json.NewEncoder(b).Encode(v)
+    writeResponse(w, r, "application/json", b.Bytes())
+}
+
+// serveApiInstances lists the configured instances along with summary counts.
+func (s *Server) serveApiInstances(w http.ResponseWriter, r *http.Request) {
+    s.m.RLock()
+    defer s.m.RUnlock()
+
+    instances := []apiInstance{}
+    for _, ij := range s.visibleInstances(r) {
+        i := apiInstance{Name: ij.Instance.Name, Jobs: len(ij.Jobs), Errors: []string{}}
+        if ij.Stale {
+            i.StaleSince = ij.FetchedAt.Format(time.RFC3339)
+        }
+        for _, j := range ij.Jobs {
+            if j.Failing() {
+                i.Failing++
+            }
+        }
+        for _, e := range ij.Errors {
+            i.Errors = append(i.Errors, e.Error())
+        }
+        instances = append(instances, i)
+    }
+    writeJson(w, r, instances)
+}
+
+// serveApiJobs lists the jobs of every instance, or of the instance named by the instance query parameter.
+func (s *Server) serveApiJobs(w http.ResponseWriter, r *http.Request) {
+    instance := r.URL.Query().Get("instance")
+
+    s.m.RLock()
+    defer s.m.RUnlock()
+
+    jobs := []apiJob{}
+    for _, ij := range s.visibleInstances(r) {
+        if instance != "" && ij.Instance.Name != instance {
+            continue
+        }
+        for _, j := range ij.Jobs {
+            var last *jenkins.Build
+            if len(j.Builds) != 0 {
+                last = j.Builds[len(j.Builds) - 1]
+            }
+            jobs = append(jobs, apiJob{ij.Instance.Name, j.Name, j.DisplayName, j.Url, j.Group, j.Failing(), j.Queued, j.Upstream, j.Downstream,
+                newApiBuild(ij.Instance, last)})
+        }
+    }
+    writeJson(w, r, jobs)
+}
+
+// serveApiBuilds lists the builds of a single job, oldest first. The request path has the form
+// "/api/v1/jobs/{instance}/{job}/builds"; instance and job names that contain slashes must be escaped.
+func (s *Server) serveApiBuilds(w http.ResponseWriter, r *http.Request) {
+    segments := strings.Split(strings.TrimPrefix(r.URL.EscapedPath(), "/api/v1/jobs/"), "/")
+    if len(segments) != 3 || segments[2] != "builds" {
+        http.NotFound(w, r)
+        return
+    }
+
+    instance, err := url.PathUnescape(segments[0])
+    if err != nil {
+        http.NotFound(w, r)
+        return
+    }
+    job, err := url.PathUnescape(segments[1])
+    if err != nil {
+        http.NotFound(w, r)
+        return
+    }
+
+    s.m.RLock()
+    defer s.m.RUnlock()
+
+    for _, ij := range s.visibleInstances(r) {
+        if ij.Instance.Name != instance {
+            continue
+        }
+        for _, j := range ij.Jobs {
+            if j.Name != job {
+                continue
+            }
+
+            builds := []*apiBuild{}
+            for _, b := range j.Builds {
+                builds = append(builds, newApiBuild(ij.Instance, b))
+            }
+            writeJson(w, r, builds)
+            return
+        }
+    }
+    http.NotFound(w, r)
+}
+
+// serveApiStats reports the failure rate, mean time to recovery, and longest run of failures of every job, or of the
+// jobs of the instance named by the instance query parameter. Statistics cover the period given by the period query
+// parameter (e.g. "7d" or "12h"), which defaults to a week.
+func (s *Server) serveApiStats(w http.ResponseWriter, r *http.Request) {
+    query := r.URL.Query()
+    instance := query.Get("instance")
+
+    period := 7 * 24 * time.Hour
+    if p := query.Get("period"); p != "" {
+        d, err := jenkins.ParseWindow(p)
+        if err != nil {
+            http.Error(w, "invalid period: " + err.Error(), http.StatusBadRequest)
+            return
+        }
+        period = d
+    }
+    since := time.Now().Add(-period)
+
+    s.m.RLock()
+    defer s.m.RUnlock()
+
+    stats := []apiStats{}
+    for _, ij := range s.visibleInstances(r) {
+        if instance != "" && ij.Instance.Name != instance {
+            continue
+        }
+        for _, j := range ij.Jobs {
+            if j.Duplicate {
+                continue
+            }
+            js := j.Stats(since)
+            stats = append(stats, apiStats{ij.Instance.Name, j.Name, js.Builds, js.Failures, js.FailureRate, js.Recoveries,
+                js.MTTR.Seconds(), js.LongestRedStreak})
+        }
+    }
+    writeJson(w, r, stats)
+}
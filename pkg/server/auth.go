@@ -0,0 +1,284 @@
+package server
+
+import (
+    "context"
+    "crypto/sha1"
+    "crypto/subtle"
+    "encoding/base64"
+    "errors"
+    "fmt"
+    "log/slog"
+    "net"
+    "net/http"
+    "os"
+    "strings"
+
+    "github.com/pgavlin/jitdash/pkg/jenkins"
+)
+
+// AuthConfig describes how to protect the server's endpoints. Access control is configured by the "auth" section of the
+// jitdash configuration, which may combine an allowlist of client networks, HTTP basic authentication, and
+// authentication by a trusted reverse proxy, such as an OpenID Connect proxy that logs users in and forwards their
+// identity in request headers:
+//
+//     "auth": {
+//         "allowedNetworks": ["10.0.0.0/8", "192.168.1.17"],
+//         "users": {"alice": "secret", "bob": "{SHA}5en6G6MezRroT3XKqkdPOmY/BfQ="},
+//         "htpasswd": "/etc/jitdash/htpasswd",
+//         "trustedProxies": ["10.0.0.1"],
+//         "userHeader": "X-Forwarded-User",
+//         "groupsHeader": "X-Forwarded-Groups",
+//         "allowedGroups": ["ci-readers"]
+//     }
+//
+// If networks are listed, requests from other addresses are rejected. If users are listed, either inline or in an
+// htpasswd file, requests must carry the credentials of one of them. Passwords are given in plain text or as "{SHA}"
+// hashes. If trusted proxies are listed, requests from those proxies may instead identify their user by header, in
+// which case the user must be a member of one of the allowed groups. If no groups are listed, any identified user is
+// allowed. Jenkins webhooks, which carry no credentials, are only subject to the network allowlist, and the health
+// endpoints are not protected at all.
+//
+// Named dashboards may further restrict themselves and the jobs they show to some of the authenticated users and
+// groups; see jenkins.Dashboard.Allows and jenkins.Visible. Users authenticated by password belong to no groups.
+type AuthConfig struct {
+    AllowedNetworks []*net.IPNet
+    Users map[string]string // maps user names to plain text or "{SHA}" passwords
+    TrustedProxies []*net.IPNet
+    UserHeader string
+    GroupsHeader string // the header that lists the user's groups, separated by commas
+    AllowedGroups []string
+}
+
+// parseNetworks parses an array of CIDR blocks. Bare addresses denote a single host.
+func parseNetworks(o jenkins.JsonObject, key string) ([]*net.IPNet, error) {
+    var networks []*net.IPNet
+    networksArray, _ := o.GetArray(key)
+    for _, n := range networksArray {
+        cidr, ok := n.(string)
+        if !ok {
+            return nil, errors.New(fmt.Sprintf("%s contains %v, which is not a string", key, n))
+        }
+        if !strings.Contains(cidr, "/") {
+            if strings.Contains(cidr, ":") {
+                cidr += "/128"
+            } else {
+                cidr += "/32"
+            }
+        }
+        _, network, err := net.ParseCIDR(cidr)
+        if err != nil {
+            return nil, errors.New(fmt.Sprintf("%s contains an invalid network %s: %s", key, cidr, err))
+        }
+        networks = append(networks, network)
+    }
+    return networks, nil
+}
+
+// readHtpasswd reads the users and passwords of an htpasswd file. Only plain text and "{SHA}" passwords are supported.
+func readHtpasswd(path string, users map[string]string) error {
+    contents, err := os.ReadFile(path)
+    if err != nil {
+        return err
+    }
+
+    for n, line := range strings.Split(string(contents), "\n") {
+        line = strings.TrimSpace(line)
+        if line == "" || strings.HasPrefix(line, "#") {
+            continue
+        }
+
+        user, password, ok := strings.Cut(line, ":")
+        if !ok {
+            return errors.New(fmt.Sprintf("%s:%d: expected user:password", path, n + 1))
+        }
+        if strings.HasPrefix(password, "$") {
+            return errors.New(fmt.Sprintf("%s:%d: unsupported password hash; use {SHA} (htpasswd -s) instead", path, n + 1))
+        }
+        users[user] = password
+    }
+    return nil
+}
+
+// ParseAuthConfig parses the "auth" section of the given configuration object. If there is no such section,
+// ParseAuthConfig returns nil.
+func ParseAuthConfig(config jenkins.JsonObject) (*AuthConfig, error) {
+    authObject, ok := config.GetObject("auth")
+    if !ok {
+        return nil, nil
+    }
+
+    a := &AuthConfig{Users: make(map[string]string)}
+
+    var err error
+    if a.AllowedNetworks, err = parseNetworks(authObject, "allowedNetworks"); err != nil {
+        return nil, err
+    }
+    if a.TrustedProxies, err = parseNetworks(authObject, "trustedProxies"); err != nil {
+        return nil, err
+    }
+
+    usersObject, _ := authObject.GetObject("users")
+    for user := range usersObject {
+        password, ok := usersObject.GetString(user)
+        if !ok {
+            return nil, errors.New(fmt.Sprintf("the password of user %s is not a string", user))
+        }
+        a.Users[user] = password
+    }
+    if path, ok := authObject.GetString("htpasswd"); ok {
+        if err := readHtpasswd(path, a.Users); err != nil {
+            return nil, err
+        }
+    }
+
+    if len(a.AllowedNetworks) == 0 && len(a.Users) == 0 && len(a.TrustedProxies) == 0 {
+        return nil, errors.New("auth has no allowed networks, users, or trusted proxies")
+    }
+
+    a.UserHeader, ok = authObject.GetString("userHeader")
+    if !ok {
+        a.UserHeader = "X-Forwarded-User"
+    }
+    a.GroupsHeader, ok = authObject.GetString("groupsHeader")
+    if !ok {
+        a.GroupsHeader = "X-Forwarded-Groups"
+    }
+
+    groupsArray, _ := authObject.GetArray("allowedGroups")
+    for _, g := range groupsArray {
+        group, ok := g.(string)
+        if !ok {
+            return nil, errors.New(fmt.Sprintf("allowed group %v is not a string", g))
+        }
+        a.AllowedGroups = append(a.AllowedGroups, group)
+    }
+
+    return a, nil
+}
+
+// contains returns true if the given remote address belongs to one of the given networks.
+func contains(networks []*net.IPNet, remoteAddr string) bool {
+    host, _, err := net.SplitHostPort(remoteAddr)
+    if err != nil {
+        host = remoteAddr
+    }
+    ip := net.ParseIP(host)
+    if ip == nil {
+        return false
+    }
+
+    for _, network := range networks {
+        if network.Contains(ip) {
+            return true
+        }
+    }
+    return false
+}
+
+// checkPassword returns true if the given password matches the given plain text or "{SHA}" password.
+func checkPassword(expected, password string) bool {
+    if hash, ok := strings.CutPrefix(expected, "{SHA}"); ok {
+        sum := sha1.Sum([]byte(password))
+        password, expected = base64.StdEncoding.EncodeToString(sum[:]), hash
+    }
+    return subtle.ConstantTimeCompare([]byte(expected), []byte(password)) == 1
+}
+
+// splitGroups splits a comma-separated list of groups.
+func splitGroups(groups string) []string {
+    var result []string
+    for _, g := range strings.Split(groups, ",") {
+        if g = strings.TrimSpace(g); g != "" {
+            result = append(result, g)
+        }
+    }
+    return result
+}
+
+// allowed returns true if a user with the given comma-separated groups may access the dashboard.
+func (a *AuthConfig) allowed(groups string) bool {
+    if len(a.AllowedGroups) == 0 {
+        return true
+    }
+
+    for _, g := range splitGroups(groups) {
+        for _, allowed := range a.AllowedGroups {
+            if g == allowed {
+                return true
+            }
+        }
+    }
+    return false
+}
+
+// identity is the authenticated user of a request and the groups the user belongs to.
+type identity struct {
+    user string
+    groups []string
+}
+
+type identityKey struct{}
+
+// withIdentity returns the given request with the given authenticated user and groups attached.
+func withIdentity(r *http.Request, user string, groups []string) *http.Request {
+    return r.WithContext(context.WithValue(r.Context(), identityKey{}, identity{user, groups}))
+}
+
+// requestIdentity returns the authenticated user of the given request and the user's groups. The user is empty if the
+// request is anonymous.
+func requestIdentity(r *http.Request) (string, []string) {
+    id, _ := r.Context().Value(identityKey{}).(identity)
+    return id.user, id.groups
+}
+
+// Wrap returns a handler that authenticates requests before passing them to h.
+func (a *AuthConfig) Wrap(h http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.URL.Path == "/healthz" || r.URL.Path == "/readyz" {
+            h.ServeHTTP(w, r)
+            return
+        }
+        if len(a.AllowedNetworks) != 0 && !contains(a.AllowedNetworks, r.RemoteAddr) {
+            slog.Warn("rejecting request from a network that is not allowed", "addr", r.RemoteAddr, "path", r.URL.Path)
+            http.Error(w, "forbidden", http.StatusForbidden)
+            return
+        }
+        if strings.HasPrefix(r.URL.Path, "/hooks/") {
+            h.ServeHTTP(w, r)
+            return
+        }
+
+        if len(a.TrustedProxies) != 0 && contains(a.TrustedProxies, r.RemoteAddr) {
+            if user := r.Header.Get(a.UserHeader); user != "" {
+                if !a.allowed(r.Header.Get(a.GroupsHeader)) {
+                    slog.Info("rejecting request from user outside of the allowed groups", "user", user, "path", r.URL.Path)
+                    http.Error(w, "forbidden", http.StatusForbidden)
+                    return
+                }
+
+                slog.Debug("authenticated request", "user", user, "path", r.URL.Path)
+                h.ServeHTTP(w, withIdentity(r, user, splitGroups(r.Header.Get(a.GroupsHeader))))
+                return
+            }
+        }
+
+        if len(a.Users) != 0 {
+            user, password, ok := r.BasicAuth()
+            if expected, known := a.Users[user]; !ok || !known || !checkPassword(expected, password) {
+                w.Header().Set("WWW-Authenticate", `Basic realm="jitdash", charset="UTF-8"`)
+                http.Error(w, "unauthorized", http.StatusUnauthorized)
+                return
+            }
+
+            slog.Debug("authenticated request", "user", user, "path", r.URL.Path)
+            h.ServeHTTP(w, withIdentity(r, user, nil))
+            return
+        }
+
+        if len(a.TrustedProxies) != 0 {
+            http.Error(w, "unauthorized", http.StatusUnauthorized)
+            return
+        }
+        h.ServeHTTP(w, r)
+    })
+}
@@ -0,0 +1,187 @@
+package server
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "path/filepath"
+    "reflect"
+    "strings"
+    "testing"
+
+    "github.com/pgavlin/jitdash/pkg/jenkins"
+)
+
+// parseAuth parses the given "auth" section.
+func parseAuth(t *testing.T, auth map[string]interface{}) *AuthConfig {
+    a, err := ParseAuthConfig(jenkins.JsonObject{"auth": auth})
+    if err != nil {
+        t.Fatalf("parsing auth: %s", err)
+    }
+    return a
+}
+
+// echoIdentity is a handler that responds with the authenticated user of each request and the user's groups.
+var echoIdentity = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    user, groups := requestIdentity(r)
+    w.Write([]byte(user + ":" + strings.Join(groups, ",")))
+})
+
+func TestContains(t *testing.T) {
+    a := parseAuth(t, map[string]interface{}{"allowedNetworks": []interface{}{"10.0.0.0/8", "192.168.1.17", "::1",
+        "2001:db8::/32"}})
+
+    tests := []struct {
+        addr string
+        allowed bool
+    }{
+        {"10.1.2.3:1234", true},
+        {"11.0.0.1:1234", false},
+        {"192.168.1.17:80", true},
+        {"192.168.1.18:80", false},
+        {"[::1]:8080", true},
+        {"[::2]:8080", false},
+        {"[2001:db8::1]:443", true},
+        {"10.1.2.3", true},
+        {"not an address", false},
+    }
+    for _, test := range tests {
+        if allowed := contains(a.AllowedNetworks, test.addr); allowed != test.allowed {
+            t.Errorf("contains(%q) is %v, want %v", test.addr, allowed, test.allowed)
+        }
+    }
+}
+
+func TestCheckPassword(t *testing.T) {
+    tests := []struct {
+        expected string
+        password string
+        ok bool
+    }{
+        {"secret", "secret", true},
+        {"secret", "Secret", false},
+        {"secret", "", false},
+        {"{SHA}5en6G6MezRroT3XKqkdPOmY/BfQ=", "secret", true},
+        {"{SHA}5en6G6MezRroT3XKqkdPOmY/BfQ=", "wrong", false},
+        {"{SHA}5en6G6MezRroT3XKqkdPOmY/BfQ=", "{SHA}5en6G6MezRroT3XKqkdPOmY/BfQ=", false},
+    }
+    for _, test := range tests {
+        if ok := checkPassword(test.expected, test.password); ok != test.ok {
+            t.Errorf("checkPassword(%q, %q) is %v, want %v", test.expected, test.password, ok, test.ok)
+        }
+    }
+}
+
+func TestReadHtpasswd(t *testing.T) {
+    tests := []struct {
+        name string
+        contents string
+        users map[string]string
+        err bool
+    }{
+        {"users", "# comment\nalice:secret\n\nbob:{SHA}5en6G6MezRroT3XKqkdPOmY/BfQ=\n",
+            map[string]string{"alice": "secret", "bob": "{SHA}5en6G6MezRroT3XKqkdPOmY/BfQ="}, false},
+        {"missing password", "alice\n", map[string]string{}, true},
+        {"bcrypt", "alice:$2y$05$abcdefghijklmnopqrstuv\n", map[string]string{}, true},
+    }
+    for _, test := range tests {
+        t.Run(test.name, func(t *testing.T) {
+            path := filepath.Join(t.TempDir(), "htpasswd")
+            if err := os.WriteFile(path, []byte(test.contents), 0600); err != nil {
+                t.Fatal(err)
+            }
+
+            users := map[string]string{}
+            err := readHtpasswd(path, users)
+            if (err != nil) != test.err {
+                t.Fatalf("error is %v, want an error: %v", err, test.err)
+            }
+            if !test.err && !reflect.DeepEqual(users, test.users) {
+                t.Errorf("users are %v, want %v", users, test.users)
+            }
+        })
+    }
+}
+
+func TestWrap(t *testing.T) {
+    networks := parseAuth(t, map[string]interface{}{"allowedNetworks": []interface{}{"10.0.0.0/8", "::1"}})
+    users := parseAuth(t, map[string]interface{}{"users": map[string]interface{}{
+        "alice": "secret",
+        "bob": "{SHA}5en6G6MezRroT3XKqkdPOmY/BfQ=",
+    }})
+    proxy := parseAuth(t, map[string]interface{}{"trustedProxies": []interface{}{"10.0.0.1"}})
+    proxyGroups := parseAuth(t, map[string]interface{}{"trustedProxies": []interface{}{"10.0.0.1"},
+        "allowedGroups": []interface{}{"ci-readers"}})
+    proxyUsers := parseAuth(t, map[string]interface{}{"trustedProxies": []interface{}{"10.0.0.1"},
+        "users": map[string]interface{}{"alice": "secret"}})
+    restricted := parseAuth(t, map[string]interface{}{"allowedNetworks": []interface{}{"10.0.0.0/8"},
+        "users": map[string]interface{}{"alice": "secret"}})
+
+    tests := []struct {
+        name string
+        auth *AuthConfig
+        path string
+        addr string
+        user string // the user sent by basic authentication, if any
+        password string
+        headers map[string]string
+        status int
+        identity string // the user and groups that the wrapped handler sees
+    }{
+        {"allowed network", networks, "/", "10.1.2.3:1234", "", "", nil, http.StatusOK, ":"},
+        {"disallowed network", networks, "/", "192.168.1.1:1234", "", "", nil, http.StatusForbidden, ""},
+        {"allowed IPv6 host", networks, "/", "[::1]:1234", "", "", nil, http.StatusOK, ":"},
+        {"disallowed IPv6 host", networks, "/", "[::2]:1234", "", "", nil, http.StatusForbidden, ""},
+
+        {"plain password", users, "/", "10.1.2.3:1234", "alice", "secret", nil, http.StatusOK, "alice:"},
+        {"SHA password", users, "/", "10.1.2.3:1234", "bob", "secret", nil, http.StatusOK, "bob:"},
+        {"wrong password", users, "/", "10.1.2.3:1234", "alice", "wrong", nil, http.StatusUnauthorized, ""},
+        {"unknown user", users, "/", "10.1.2.3:1234", "carol", "secret", nil, http.StatusUnauthorized, ""},
+        {"no credentials", users, "/", "10.1.2.3:1234", "", "", nil, http.StatusUnauthorized, ""},
+
+        {"proxy user", proxy, "/", "10.0.0.1:1234", "", "", map[string]string{"X-Forwarded-User": "alice",
+            "X-Forwarded-Groups": "ci-readers, admins"}, http.StatusOK, "alice:ci-readers,admins"},
+        {"proxy without user", proxy, "/", "10.0.0.1:1234", "", "", nil, http.StatusUnauthorized, ""},
+        {"proxy user in allowed group", proxyGroups, "/", "10.0.0.1:1234", "", "", map[string]string{
+            "X-Forwarded-User": "alice", "X-Forwarded-Groups": "admins,ci-readers"}, http.StatusOK,
+            "alice:admins,ci-readers"},
+        {"proxy user outside allowed groups", proxyGroups, "/", "10.0.0.1:1234", "", "", map[string]string{
+            "X-Forwarded-User": "alice", "X-Forwarded-Groups": "admins"}, http.StatusForbidden, ""},
+        {"proxy user without groups", proxyGroups, "/", "10.0.0.1:1234", "", "", map[string]string{
+            "X-Forwarded-User": "alice"}, http.StatusForbidden, ""},
+
+        {"spoofed user header", proxy, "/", "10.0.0.2:1234", "", "", map[string]string{"X-Forwarded-User": "alice",
+            "X-Forwarded-Groups": "ci-readers"}, http.StatusUnauthorized, ""},
+        {"spoofed user header with password", proxyUsers, "/", "10.0.0.2:1234", "alice", "secret", map[string]string{
+            "X-Forwarded-User": "mallory", "X-Forwarded-Groups": "admins"}, http.StatusOK, "alice:"},
+        {"spoofed user header without password", proxyUsers, "/", "10.0.0.2:1234", "", "", map[string]string{
+            "X-Forwarded-User": "alice"}, http.StatusUnauthorized, ""},
+
+        {"health check", restricted, "/healthz", "192.168.1.1:1234", "", "", nil, http.StatusOK, ":"},
+        {"readiness check", restricted, "/readyz", "192.168.1.1:1234", "", "", nil, http.StatusOK, ":"},
+        {"webhook", restricted, "/hooks/jenkins", "10.1.2.3:1234", "", "", nil, http.StatusOK, ":"},
+        {"webhook from disallowed network", restricted, "/hooks/jenkins", "192.168.1.1:1234", "", "", nil,
+            http.StatusForbidden, ""},
+    }
+    for _, test := range tests {
+        t.Run(test.name, func(t *testing.T) {
+            r := httptest.NewRequest("GET", test.path, nil)
+            r.RemoteAddr = test.addr
+            if test.user != "" {
+                r.SetBasicAuth(test.user, test.password)
+            }
+            for name, value := range test.headers {
+                r.Header.Set(name, value)
+            }
+
+            w := httptest.NewRecorder()
+            test.auth.Wrap(echoIdentity).ServeHTTP(w, r)
+            if w.Code != test.status {
+                t.Fatalf("status is %d, want %d", w.Code, test.status)
+            }
+            if test.status == http.StatusOK && w.Body.String() != test.identity {
+                t.Errorf("identity is %q, want %q", w.Body.String(), test.identity)
+            }
+        })
+    }
+}
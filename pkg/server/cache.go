@@ -0,0 +1,53 @@
+package server
+
+import (
+    "bytes"
+    "compress/gzip"
+    "crypto/sha256"
+    "encoding/base64"
+    "net/http"
+    "strings"
+    "time"
+)
+
+// minGzipSize is the size below which responses are not worth compressing.
+const minGzipSize = 1024
+
+// acceptsGzip returns true if the client that sent the given request accepts gzip-encoded responses.
+func acceptsGzip(r *http.Request) bool {
+    for _, e := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+        coding, params, _ := strings.Cut(strings.TrimSpace(e), ";")
+        if strings.TrimSpace(coding) == "gzip" && strings.ReplaceAll(params, " ", "") != "q=0" {
+            return true
+        }
+    }
+    return false
+}
+
+// writeResponse writes a rendered response. The response is tagged with a hash of its body so that clients can
+// revalidate their cached copy with a conditional request, which is answered with 304 Not Modified if the body has not
+// changed. Large bodies are compressed if the client accepts gzip.
+func writeResponse(w http.ResponseWriter, r *http.Request, contentType string, body []byte) {
+    sum := sha256.Sum256(body)
+    etag := base64.RawURLEncoding.EncodeToString(sum[:16])
+
+    header := w.Header()
+    header.Set("Content-Type", contentType)
+    header.Set("Cache-Control", "no-cache")
+    header.Add("Vary", "Accept-Encoding")
+
+    // The compressed and uncompressed representations of a body are tagged distinctly.
+    if len(body) >= minGzipSize && acceptsGzip(r) {
+        compressed := new(bytes.Buffer)
+        gz := gzip.NewWriter(compressed)
+        gz.Write(body)
+        gz.Close()
+
+        header.Set("Content-Encoding", "gzip")
+        body, etag = compressed.Bytes(), etag + "-gzip"
+    }
+    header.Set("ETag", `"` + etag + `"`)
+
+    // ServeContent answers conditional requests using the ETag.
+    http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(body))
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+    "fmt"
+    "net/http"
+    "time"
+)
+
+// keepAliveInterval is the interval at which comments are sent to idle event streams so that proxies do not close
+// them.
+const keepAliveInterval = 30 * time.Second
+
+// subscribe registers a channel that receives a value each time the server's model changes.
+func (s *Server) subscribe() chan struct{} {
+    c := make(chan struct{}, 1)
+
+    s.subscribersM.Lock()
+    defer s.subscribersM.Unlock()
+    if s.subscribers == nil {
+        s.subscribers = make(map[chan struct{}]bool)
+    }
+    s.subscribers[c] = true
+    return c
+}
+
+func (s *Server) unsubscribe(c chan struct{}) {
+    s.subscribersM.Lock()
+    defer s.subscribersM.Unlock()
+    delete(s.subscribers, c)
+}
+
+// notify informs all subscribers that the server's model has changed. Subscribers that have not yet consumed a previous
+// notification are not sent another.
+func (s *Server) notify() {
+    s.subscribersM.Lock()
+    defer s.subscribersM.Unlock()
+    for c := range s.subscribers {
+        select {
+        case c <- struct{}{}:
+        default:
+        }
+    }
+}
+
+// visible returns true if the requester may see any instance.
+func (s *Server) visible(r *http.Request) bool {
+    s.m.RLock()
+    defer s.m.RUnlock()
+    return len(s.visibleInstances(r)) != 0
+}
+
+// serveEvents streams an "update" Server-Sent Event to the client each time the server's model changes, as long as the
+// requester may see any of it.
+func (s *Server) serveEvents(w http.ResponseWriter, r *http.Request) {
+    flusher, ok := w.(http.Flusher)
+    if !ok {
+        http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+        return
+    }
+
+    c := s.subscribe()
+    defer s.unsubscribe(c)
+
+    w.Header().Set("Content-Type", "text/event-stream")
+    w.Header().Set("Cache-Control", "no-cache")
+    w.WriteHeader(http.StatusOK)
+    flusher.Flush()
+
+    keepAlive := time.NewTicker(keepAliveInterval)
+    defer keepAlive.Stop()
+
+    for {
+        select {
+        case <-c:
+            if !s.visible(r) {
+                continue
+            }
+            fmt.Fprintf(w, "event: update\ndata: %d\n\n", time.Now().Unix())
+        case <-keepAlive.C:
+            fmt.Fprintf(w, ": keep-alive\n\n")
+        case <-r.Context().Done():
+            return
+        case <-s.stopping:
+            return
+        }
+        flusher.Flush()
+    }
+}
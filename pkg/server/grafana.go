@@ -0,0 +1,195 @@
+package server
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "sort"
+    "strings"
+    "time"
+
+    "github.com/pgavlin/jitdash/pkg/jenkins"
+)
+
+// grafanaMetrics maps the names of the metrics served to Grafana's JSON datasource to functions that return the value
+// of the metric for a completed build. A metric has no value for builds for which the function returns false.
+//
+// Each target has the form "{metric}:{instance}/{job}", e.g. "failures:ci/nightly". The datasource's URL is the
+// server's root; Grafana tests the connection by requesting "/", which serves the dashboard.
+var grafanaMetrics = map[string]func(b *jenkins.Build) (float64, bool){
+    "failed": func(b *jenkins.Build) (float64, bool) {
+        if b.Failed() {
+            return 1, true
+        }
+        return 0, b.Passed()
+    },
+    "failures": func(b *jenkins.Build) (float64, bool) {
+        return float64(b.Failures), b.Failures >= 0
+    },
+    "duration": func(b *jenkins.Build) (float64, bool) {
+        return b.Duration.Seconds(), b.Duration != 0
+    },
+}
+
+type grafanaSearchRequest struct {
+    Target string `json:"target"`
+}
+
+type grafanaRange struct {
+    From time.Time `json:"from"`
+    To time.Time `json:"to"`
+}
+
+type grafanaTarget struct {
+    Target string `json:"target"`
+    Type string `json:"type"` // "timeserie" or "table"
+}
+
+type grafanaQueryRequest struct {
+    Range grafanaRange `json:"range"`
+    Targets []grafanaTarget `json:"targets"`
+}
+
+type grafanaSeries struct {
+    Target string `json:"target"`
+    Datapoints [][2]float64 `json:"datapoints"` // pairs of values and Unix timestamps in milliseconds
+}
+
+type grafanaColumn struct {
+    Text string `json:"text"`
+    Type string `json:"type"`
+}
+
+type grafanaTable struct {
+    Type string `json:"type"`
+    Columns []grafanaColumn `json:"columns"`
+    Rows [][]interface{} `json:"rows"`
+}
+
+// grafanaTargets returns the names of the targets of every job that the requester may see, ordered by name.
+func (s *Server) grafanaTargets(r *http.Request) []string {
+    s.m.RLock()
+    defer s.m.RUnlock()
+
+    targets := []string{}
+    for _, ij := range s.visibleInstances(r) {
+        for _, j := range ij.Jobs {
+            if j.Duplicate {
+                continue
+            }
+            for metric := range grafanaMetrics {
+                targets = append(targets, metric + ":" + ij.Instance.Name + "/" + j.Name)
+            }
+        }
+    }
+    sort.Strings(targets)
+    return targets
+}
+
+// serveGrafanaSearch lists the targets whose names contain the requested target, as the "/search" endpoint of Grafana's
+// JSON datasource.
+func (s *Server) serveGrafanaSearch(w http.ResponseWriter, r *http.Request) {
+    var request grafanaSearchRequest
+    if r.Method == "POST" {
+        if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+            http.Error(w, fmt.Sprintf("invalid request: %s", err), http.StatusBadRequest)
+            return
+        }
+    }
+
+    targets := []string{}
+    for _, t := range s.grafanaTargets(r) {
+        if strings.Contains(t, request.Target) {
+            targets = append(targets, t)
+        }
+    }
+    writeJson(w, r, targets)
+}
+
+// grafanaJob returns the instance and job named by the given target along with the target's metric, if the requester
+// may see the job. The caller must hold s.m.
+func (s *Server) grafanaJob(r *http.Request, target string) (*jenkins.Instance, *jenkins.Job, func(b *jenkins.Build) (float64, bool)) {
+    metric, path, _ := strings.Cut(target, ":")
+    value, ok := grafanaMetrics[metric]
+    if !ok {
+        return nil, nil, nil
+    }
+    instance, job, _ := strings.Cut(path, "/")
+
+    for _, ij := range s.visibleInstances(r) {
+        if ij.Instance.Name != instance {
+            continue
+        }
+        for _, j := range ij.Jobs {
+            if j.Name == job && !j.Duplicate {
+                return ij.Instance, j, value
+            }
+        }
+    }
+    return nil, nil, nil
+}
+
+// serveGrafanaQuery answers the "/query" endpoint of Grafana's JSON datasource. Timeseries targets yield a datapoint
+// per completed build within the requested range, at the time the build started; table targets yield a row per build.
+// Unknown targets yield no data.
+func (s *Server) serveGrafanaQuery(w http.ResponseWriter, r *http.Request) {
+    if r.Method != "POST" {
+        w.Header().Set("Allow", "POST")
+        http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+
+    var request grafanaQueryRequest
+    if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+        http.Error(w, fmt.Sprintf("invalid request: %s", err), http.StatusBadRequest)
+        return
+    }
+    inRange := func(b *jenkins.Build) bool {
+        return b.Complete && (request.Range.From.IsZero() || !b.Timestamp.Before(request.Range.From)) &&
+            (request.Range.To.IsZero() || !b.Timestamp.After(request.Range.To))
+    }
+
+    s.m.RLock()
+    defer s.m.RUnlock()
+
+    results := []interface{}{}
+    for _, t := range request.Targets {
+        instance, job, value := s.grafanaJob(r, t.Target)
+
+        if t.Type == "table" {
+            table := grafanaTable{Type: "table", Rows: [][]interface{}{}, Columns: []grafanaColumn{
+                {"Time", "time"}, {"Instance", "string"}, {"Job", "string"}, {"Build", "number"}, {"Result", "string"},
+                {"Failures", "number"}, {"Duration", "number"},
+            }}
+            if job != nil {
+                for _, b := range job.Builds {
+                    if !inRange(b) {
+                        continue
+                    }
+                    var failures interface{}
+                    if b.Failures >= 0 {
+                        failures = b.Failures
+                    }
+                    table.Rows = append(table.Rows, []interface{}{b.Timestamp.UnixMilli(), instance.Name, job.Name, b.Id,
+                        b.Result.String(), failures, b.Duration.Seconds()})
+                }
+            }
+            results = append(results, table)
+            continue
+        }
+
+        series := grafanaSeries{Target: t.Target, Datapoints: [][2]float64{}}
+        if job != nil {
+            for _, b := range job.Builds {
+                if !inRange(b) || b.Err != nil {
+                    continue
+                }
+                if v, ok := value(b); ok {
+                    series.Datapoints = append(series.Datapoints, [2]float64{v, float64(b.Timestamp.UnixMilli())})
+                }
+            }
+        }
+        results = append(results, series)
+    }
+    writeJson(w, r, results)
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+    "fmt"
+    "net/http"
+    "strings"
+
+    "github.com/pgavlin/jitdash/pkg/jenkins"
+)
+
+// hookJobUrl determines the URL of the job referenced by a webhook payload. Payloads from the Jenkins Notification
+// plugin identify the job by the full URL of the build that triggered the notification; generic payloads may simply
+// give the job's absolute URL.
+func hookJobUrl(payload jenkins.JsonObject) (string, bool) {
+    if build, ok := payload.GetObject("build"); ok {
+        fullUrl, hasUrl := build.GetString("full_url")
+        number, hasNumber := build.GetInt64("number")
+        if hasUrl && hasNumber {
+            suffix := fmt.Sprintf("%d/", number)
+            if !strings.HasSuffix(fullUrl, "/") {
+                fullUrl += "/"
+            }
+            if strings.HasSuffix(fullUrl, suffix) {
+                return strings.TrimSuffix(fullUrl, suffix), true
+            }
+        }
+    }
+
+    if url, ok := payload.GetString("url"); ok && (strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://")) {
+        if !strings.HasSuffix(url, "/") {
+            url += "/"
+        }
+        return url, true
+    }
+
+    return "", false
+}
+
+// serveJenkinsHook accepts a job notification and schedules a refresh of the affected job.
+func (s *Server) serveJenkinsHook(w http.ResponseWriter, r *http.Request) {
+    if r.Method != "POST" {
+        w.Header().Set("Allow", "POST")
+        http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+
+    var payload jenkins.JsonObject
+    if err := jenkins.DecodeJson(r.Body, &payload); err != nil {
+        http.Error(w, fmt.Sprintf("invalid payload: %s", err), http.StatusBadRequest)
+        return
+    }
+
+    jobUrl, ok := hookJobUrl(payload)
+    if !ok {
+        http.Error(w, "payload does not identify a job", http.StatusBadRequest)
+        return
+    }
+
+    var instance *jenkins.Instance
+    var job *jenkins.Job
+    s.m.RLock()
+    for _, ij := range s.instances {
+        if n := ij.FindJob(jobUrl); n != -1 && ij.Instance.Backend == nil {
+            instance, job = ij.Instance, ij.Jobs[n]
+            break
+        }
+    }
+    s.m.RUnlock()
+
+    if job == nil {
+        http.Error(w, "unknown job", http.StatusNotFound)
+        return
+    }
+
+    s.refreshes.Add(1)
+    go func() {
+        defer s.refreshes.Done()
+        instance.Logger().Info("refreshing job", "job", job.Name)
+
+        updated, err := instance.RefreshJob(job, s.currentConfig().MaxBuilds)
+        if err != nil {
+            instance.Logger().Warn("error refreshing job", "job", job.Name, "err", err)
+            return
+        }
+        s.updateJob(instance, updated)
+    }()
+
+    w.WriteHeader(http.StatusAccepted)
+}
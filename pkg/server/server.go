@@ -0,0 +1,615 @@
+// Package server serves a jitdash dashboard over HTTP and keeps it up to date, either by periodically re-fetching
+// every instance or by incrementally updating individual jobs in response to webhooks.
+package server
+
+import (
+    "bytes"
+    "context"
+    "fmt"
+    "log/slog"
+    "math/rand"
+    "net/http"
+    "net/url"
+    "strconv"
+    "strings"
+    "sync"
+    "sync/atomic"
+    "time"
+
+    "github.com/pgavlin/jitdash/pkg/jenkins"
+    "github.com/pgavlin/jitdash/pkg/metrics"
+    "github.com/pgavlin/jitdash/pkg/notify"
+    "github.com/pgavlin/jitdash/pkg/render"
+)
+
+// Server holds the most recently fetched state of a dashboard's instances in memory and serves it over HTTP.
+type Server struct {
+    m sync.RWMutex
+    config *jenkins.Config
+    options render.Options
+    instances []*jenkins.InstanceJobs
+    notifiers []notify.Notifier
+    alerter *notify.Alerter
+    reloadedAt time.Time // the time of the last reload; jobs fetched before then were fetched with another configuration
+
+    subscribersM sync.Mutex
+    subscribers map[chan struct{}]bool
+
+    ready atomic.Bool // true once the first refresh of every instance has completed, whether or not it succeeded
+    stopping chan struct{} // closed when the server begins to shut down
+    refreshes sync.WaitGroup // tracks in-flight refreshes
+
+    loopsM sync.Mutex
+    runCtx context.Context // the context passed to Run, or nil if Run has not been called
+    defaultInterval time.Duration // the default refresh interval passed to Run
+    stopLoops context.CancelFunc // stops the current instances' refresh loops
+}
+
+// New creates a server for the given configuration. The server's model is empty until the first call to Refresh.
+// Pages rendered by the server include a script that reloads their content when the model changes.
+func New(config *jenkins.Config, options render.Options) *Server {
+    options.EventsUrl = "/events"
+    return &Server{config: config, options: options, stopping: make(chan struct{})}
+}
+
+// SetNotifiers sets the notifiers that are sent the jobs that break, recover, or change their failure counts when the
+// server's model is updated.
+func (s *Server) SetNotifiers(notifiers []notify.Notifier) {
+    s.m.Lock()
+    defer s.m.Unlock()
+    s.notifiers = notifiers
+}
+
+// SetAlerter sets the alerter that opens and resolves incidents for jobs with sustained failures whenever the server's
+// model is updated. The alerter takes over the incidents opened by the previous alerter, if any.
+func (s *Server) SetAlerter(alerter *notify.Alerter) {
+    s.m.Lock()
+    defer s.m.Unlock()
+    if alerter != nil && s.alerter != nil {
+        alerter.Adopt(s.alerter)
+    }
+    s.alerter = alerter
+}
+
+// evaluateAlerts evaluates the server's alert rules against its model in the background.
+func (s *Server) evaluateAlerts() {
+    s.m.RLock()
+    alerter, instances := s.alerter, s.instances
+    s.m.RUnlock()
+    if alerter == nil {
+        return
+    }
+
+    s.refreshes.Add(1)
+    go func() {
+        defer s.refreshes.Done()
+        alerter.Evaluate(instances)
+    }()
+}
+
+// alert sends the changes between the given states of the model to the server's notifiers in the background. Instances
+// that are absent from the earlier state have not been fetched before, so their failing jobs are not reported.
+func (s *Server) alert(before, after []*jenkins.InstanceJobs) {
+    s.m.RLock()
+    notifiers := s.notifiers
+    s.m.RUnlock()
+    if len(notifiers) == 0 {
+        return
+    }
+
+    known := make(map[string]bool)
+    for _, ij := range before {
+        known[ij.Instance.Name] = true
+    }
+    var fetched []*jenkins.InstanceJobs
+    for _, ij := range after {
+        if known[ij.Instance.Name] {
+            fetched = append(fetched, ij)
+        }
+    }
+
+    changes := jenkins.Diff(before, fetched)
+    if len(changes) == 0 {
+        return
+    }
+    s.refreshes.Add(1)
+    go func() {
+        defer s.refreshes.Done()
+        notify.Notify(notifiers, changes)
+    }()
+}
+
+// Refresh re-fetches every instance and replaces the server's model.
+func (s *Server) Refresh() {
+    slog.Info("refreshing dashboard")
+    s.m.RLock()
+    config, before := s.config, s.instances
+    s.m.RUnlock()
+
+    // Each instance replaces its part of the model as soon as it has been fetched, so an instance that is slow to
+    // respond does not hold up the others.
+    jenkins.Refetch(config.Instances, nil, config.MaxBuilds, config.Workers, func(ij *jenkins.InstanceJobs) {
+        if _, _, ok := s.replaceInstance(config, ij); ok {
+            s.notify()
+        }
+    })
+
+    // Instances that are unreachable or misconfigured are reported on the dashboard, so they do not keep the server
+    // from becoming ready.
+    s.ready.Store(true)
+
+    s.m.RLock()
+    after, current := s.instances, s.config == config
+    s.m.RUnlock()
+    if !current {
+        // The configuration was reloaded during the fetch, and the reload's own refresh supersedes this one.
+        return
+    }
+    s.alert(before, after)
+    s.evaluateAlerts()
+    s.publish()
+}
+
+// replaceInstance replaces the fetched instance's part of the server's model, which keeps the order of the instances in
+// the given configuration. If the instance was unreachable, its last-known jobs are kept and marked stale. If the
+// configuration has been reloaded since the instance was fetched, the model is left alone and replaceInstance returns
+// false; otherwise, it returns the instance's previous and new parts of the model.
+func (s *Server) replaceInstance(config *jenkins.Config, ij *jenkins.InstanceJobs) (*jenkins.InstanceJobs, *jenkins.InstanceJobs, bool) {
+    s.m.Lock()
+    defer s.m.Unlock()
+    if s.config != config {
+        return nil, nil, false
+    }
+
+    current := make(map[*jenkins.Instance]*jenkins.InstanceJobs)
+    for _, old := range s.instances {
+        current[old.Instance] = old
+    }
+    old := current[ij.Instance]
+    ij = jenkins.KeepLastKnown(old, ij)
+    current[ij.Instance] = ij
+
+    instances := make([]*jenkins.InstanceJobs, 0, len(config.Instances))
+    for _, i := range config.Instances {
+        if c, ok := current[i]; ok {
+            instances = append(instances, c)
+        }
+    }
+    s.instances = instances
+    return old, ij, true
+}
+
+// publish publishes the state of the jobs of every instance to the configured metric sinks, if any.
+func (s *Server) publish() {
+    s.m.RLock()
+    sinks, samples := s.config.Sinks, jenkins.JobSamples(s.instances)
+    s.m.RUnlock()
+
+    for _, sink := range sinks {
+        if err := sink.Publish(samples); err != nil {
+            slog.Warn("could not publish metrics", "err", err)
+        }
+    }
+}
+
+// Run refreshes the server's model and then continues to refresh each instance at its configured refresh interval or
+// that of its refresh tier, plus its jitter, or at the given default interval for instances that specify neither.
+// Instances with an interval of zero are only fetched once, and further updates must arrive via webhooks. Run returns
+// once the given context is done; refreshes that are in flight at that point continue until they finish, which Wait
+// awaits.
+func (s *Server) Run(ctx context.Context, defaultInterval time.Duration) {
+    s.Refresh()
+
+    s.loopsM.Lock()
+    s.runCtx, s.defaultInterval = ctx, defaultInterval
+    s.startLoops(s.currentConfig().Instances)
+    s.loopsM.Unlock()
+
+    <-ctx.Done()
+    close(s.stopping)
+}
+
+// startLoops stops the refresh loops of the previous instances, if any, and starts a refresh loop for each of the given
+// instances. s.loopsM must be held.
+func (s *Server) startLoops(instances []*jenkins.Instance) {
+    if s.stopLoops != nil {
+        s.stopLoops()
+    }
+    ctx, cancel := context.WithCancel(s.runCtx)
+    s.stopLoops = cancel
+
+    for _, i := range instances {
+        interval := i.RefreshInterval
+        if interval == 0 {
+            interval = s.defaultInterval
+        }
+        if interval == 0 {
+            continue
+        }
+
+        // Each wait is lengthened by a random jitter so that instances with the same interval drift apart rather than
+        // loading their servers and the network in lockstep.
+        s.refreshes.Add(1)
+        go func(i *jenkins.Instance, interval time.Duration) {
+            defer s.refreshes.Done()
+
+            for {
+                wait := interval
+                if i.RefreshJitter > 0 {
+                    wait += time.Duration(rand.Int63n(int64(i.RefreshJitter)))
+                }
+                timer := time.NewTimer(wait)
+                select {
+                case <-timer.C:
+                    s.refreshInstance(i)
+                case <-ctx.Done():
+                    timer.Stop()
+                    return
+                }
+            }
+        }(i, interval)
+    }
+}
+
+// Reload replaces the server's configuration and render options. Instances that keep their names keep their
+// previously fetched jobs until they are re-fetched with their new configuration, so the dashboard does not go blank
+// while the new configuration is fetched.
+func (s *Server) Reload(config *jenkins.Config, options render.Options) {
+    options.EventsUrl = "/events"
+
+    s.m.Lock()
+    previous := make(map[string]*jenkins.InstanceJobs)
+    for _, ij := range s.instances {
+        previous[ij.Instance.Name] = ij
+    }
+
+    instances := make([]*jenkins.InstanceJobs, 0, len(config.Instances))
+    for _, i := range config.Instances {
+        if old, ok := previous[i.Name]; ok {
+            instances = append(instances, &jenkins.InstanceJobs{Instance: i, Jobs: old.Jobs, Errors: old.Errors, Agents: old.Agents,
+                FetchedAt: old.FetchedAt})
+        }
+    }
+    s.config, s.options, s.instances, s.reloadedAt = config, options, instances, time.Now()
+    s.m.Unlock()
+    s.notify()
+
+    s.loopsM.Lock()
+    defer s.loopsM.Unlock()
+    if s.runCtx == nil || s.runCtx.Err() != nil {
+        return
+    }
+    s.startLoops(config.Instances)
+
+    s.refreshes.Add(1)
+    go func() {
+        defer s.refreshes.Done()
+        s.Refresh()
+    }()
+}
+
+// currentConfig returns the server's current configuration.
+func (s *Server) currentConfig() *jenkins.Config {
+    s.m.RLock()
+    defer s.m.RUnlock()
+    return s.config
+}
+
+// Wait waits for in-flight refreshes to finish. Wait must not be called until Run has returned and the server's handler
+// has stopped serving requests.
+func (s *Server) Wait() {
+    s.refreshes.Wait()
+}
+
+// refreshInstance re-fetches a single instance and replaces its part of the server's model. Unless the configuration
+// has been reloaded since the instance was last fetched, only its changed jobs and builds are fetched.
+func (s *Server) refreshInstance(i *jenkins.Instance) {
+    i.Logger().Info("refreshing instance")
+
+    s.m.RLock()
+    config := s.config
+    var previous []*jenkins.InstanceJobs
+    for _, old := range s.instances {
+        if old.Instance == i && old.FetchedAt.After(s.reloadedAt) {
+            previous = append(previous, old)
+        }
+    }
+    s.m.RUnlock()
+
+    ij := jenkins.Refetch([]*jenkins.Instance{i}, previous, config.MaxBuilds, config.Workers, nil)[0]
+
+    old, ij, ok := s.replaceInstance(config, ij)
+    if !ok {
+        return
+    }
+    var before []*jenkins.InstanceJobs
+    if old != nil {
+        before = append(before, old)
+    }
+
+    s.alert(before, []*jenkins.InstanceJobs{ij})
+    s.evaluateAlerts()
+    s.notify()
+    s.publish()
+}
+
+// updateJob replaces the job with the given URL in the given instance. Parts of the model are never modified once they
+// are published, since they are read without holding s.m (e.g. by refreshes and alerts), so the instance's part of the
+// model is replaced with an updated copy.
+func (s *Server) updateJob(instance *jenkins.Instance, job *jenkins.Job) {
+    var before, after []*jenkins.InstanceJobs
+    s.m.Lock()
+    instances := make([]*jenkins.InstanceJobs, len(s.instances))
+    for k, ij := range s.instances {
+        instances[k] = ij
+        if ij.Instance != instance {
+            continue
+        }
+        if n := ij.FindJob(job.Url); n != -1 {
+            before = append(before, &jenkins.InstanceJobs{Instance: instance, Jobs: []*jenkins.Job{ij.Jobs[n]}})
+            after = append(after, &jenkins.InstanceJobs{Instance: instance, Jobs: []*jenkins.Job{job}})
+
+            updated := *ij
+            updated.Jobs = make([]*jenkins.Job, len(ij.Jobs))
+            copy(updated.Jobs, ij.Jobs)
+            updated.Jobs[n] = job
+            instances[k] = &updated
+        }
+    }
+    s.instances = instances
+    s.m.Unlock()
+
+    s.alert(before, after)
+    s.evaluateAlerts()
+    s.notify()
+}
+
+// Handler returns the server's HTTP handler. The dashboard (or an overview, if so configured) is served at "/", each
+// instance's page is served at "/instances/{instance}", model change events are streamed from
+// "/events", the model is available as JSON under "/api/v1/", and Jenkins notifications are accepted at
+// "/hooks/jenkins". Liveness and readiness probes are answered at "/healthz" and "/readyz", and jitdash's own metrics
+// are exposed at "/metrics".
+func (s *Server) Handler() http.Handler {
+    mux := http.NewServeMux()
+    mux.HandleFunc("/", s.serveDashboard)
+    mux.HandleFunc("/instances/", s.serveInstance)
+    mux.HandleFunc("/d/", s.serveNamedDashboard)
+    mux.HandleFunc("/embed/", s.serveEmbed)
+    mux.HandleFunc("/search", s.serveGrafanaSearch)
+    mux.HandleFunc("/query", s.serveGrafanaQuery)
+    mux.HandleFunc("/events", s.serveEvents)
+    mux.HandleFunc("/api/v1/instances", s.serveApiInstances)
+    mux.HandleFunc("/api/v1/jobs", s.serveApiJobs)
+    mux.HandleFunc("/api/v1/jobs/", s.serveApiBuilds)
+    mux.HandleFunc("/api/v1/stats", s.serveApiStats)
+    mux.HandleFunc("/hooks/jenkins", s.serveJenkinsHook)
+    mux.HandleFunc("/healthz", s.serveHealth)
+    mux.HandleFunc("/readyz", s.serveReady)
+    mux.HandleFunc("/metrics", s.serveMetrics)
+    return mux
+}
+
+// serveMetrics exposes jitdash's own metrics in the Prometheus text format.
+func (s *Server) serveMetrics(w http.ResponseWriter, r *http.Request) {
+    b := new(bytes.Buffer)
+    if err := metrics.WriteText(b); err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+    writeResponse(w, r, "text/plain; version=0.0.4; charset=utf-8", b.Bytes())
+}
+
+// serveHealth reports that the server is alive.
+func (s *Server) serveHealth(w http.ResponseWriter, r *http.Request) {
+    fmt.Fprintf(w, "ok\n")
+}
+
+// serveReady reports whether the server is ready to serve the dashboard, i.e. whether its first refresh of every
+// instance has completed.
+func (s *Server) serveReady(w http.ResponseWriter, r *http.Request) {
+    if !s.ready.Load() {
+        http.Error(w, "not ready", http.StatusServiceUnavailable)
+        return
+    }
+    fmt.Fprintf(w, "ok\n")
+}
+
+// requestOptions returns the render options for the given request. The onlyFailing query parameter overrides the
+// server's default, and the page query parameter selects the page to render.
+func (s *Server) requestOptions(r *http.Request) render.Options {
+    s.m.RLock()
+    options := s.options
+    s.m.RUnlock()
+
+    query := r.URL.Query()
+    if onlyFailing := query.Get("onlyFailing"); onlyFailing != "" {
+        options.OnlyFailing = onlyFailing == "true" || onlyFailing == "1"
+    }
+    if page, err := strconv.Atoi(query.Get("page")); err == nil {
+        options.Page = page
+    }
+    options.PageUrl = func(page int) string {
+        query := r.URL.Query()
+        query.Set("page", strconv.Itoa(page))
+        return r.URL.EscapedPath() + "?" + query.Encode()
+    }
+    return options
+}
+
+// visibleInstances returns the instances and jobs that the requester may see, hiding those that only the restricted
+// dashboards the requester may not see show. The caller must hold s.m.
+func (s *Server) visibleInstances(r *http.Request) []*jenkins.InstanceJobs {
+    user, groups := requestIdentity(r)
+    return jenkins.Visible(s.config.Dashboards, user, groups, s.instances)
+}
+
+// serveDashboard renders the dashboard. See requestOptions for the query parameters it accepts.
+func (s *Server) serveDashboard(w http.ResponseWriter, r *http.Request) {
+    if r.URL.Path != "/" {
+        http.NotFound(w, r)
+        return
+    }
+
+    options := s.requestOptions(r)
+
+    s.m.RLock()
+    b := new(bytes.Buffer)
+    var err error
+    if options.Overview {
+        err = render.Overview(b, s.visibleInstances(r), options, InstancePath)
+    } else {
+        err = render.HTML(b, s.visibleInstances(r), options)
+    }
+    s.m.RUnlock()
+
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+
+    writeResponse(w, r, "text/html; charset=utf-8", b.Bytes())
+}
+
+// InstancePath returns the path of the page of the named instance.
+func InstancePath(instance string) string {
+    return "/instances/" + url.PathEscape(instance)
+}
+
+// serveInstance renders the dashboard of a single instance. The request path has the form "/instances/{instance}". The
+// query parameters are those accepted by serveDashboard.
+func (s *Server) serveInstance(w http.ResponseWriter, r *http.Request) {
+    name, err := url.PathUnescape(strings.TrimPrefix(r.URL.EscapedPath(), "/instances/"))
+    if err != nil {
+        http.NotFound(w, r)
+        return
+    }
+
+    // Instance pages are organized by group even if the dashboard has curated sections.
+    options := s.requestOptions(r)
+    options.Sections = nil
+
+    s.m.RLock()
+    var ij *jenkins.InstanceJobs
+    for _, i := range s.visibleInstances(r) {
+        if i.Instance.Name == name {
+            ij = i
+        }
+    }
+    b := new(bytes.Buffer)
+    if ij != nil {
+        err = render.HTML(b, []*jenkins.InstanceJobs{ij}, options)
+    }
+    s.m.RUnlock()
+
+    if ij == nil {
+        http.NotFound(w, r)
+        return
+    }
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+
+    writeResponse(w, r, "text/html; charset=utf-8", b.Bytes())
+}
+
+// DashboardPath returns the path of the named dashboard.
+func DashboardPath(name string) string {
+    return "/d/" + url.PathEscape(name)
+}
+
+// serveNamedDashboard renders one of the configured named dashboards. The request path has the form "/d/{name}". The
+// query parameters are those accepted by serveDashboard. Restricted dashboards are only served to the users they allow,
+// and every page omits the jobs of restricted dashboards the requester may not see.
+func (s *Server) serveNamedDashboard(w http.ResponseWriter, r *http.Request) {
+    name, err := url.PathUnescape(strings.TrimPrefix(r.URL.EscapedPath(), "/d/"))
+    if err != nil {
+        http.NotFound(w, r)
+        return
+    }
+
+    options := s.requestOptions(r)
+
+    user, groups := requestIdentity(r)
+
+    s.m.RLock()
+    var d *jenkins.Dashboard
+    for _, c := range s.config.Dashboards {
+        if c.Name == name {
+            d = c
+        }
+    }
+    allowed := d != nil && d.Allows(user, groups)
+    b := new(bytes.Buffer)
+    if allowed {
+        err = render.HTML(b, d.Filter(s.visibleInstances(r)), options.ForDashboard(d))
+    }
+    s.m.RUnlock()
+
+    if d == nil {
+        http.NotFound(w, r)
+        return
+    }
+    if !allowed {
+        slog.Info("rejecting request for a dashboard the user may not see", "user", user, "dashboard", name)
+        http.Error(w, "forbidden", http.StatusForbidden)
+        return
+    }
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+
+    writeResponse(w, r, "text/html; charset=utf-8", b.Bytes())
+}
+
+// serveEmbed renders a widget for embedding in other pages. The request path has the form "/embed/{instance}" for a
+// table of the instance's jobs or "/embed/{instance}/{job}" for a single job's sparkline. The job's name may contain
+// slashes. The onlyFailing query parameter is accepted as for serveDashboard.
+func (s *Server) serveEmbed(w http.ResponseWriter, r *http.Request) {
+    instancePath, jobPath, _ := strings.Cut(strings.TrimPrefix(r.URL.EscapedPath(), "/embed/"), "/")
+    instance, err := url.PathUnescape(instancePath)
+    if err != nil {
+        http.NotFound(w, r)
+        return
+    }
+    jobName, err := url.PathUnescape(jobPath)
+    if err != nil {
+        http.NotFound(w, r)
+        return
+    }
+
+    options := s.requestOptions(r)
+
+    s.m.RLock()
+    var ij *jenkins.InstanceJobs
+    var job *jenkins.Job
+    for _, i := range s.visibleInstances(r) {
+        if i.Instance.Name != instance {
+            continue
+        }
+        ij = i
+        for _, j := range i.Jobs {
+            if j.Name == jobName && job == nil {
+                job = j
+            }
+        }
+    }
+    found := ij != nil && (jobName == "" || job != nil)
+    b := new(bytes.Buffer)
+    if found {
+        err = render.Embed(b, ij, job, options)
+    }
+    s.m.RUnlock()
+
+    if !found {
+        http.NotFound(w, r)
+        return
+    }
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+
+    writeResponse(w, r, "text/html; charset=utf-8", b.Bytes())
+}
@@ -0,0 +1,216 @@
+// Package teamcity implements a jitdash backend that fetches builds from TeamCity. Each configured build
+// configuration is presented as a job.
+//
+// Importing this package registers the backend for instances of type "teamcity":
+//
+//     "my-server": {
+//         "type": "teamcity",
+//         "url": "https://teamcity.example.com",
+//         "buildTypes": ["Project_Build", "Project_Test"],
+//         "token": "..."
+//     }
+package teamcity
+
+import (
+    "errors"
+    "fmt"
+    "net/http"
+    "net/url"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/pgavlin/jitdash/pkg/jenkins"
+)
+
+// timestampLayout is the layout of TeamCity's timestamps, e.g. "20171015T101010+0000".
+const timestampLayout = "20060102T150405-0700"
+
+func init() {
+    jenkins.RegisterBackend("teamcity", newBackend)
+}
+
+type backend struct {
+    url string // the base URL of the server
+    token string
+    buildTypes []string
+    maxBuilds int // the number of most recent builds to list per build configuration
+}
+
+func newBackend(i *jenkins.Instance, config jenkins.JsonObject) (jenkins.Backend, error) {
+    b := &backend{maxBuilds: 30}
+
+    u, ok := config.GetString("url")
+    if !ok {
+        return nil, errors.New("no url")
+    }
+    b.url = strings.TrimSuffix(u, "/")
+
+    b.token, _ = config.GetString("token")
+
+    buildTypesArray, ok := config.GetArray("buildTypes")
+    if !ok {
+        return nil, errors.New("no buildTypes")
+    }
+    for _, bt := range buildTypesArray {
+        buildType, ok := bt.(string)
+        if !ok {
+            return nil, errors.New(fmt.Sprintf("invalid build type: %v", bt))
+        }
+        b.buildTypes = append(b.buildTypes, buildType)
+    }
+
+    if maxBuilds, ok := config.GetInt64("maxBuilds"); ok {
+        b.maxBuilds = int(maxBuilds)
+    }
+
+    return b, nil
+}
+
+func (b *backend) get(i *jenkins.Instance, path string) (jenkins.JsonObject, error) {
+    header := http.Header{}
+    header.Set("Accept", "application/json")
+    if b.token != "" {
+        header.Set("Authorization", "Bearer " + b.token)
+    }
+
+    var object jenkins.JsonObject
+    if err := jenkins.FetchJson(i.Client, b.url + path, header, &object); err != nil {
+        return nil, err
+    }
+    return object, nil
+}
+
+func (b *backend) FetchJobs(i *jenkins.Instance) ([]*jenkins.Job, []*jenkins.FetchError) {
+    var jobs []*jenkins.Job
+    var errs []*jenkins.FetchError
+    for _, buildType := range b.buildTypes {
+        buildTypePath := "/app/rest/buildTypes/id:" + url.PathEscape(buildType)
+
+        details, err := b.get(i, buildTypePath)
+        if err != nil {
+            errs = append(errs, i.NewFetchError(buildType, b.url + buildTypePath, err))
+            continue
+        }
+
+        name, ok := details.GetString("name")
+        if !ok {
+            name = buildType
+        }
+        if projectName, ok := details.GetString("projectName"); ok {
+            name = projectName + " / " + name
+        }
+        if i.IsExcluded(name) {
+            continue
+        }
+
+        i.Logger().Info("fetching build configuration", "buildType", buildType)
+
+        jobUrl, _ := details.GetString("webUrl")
+
+        locator := fmt.Sprintf("buildType:(id:%s),defaultFilter:false,count:%d", buildType, b.maxBuilds)
+        buildsPath := "/app/rest/builds?fields=build(id,webUrl)&locator=" + url.QueryEscape(locator)
+
+        list, err := b.get(i, buildsPath)
+        if err != nil {
+            errs = append(errs, i.NewFetchError(name, b.url + buildsPath, err))
+            continue
+        }
+
+        var builds []*jenkins.Build
+        buildObjects, _ := list.GetArray("build")
+        for _, bo := range buildObjects {
+            buildObject, ok := jenkins.AsJsonObject(bo)
+            if !ok {
+                continue
+            }
+
+            id, ok := buildObject.GetInt64("id")
+            if !ok {
+                continue
+            }
+
+            webUrl, ok := buildObject.GetString("webUrl")
+            if !ok {
+                continue
+            }
+
+            builds = append(builds, &jenkins.Build{Id: id, Url: webUrl})
+        }
+
+        sort.Sort(jenkins.BuildSorter(builds))
+        jobs = append(jobs, &jenkins.Job{Name: name, Url: jobUrl, Builds: builds})
+    }
+    return jobs, errs
+}
+
+// failedTests returns the FailedTestCount statistic of the given build.
+func (b *backend) failedTests(i *jenkins.Instance, id int64) (int64, error) {
+    statistics, err := b.get(i, fmt.Sprintf("/app/rest/builds/id:%d/statistics", id))
+    if err != nil {
+        return 0, err
+    }
+
+    properties, _ := statistics.GetArray("property")
+    for _, p := range properties {
+        property, ok := jenkins.AsJsonObject(p)
+        if !ok {
+            continue
+        }
+        if name, _ := property.GetString("name"); name != "FailedTestCount" {
+            continue
+        }
+
+        value, _ := property.GetString("value")
+        return strconv.ParseInt(value, 10, 64)
+    }
+    return 0, nil
+}
+
+func (b *backend) FetchDetails(i *jenkins.Instance, build *jenkins.Build) error {
+    details, err := b.get(i, fmt.Sprintf("/app/rest/builds/id:%d?fields=status,state,startDate,queuedDate,canceledInfo", build.Id))
+    if err != nil {
+        return err
+    }
+
+    state, ok := details.GetString("state")
+    if !ok {
+        return errors.New("missing state")
+    }
+    build.Complete = state == "finished"
+
+    timestamp, ok := details.GetString("startDate")
+    if !ok {
+        timestamp, ok = details.GetString("queuedDate")
+    }
+    if ok {
+        if t, err := time.Parse(timestampLayout, timestamp); err == nil {
+            build.Timestamp = t.UTC()
+        }
+    }
+    if finished, ok := details.GetString("finishDate"); ok && build.Complete {
+        if t, err := time.Parse(timestampLayout, finished); err == nil {
+            build.Duration = t.Sub(build.Timestamp)
+        }
+    }
+
+    failures, err := b.failedTests(i, build.Id)
+    if err != nil {
+        i.Logger().Debug("error fetching build statistics", "build", build.Url, "err", err)
+    }
+
+    status, _ := details.GetString("status")
+    if build.Complete {
+        build.Result = jenkins.ParseResult(status)
+        if _, canceled := details.GetObject("canceledInfo"); canceled {
+            build.Result = jenkins.ResultAborted
+        }
+    }
+    if failures == 0 && status == "FAILURE" {
+        failures = -1
+    }
+
+    build.Failures = failures
+    return nil
+}
@@ -0,0 +1,175 @@
+// Package tracing records traces of jitdash's fetch cycles and exports them to an OpenTelemetry collector using
+// OTLP/HTTP with JSON encoding. Each trace is exported when its root span ends. If tracing is not configured, spans are
+// nil and all of their methods are no-ops, so callers need not check whether tracing is enabled.
+package tracing
+
+import (
+    "bytes"
+    "crypto/rand"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "log/slog"
+    "net/http"
+    "strconv"
+    "sync"
+    "time"
+)
+
+// Exporter sends finished traces to an OTLP/HTTP endpoint.
+type Exporter struct {
+    Endpoint string // the base URL of the collector; traces are posted to Endpoint + "/v1/traces"
+    ServiceName string
+    Headers map[string]string // additional headers to send with each export, e.g. for authentication
+    Client *http.Client
+}
+
+var exporter *Exporter
+
+// SetExporter enables tracing with the given exporter. SetExporter must be called before any spans are started.
+func SetExporter(e *Exporter) {
+    exporter = e
+}
+
+// A Span is a timed operation within a trace.
+type Span struct {
+    trace *trace
+    id [8]byte
+    parent *Span
+    name string
+    start time.Time
+    end time.Time
+    attributes []string // alternating keys and values
+    err string
+}
+
+// trace collects the spans of a single trace until its root span ends.
+type trace struct {
+    id [16]byte
+
+    m sync.Mutex
+    spans []*Span
+}
+
+// Start starts the root span of a new trace. The given attributes alternate between keys and values. If tracing is
+// not enabled, Start returns nil.
+func Start(name string, attributes ...string) *Span {
+    if exporter == nil {
+        return nil
+    }
+
+    t := &trace{}
+    rand.Read(t.id[:])
+    return t.start(nil, name, attributes)
+}
+
+func (t *trace) start(parent *Span, name string, attributes []string) *Span {
+    s := &Span{trace: t, parent: parent, name: name, start: time.Now(), attributes: attributes}
+    rand.Read(s.id[:])
+    return s
+}
+
+// Start starts a child of the span.
+func (s *Span) Start(name string, attributes ...string) *Span {
+    if s == nil {
+        return nil
+    }
+    return s.trace.start(s, name, attributes)
+}
+
+// SetError marks the span as failed with the given error. SetError does nothing if err is nil.
+func (s *Span) SetError(err error) {
+    if s == nil || err == nil {
+        return
+    }
+    s.err = err.Error()
+}
+
+// End ends the span. If the span is the root of its trace, the trace is exported; every other span in the trace must
+// have ended by then.
+func (s *Span) End() {
+    if s == nil {
+        return
+    }
+    s.end = time.Now()
+
+    t := s.trace
+    t.m.Lock()
+    t.spans = append(t.spans, s)
+    t.m.Unlock()
+
+    if s.parent == nil {
+        if err := exporter.export(t); err != nil {
+            slog.Warn("error exporting trace", "err", err)
+        }
+    }
+}
+
+// otlpAttributes converts alternating keys and values to OTLP attributes.
+func otlpAttributes(attributes []string) []interface{} {
+    var result []interface{}
+    for n := 0; n + 1 < len(attributes); n += 2 {
+        result = append(result, map[string]interface{}{
+            "key": attributes[n],
+            "value": map[string]interface{}{"stringValue": attributes[n + 1]},
+        })
+    }
+    return result
+}
+
+// export posts the spans of the given trace to the collector.
+func (e *Exporter) export(t *trace) error {
+    t.m.Lock()
+    spans := make([]interface{}, len(t.spans))
+    for n, s := range t.spans {
+        span := map[string]interface{}{
+            "traceId": hex.EncodeToString(t.id[:]),
+            "spanId": hex.EncodeToString(s.id[:]),
+            "name": s.name,
+            "kind": 1, // SPAN_KIND_INTERNAL
+            "startTimeUnixNano": strconv.FormatInt(s.start.UnixNano(), 10),
+            "endTimeUnixNano": strconv.FormatInt(s.end.UnixNano(), 10),
+            "attributes": otlpAttributes(s.attributes),
+        }
+        if s.parent != nil {
+            span["parentSpanId"] = hex.EncodeToString(s.parent.id[:])
+        }
+        if s.err != "" {
+            span["status"] = map[string]interface{}{"code": 2, "message": s.err} // STATUS_CODE_ERROR
+        }
+        spans[n] = span
+    }
+    t.m.Unlock()
+
+    body, err := json.Marshal(map[string]interface{}{
+        "resourceSpans": []interface{}{map[string]interface{}{
+            "resource": map[string]interface{}{"attributes": otlpAttributes([]string{"service.name", e.ServiceName})},
+            "scopeSpans": []interface{}{map[string]interface{}{
+                "scope": map[string]interface{}{"name": "github.com/pgavlin/jitdash"},
+                "spans": spans,
+            }},
+        }},
+    })
+    if err != nil {
+        return err
+    }
+
+    req, err := http.NewRequest("POST", e.Endpoint + "/v1/traces", bytes.NewReader(body))
+    if err != nil {
+        return err
+    }
+    req.Header.Set("Content-Type", "application/json")
+    for k, v := range e.Headers {
+        req.Header.Set(k, v)
+    }
+
+    resp, err := e.Client.Do(req)
+    if err != nil {
+        return err
+    }
+    resp.Body.Close()
+    if resp.StatusCode < 200 || resp.StatusCode > 299 {
+        return errors.New(resp.Status)
+    }
+    return nil
+}